@@ -32,22 +32,48 @@ func getConnector(ctx context.Context, cc *cfg.Confluence, connectorOpts *cli.Co
 	if connectorOpts.SyncFilterIsExplicit() {
 		willSyncRbacTypes := connectorOpts.WillSyncResourceType(connector.SpaceRoleResourceTypeID) ||
 			connectorOpts.WillSyncResourceType(connector.SpaceRoleAssignmentResourceTypeID)
-		if willSyncRbacTypes && !cc.UseRbac {
-			return nil, nil, status.Error(codes.InvalidArgument, fmt.Sprintf("confluence-connector: use-rbac must be enabled when syncing %s or %s resource types",
+		// In auto mode the role mode is only known once the connector starts.
+		syncsRbac := cc.UseRbac ||
+			cc.SpaceAccessMode == cfg.SpaceAccessModeRbac ||
+			cc.SpaceAccessMode == cfg.SpaceAccessModeHybrid ||
+			cc.SpaceAccessMode == cfg.SpaceAccessModeAuto
+		if willSyncRbacTypes && !syncsRbac {
+			return nil, nil, status.Error(codes.InvalidArgument, fmt.Sprintf("confluence-connector: use-rbac or a space access mode with roles must be enabled when syncing %s or %s resource types",
 				connector.SpaceRoleResourceTypeID, connector.SpaceRoleAssignmentResourceTypeID))
 		}
 	}
 
-	cb, err := connector.New(
-		ctx,
-		cc.ApiKey,
-		cc.DomainUrl,
-		cc.Username,
-		cc.SkipPersonalSpaces,
-		cc.UseRbac,
-		cc.Noun,
-		cc.Verb,
-	)
+	cb, err := connector.New(ctx, connector.Config{
+		UserName:                  cc.Username,
+		ApiKey:                    cc.ApiKey,
+		Domain:                    cc.DomainUrl,
+		AuthMethod:                connectorOpts.SelectedAuthMethod,
+		OAuthClientId:             cc.OauthClientId,
+		OAuthClientSecret:         cc.OauthClientSecret,
+		OAuthRefreshToken:         cc.OauthRefreshToken,
+		OAuthRefreshTokenFile:     cc.OauthRefreshTokenFile,
+		CloudId:                   cc.CloudId,
+		PersonalAccessToken:       cc.PersonalAccessToken,
+		DeploymentType:            cc.DeploymentType,
+		SkipPersonalSpaces:        cc.SkipPersonalSpaces,
+		PersonalSpaceOwners:       cc.PersonalSpaceOwners,
+		IncludeSpaceKeys:          cc.IncludeSpaceKeys,
+		ExcludeSpaceKeys:          cc.ExcludeSpaceKeys,
+		SpaceTypes:                cc.SpaceTypes,
+		SpaceStatuses:             cc.SpaceStatuses,
+		IncludeSpaceLabels:        cc.IncludeSpaceLabels,
+		ExcludeSpaceLabels:        cc.ExcludeSpaceLabels,
+		UseRbac:                   cc.UseRbac,
+		SpaceAccessMode:           cc.SpaceAccessMode,
+		Nouns:                     cc.Noun,
+		Verbs:                     cc.Verb,
+		DiscoverSpaceEntitlements: cc.DiscoverSpaceEntitlements,
+		SyncContentRestrictions:   cc.SyncContentRestrictions,
+		MaxRequestsPerSecond:      cc.MaxRequestsPerSecond,
+		SyncConcurrency:           cc.SyncConcurrency,
+		OrgId:                     cc.OrgId,
+		OrgAdminApiKey:            cc.OrgAdminApiKey,
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,35 @@
+// Command fake-confluence serves an in-memory Confluence Cloud site seeded
+// with demo data, so a local baton-confluence can be pointed at it:
+//
+//	go run ./cmd/fake-confluence -addr localhost:8080
+//	baton-confluence --domain-url http://localhost:8080 --username admin --api-key any
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/conductorone/baton-confluence/test/fake"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	rateLimit := flag.Int("rate-limit", 0, "number of requests to answer with 429 Too Many Requests before serving normally")
+	retryAfter := flag.Duration("retry-after", 5*time.Second, "Retry-After sent with rate limited responses")
+	flag.Parse()
+
+	server := fake.NewDemo()
+	if *rateLimit > 0 {
+		server.RateLimitNext(*rateLimit, *retryAfter)
+	}
+
+	log.Printf("serving fake Confluence on http://%s", *addr)
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Fatal(httpServer.ListenAndServe())
+}
@@ -7,10 +7,31 @@ type Confluence struct {
 	ApiKey string `mapstructure:"api-key"`
 	DomainUrl string `mapstructure:"domain-url"`
 	Username string `mapstructure:"username"`
+	OauthClientId string `mapstructure:"oauth-client-id"`
+	OauthClientSecret string `mapstructure:"oauth-client-secret"`
+	OauthRefreshToken string `mapstructure:"oauth-refresh-token"`
+	OauthRefreshTokenFile string `mapstructure:"oauth-refresh-token-file"`
+	CloudId string `mapstructure:"cloud-id"`
+	PersonalAccessToken string `mapstructure:"personal-access-token"`
+	DeploymentType string `mapstructure:"deployment-type"`
 	SkipPersonalSpaces bool `mapstructure:"skip-personal-spaces"`
+	PersonalSpaceOwners []string `mapstructure:"personal-space-owners"`
+	IncludeSpaceKeys []string `mapstructure:"include-space-keys"`
+	ExcludeSpaceKeys []string `mapstructure:"exclude-space-keys"`
+	SpaceTypes []string `mapstructure:"space-types"`
+	SpaceStatuses []string `mapstructure:"space-statuses"`
+	IncludeSpaceLabels []string `mapstructure:"include-space-labels"`
+	ExcludeSpaceLabels []string `mapstructure:"exclude-space-labels"`
 	Noun []string `mapstructure:"noun"`
 	Verb []string `mapstructure:"verb"`
 	UseRbac bool `mapstructure:"use-rbac"`
+	SpaceAccessMode string `mapstructure:"space-access-mode"`
+	DiscoverSpaceEntitlements bool `mapstructure:"discover-space-entitlements"`
+	SyncContentRestrictions bool `mapstructure:"sync-content-restrictions"`
+	MaxRequestsPerSecond int `mapstructure:"max-requests-per-second"`
+	SyncConcurrency int `mapstructure:"sync-concurrency"`
+	OrgId string `mapstructure:"org-id"`
+	OrgAdminApiKey string `mapstructure:"org-admin-api-key"`
 }
 
 func (c *Confluence) findFieldByTag(tagValue string) (any, bool) {
@@ -2,20 +2,60 @@
 package config
 
 import (
+	"slices"
+
 	"github.com/conductorone/baton-sdk/pkg/field"
 )
 
-var defaultNouns = []string{
+// Auth methods, selected with `--auth-method`. Each one is a field group that
+// lists the fields it needs.
+const (
+	AuthMethodAPIToken                = "api-token"
+	AuthMethodOAuth2                  = "oauth2"
+	AuthMethodOAuth2ClientCredentials = "oauth2-client-credentials"
+	AuthMethodPersonalAccessToken     = "personal-access-token"
+	AuthMethodScopedAPIToken          = "scoped-api-token"
+)
+
+// Deployment types, selected with `--deployment-type`.
+const (
+	DeploymentTypeCloud      = "cloud"
+	DeploymentTypeDataCenter = "data-center"
+)
+
+// Space access modes, selected with `--space-access-mode`.
+const (
+	// SpaceAccessModePermissions syncs granular space permissions.
+	SpaceAccessModePermissions = "permissions"
+	// SpaceAccessModeRbac syncs RBAC space roles, like `--use-rbac`.
+	SpaceAccessModeRbac = "rbac"
+	// SpaceAccessModeHybrid syncs both, for sites in ROLES_TRANSITION mode.
+	SpaceAccessModeHybrid = "hybrid"
+	// SpaceAccessModeAuto picks one of the above from the site's space role
+	// mode at startup.
+	SpaceAccessModeAuto = "auto"
+)
+
+// DefaultNouns and DefaultVerbs are the space permission targets and
+// operations synced when none are configured. "update" is deprecated: it is
+// kept so that existing deployments keep their update-* entitlements.
+var DefaultNouns = []string{
+	"attachment",
+	"blogpost",
+	"comment",
+	"page",
 	"space",
 }
 
-var defaultVerbs = []string{
+var DefaultVerbs = []string{
 	"administer",
+	"archive",
 	"create",
 	"delete",
 	"export",
 	"read",
 	"restrict_content",
+	"update",
 }
 
 var (
@@ -40,24 +80,113 @@ var (
 		field.WithPlaceholder("user@example.com"),
 		field.WithRequired(true),
 	)
+	oauthClientIdField = field.StringField(
+		"oauth-client-id",
+		field.WithDescription("The client ID of your Atlassian OAuth 2.0 app or service account credential"),
+		field.WithDisplayName("OAuth Client ID"),
+		field.WithRequired(true),
+	)
+	oauthClientSecretField = field.StringField(
+		"oauth-client-secret",
+		field.WithDescription("The client secret of your Atlassian OAuth 2.0 app or service account credential"),
+		field.WithDisplayName("OAuth Client Secret"),
+		field.WithRequired(true),
+		field.WithIsSecret(true),
+	)
+	oauthRefreshTokenField = field.StringField(
+		"oauth-refresh-token",
+		field.WithDescription("The refresh token obtained through the OAuth 2.0 (3LO) authorization code flow"),
+		field.WithDisplayName("OAuth Refresh Token"),
+		field.WithRequired(true),
+		field.WithIsSecret(true),
+	)
+	oauthRefreshTokenFileField = field.StringField(
+		"oauth-refresh-token-file",
+		field.WithDescription("A file the OAuth 2.0 (3LO) refresh token is kept in. Atlassian rotates refresh tokens on use: "+
+			"the rotated token is saved to this file and read from it on the next run"),
+		field.WithDisplayName("OAuth Refresh Token File"),
+		field.WithRequired(false),
+	)
+	cloudIdField = field.StringField(
+		"cloud-id",
+		field.WithDescription("The Atlassian cloud ID of your site. Discovered from the domain URL when omitted"),
+		field.WithDisplayName("Cloud ID"),
+		field.WithRequired(false),
+	)
+	personalAccessTokenField = field.StringField(
+		"personal-access-token",
+		field.WithDescription("A Confluence Data Center personal access token"),
+		field.WithDisplayName("Personal Access Token"),
+		field.WithRequired(true),
+		field.WithIsSecret(true),
+	)
+	deploymentTypeField = field.SelectField(
+		"deployment-type",
+		[]string{DeploymentTypeCloud, DeploymentTypeDataCenter},
+		field.WithDescription("Whether the domain URL points to Confluence Cloud or to Confluence Data Center / Server"),
+		field.WithDisplayName("Deployment Type"),
+		field.WithDefaultValue(DeploymentTypeCloud),
+	)
 	skipPersonalSpaces = field.BoolField(
 		"skip-personal-spaces",
 		field.WithDescription("Skip syncing personal spaces and their permissions"),
 		field.WithDisplayName("Skip Personal Spaces"),
 		field.WithRequired(false),
 	)
+	personalSpaceOwnersField = field.StringSliceField(
+		"personal-space-owners",
+		field.WithDescription("Only sync the personal spaces of these users: account IDs, or usernames on Data Center"),
+		field.WithDisplayName("Personal Space Owners"),
+		field.WithRequired(false),
+	)
+	includeSpaceKeysField = field.StringSliceField(
+		"include-space-keys",
+		field.WithDescription("Only sync the spaces whose key matches one of these glob patterns, such as ENG or TEAM-*"),
+		field.WithDisplayName("Include Space Keys"),
+		field.WithRequired(false),
+	)
+	excludeSpaceKeysField = field.StringSliceField(
+		"exclude-space-keys",
+		field.WithDescription("Skip the spaces whose key matches one of these glob patterns"),
+		field.WithDisplayName("Exclude Space Keys"),
+		field.WithRequired(false),
+	)
+	spaceTypesField = field.StringSliceField(
+		"space-types",
+		field.WithDescription("Only sync spaces of these types: global, personal, collaboration or knowledge_base"),
+		field.WithDisplayName("Space Types"),
+		field.WithRequired(false),
+	)
+	spaceStatusesField = field.StringSliceField(
+		"space-statuses",
+		field.WithDescription("Only sync spaces with these statuses: current or archived"),
+		field.WithDisplayName("Space Statuses"),
+		field.WithRequired(false),
+	)
+	includeSpaceLabelsField = field.StringSliceField(
+		"include-space-labels",
+		field.WithDescription("Only sync the spaces that have one of these labels"),
+		field.WithDisplayName("Include Space Labels"),
+		field.WithRequired(false),
+	)
+	excludeSpaceLabelsField = field.StringSliceField(
+		"exclude-space-labels",
+		field.WithDescription("Skip the spaces that have one of these labels"),
+		field.WithDisplayName("Exclude Space Labels"),
+		field.WithRequired(false),
+	)
 	nounsField = field.StringSliceField(
 		"noun",
-		field.WithDescription("The nouns for your Confluence Space sync"),
+		field.WithDescription("The nouns for your Confluence Space sync: the targets of the space permissions to sync"),
 		field.WithDisplayName("Nouns"),
-		field.WithSuggestedValue(defaultNouns),
+		field.WithSuggestedValue(DefaultNouns),
 		field.WithRequired(false),
 	)
 	verbsField = field.StringSliceField(
 		"verb",
-		field.WithDescription("The verbs for your Confluence Space sync"),
+		field.WithDescription("The verbs for your Confluence Space sync: the operations of the space permissions to sync"),
 		field.WithDisplayName("Verbs"),
-		field.WithSuggestedValue(defaultVerbs),
+		field.WithSuggestedValue(DefaultVerbs),
 		field.WithRequired(false),
 	)
 	useRbacField = field.BoolField(
@@ -66,20 +195,153 @@ var (
 		field.WithDisplayName("Use RBAC"),
 		field.WithDefaultValue(false),
 	)
+	spaceAccessModeField = field.SelectField(
+		"space-access-mode",
+		[]string{SpaceAccessModePermissions, SpaceAccessModeRbac, SpaceAccessModeHybrid, SpaceAccessModeAuto},
+		field.WithDescription("The space access model to sync: granular permissions, RBAC space roles, "+
+			"both for sites migrating to roles (ROLES_TRANSITION), or auto to follow the site's space role mode"),
+		field.WithDisplayName("Space Access Mode"),
+		field.WithDefaultValue(SpaceAccessModePermissions),
+	)
+	discoverSpaceEntitlementsField = field.BoolField(
+		"discover-space-entitlements",
+		field.WithDescription("Build each space's permission entitlements from the operations Confluence reports for the space "+
+			"instead of every combination of the configured nouns and verbs"),
+		field.WithDisplayName("Discover Space Entitlements"),
+		field.WithDefaultValue(false),
+		field.WithRequired(false),
+	)
+	syncContentRestrictionsField = field.BoolField(
+		"sync-content-restrictions",
+		field.WithDescription("Sync the pages and blog posts with view or edit restrictions, and who the restrictions are limited to. "+
+			"Every page and blog post of every space is read to find them"),
+		field.WithDisplayName("Sync Content Restrictions"),
+		field.WithDefaultValue(false),
+		field.WithRequired(false),
+	)
+	maxRequestsPerSecondField = field.IntField(
+		"max-requests-per-second",
+		field.WithDescription("The maximum number of requests per second sent to Confluence. 0 means no fixed cap; "+
+			"requests are still slowed down when Confluence reports that its rate limits are near"),
+		field.WithDisplayName("Max Requests Per Second"),
+		field.WithDefaultValue(0),
+		field.WithRequired(false),
+	)
+	syncConcurrencyField = field.IntField(
+		"sync-concurrency",
+		field.WithDescription("The number of group member and space permission pages fetched at once during a sync. "+
+			"1 fetches them one at a time"),
+		field.WithDisplayName("Sync Concurrency"),
+		field.WithDefaultValue(1),
+		field.WithRequired(false),
+	)
+	orgIdField = field.StringField(
+		"org-id",
+		field.WithDescription("The ID of your Atlassian organization. With an organization admin API key, "+
+			"the status, last active date and verified email of managed accounts are read from the organization"),
+		field.WithDisplayName("Organization ID"),
+		field.WithRequired(false),
+	)
+	orgAdminApiKeyField = field.StringField(
+		"org-admin-api-key",
+		field.WithDescription("An API key of your Atlassian organization, used with the organization ID"),
+		field.WithDisplayName("Organization Admin API Key"),
+		field.WithRequired(false),
+		field.WithIsSecret(true),
+	)
 )
 
-var ConfigurationFields = []field.SchemaField{
-	apiKeyField,
-	domainUrl,
-	usernameField,
+// syncFields are shared by every auth method.
+var syncFields = []field.SchemaField{
+	deploymentTypeField,
 	skipPersonalSpaces,
+	personalSpaceOwnersField,
+	includeSpaceKeysField,
+	excludeSpaceKeysField,
+	spaceTypesField,
+	spaceStatusesField,
+	includeSpaceLabelsField,
+	excludeSpaceLabelsField,
 	nounsField,
 	verbsField,
 	useRbacField,
+	spaceAccessModeField,
+	discoverSpaceEntitlementsField,
+	syncContentRestrictionsField,
+	maxRequestsPerSecondField,
+	syncConcurrencyField,
+	orgIdField,
+	orgAdminApiKeyField,
+}
+
+var ConfigurationFields = slices.Concat(
+	[]field.SchemaField{
+		apiKeyField,
+		domainUrl,
+		usernameField,
+		oauthClientIdField,
+		oauthClientSecretField,
+		oauthRefreshTokenField,
+		oauthRefreshTokenFileField,
+		cloudIdField,
+		personalAccessTokenField,
+	},
+	syncFields,
+)
+
+var FieldGroups = []field.SchemaFieldGroup{
+	{
+		Name:        AuthMethodAPIToken,
+		DisplayName: "API token",
+		HelpText:    "Authenticate with an Atlassian account's email address and API token, or a Data Center username and password.",
+		Fields:      slices.Concat([]field.SchemaField{domainUrl, usernameField, apiKeyField}, syncFields),
+		Default:     true,
+	},
+	{
+		Name:        AuthMethodScopedAPIToken,
+		DisplayName: "Scoped API token",
+		HelpText:    "Authenticate with an Atlassian account's email address and a scoped API token. Requests go through the api.atlassian.com gateway.",
+		Fields: slices.Concat(
+			[]field.SchemaField{domainUrl, usernameField, apiKeyField, cloudIdField},
+			syncFields,
+		),
+	},
+	{
+		Name:        AuthMethodOAuth2,
+		DisplayName: "OAuth 2.0 (3LO)",
+		HelpText:    "Authenticate as an OAuth 2.0 app using a refresh token from the authorization code flow.",
+		Fields: slices.Concat(
+			[]field.SchemaField{
+				domainUrl,
+				oauthClientIdField,
+				oauthClientSecretField,
+				oauthRefreshTokenField,
+				oauthRefreshTokenFileField,
+				cloudIdField,
+			},
+			syncFields,
+		),
+	},
+	{
+		Name:        AuthMethodOAuth2ClientCredentials,
+		DisplayName: "OAuth 2.0 client credentials",
+		HelpText:    "Authenticate as an Atlassian service account using OAuth 2.0 client credentials.",
+		Fields: slices.Concat(
+			[]field.SchemaField{domainUrl, oauthClientIdField, oauthClientSecretField, cloudIdField},
+			syncFields,
+		),
+	},
+	{
+		Name:        AuthMethodPersonalAccessToken,
+		DisplayName: "Personal access token (Data Center)",
+		HelpText:    "Authenticate against Confluence Data Center or Server with a personal access token.",
+		Fields:      slices.Concat([]field.SchemaField{domainUrl, personalAccessTokenField}, syncFields),
+	},
 }
 
 var Configuration = field.NewConfiguration(
 	ConfigurationFields,
+	field.WithFieldGroups(FieldGroups),
 	field.WithConnectorDisplayName("Confluence"),
 	field.WithHelpUrl("/docs/baton/confluence"),
 	field.WithIconUrl("/static/app-icons/confluence.svg"),
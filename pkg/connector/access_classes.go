@@ -0,0 +1,87 @@
+package connector
+
+import (
+	"context"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// anonymousAccessClassID is the access class of anonymous users. Granular
+// space permissions given to anonymous users are granted to it too.
+const anonymousAccessClassID = "anonymous-users"
+
+// accessClass is a class of users Confluence can grant access to as a whole,
+// instead of naming users or groups.
+type accessClass struct {
+	id          string
+	displayName string
+	description string
+}
+
+// accessClasses lists the access classes space roles can be assigned to. Their
+// IDs are the principal IDs of ACCESS_CLASS role assignments.
+//
+// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-space-roles/
+var accessClasses = []accessClass{
+	{anonymousAccessClassID, "Anonymous users", "Anyone on the internet, without signing in"},
+	{"authenticated-users", "Signed-in users", "Everyone signed in to the site, including guests"},
+	{"all-licensed-users", "All licensed users", "Every user with a Confluence license on the site"},
+	{"all-product-admins", "Product admins", "Every Confluence administrator of the site"},
+	{"jsm-project-admins", "JSM project admins", "The admins of linked Jira Service Management projects"},
+}
+
+// isAccessClass reports whether id is a known access class.
+func isAccessClass(id string) bool {
+	for _, class := range accessClasses {
+		if class.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+type accessClassBuilder struct{}
+
+func (b *accessClassBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return accessClassResourceType
+}
+
+// List returns every access class. They are the same on every site, so no
+// request is made.
+func (b *accessClassBuilder) List(
+	_ context.Context,
+	parentResourceID *v2.ResourceId,
+	_ rs.SyncOpAttrs,
+) ([]*v2.Resource, *rs.SyncOpResults, error) {
+	if parentResourceID != nil {
+		return nil, nil, nil
+	}
+
+	resources := make([]*v2.Resource, 0, len(accessClasses))
+	for _, class := range accessClasses {
+		r, err := rs.NewResource(
+			class.displayName,
+			accessClassResourceType,
+			class.id,
+			rs.WithDescription(class.description),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		resources = append(resources, r)
+	}
+	return resources, syncResults("", nil), nil
+}
+
+func (b *accessClassBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ rs.SyncOpAttrs) ([]*v2.Entitlement, *rs.SyncOpResults, error) {
+	return nil, nil, nil
+}
+
+func (b *accessClassBuilder) Grants(_ context.Context, _ *v2.Resource, _ rs.SyncOpAttrs) ([]*v2.Grant, *rs.SyncOpResults, error) {
+	return nil, nil, nil
+}
+
+func newAccessClassBuilder() *accessClassBuilder {
+	return &accessClassBuilder{}
+}
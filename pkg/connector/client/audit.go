@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// GetAuditRecords fetches a page of the audit records created between
+// startDate and endDate, in milliseconds since the epoch. A zero bound is
+// left open. Records are returned newest first.
+func (c *ConfluenceClient) GetAuditRecords(
+	ctx context.Context,
+	startDate int64,
+	endDate int64,
+	pageToken string,
+	pageSize int,
+) (
+	[]AuditRecord,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	path := AuditUrlPath
+	if c.dataCenter {
+		path = DataCenterAuditUrlPath
+	}
+
+	parameters := make(map[string]interface{})
+	if startDate > 0 {
+		parameters["startDate"] = fmt.Sprint(startDate)
+	}
+	if endDate > 0 {
+		parameters["endDate"] = fmt.Sprint(endDate)
+	}
+
+	return getOffsetPage[AuditRecord](ctx, c, path, pageToken, pageSize, withQueryParameters(parameters))
+}
+
+// AuditRecords lists the audit records created between startDate and
+// endDate.
+func (c *ConfluenceClient) AuditRecords(startDate int64, endDate int64) *Pager[AuditRecord] {
+	return newPager(defaultSize, func(ctx context.Context, pageToken string, pageSize int) ([]AuditRecord, string, *v2.RateLimitDescription, error) {
+		return c.GetAuditRecords(ctx, startDate, endDate, pageToken, pageSize)
+	})
+}
+
+// GetGroupByName looks up a group by its name. Data Center identifies groups
+// by name, so no request is needed there.
+func (c *ConfluenceClient) GetGroupByName(
+	ctx context.Context,
+	name string,
+) (*ConfluenceGroup, *v2.RateLimitDescription, error) {
+	if c.dataCenter {
+		return &ConfluenceGroup{Type: "group", Name: name, Id: name}, nil, nil
+	}
+
+	groupUrl, err := c.parse(
+		groupByNameUrlPath,
+		withQueryParameters(map[string]interface{}{"name": name}),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response *ConfluenceGroup
+	ratelimitData, err := c.get(ctx, groupUrl, &response)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	return response, ratelimitData, nil
+}
+
+// FindUserByName resolves the name audit records give a user to the ID the
+// user is synced with, or "" when there is no such user. Data Center names
+// users by username. Cloud names them by account ID or display name; a
+// display name shared by several users is not resolved.
+func (c *ConfluenceClient) FindUserByName(
+	ctx context.Context,
+	name string,
+) (string, *v2.RateLimitDescription, error) {
+	if c.dataCenter {
+		return c.findUserKeyDataCenter(ctx, name)
+	}
+
+	userUrl, err := c.parse(
+		userUrlPath,
+		withQueryParameters(map[string]interface{}{"accountId": name}),
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var user *ConfluenceUser
+	ratelimitData, err := c.get(ctx, userUrl, &user)
+	var reqErr *RequestError
+	switch {
+	case err == nil:
+		return user.AccountId, ratelimitData, nil
+	case errors.As(err, &reqErr) && (reqErr.Status == http.StatusNotFound || reqErr.Status == http.StatusBadRequest):
+	default:
+		return "", ratelimitData, err
+	}
+
+	searchUrl, err := c.parse(
+		SearchUrlPath,
+		withQueryParameters(map[string]interface{}{
+			"cql":   fmt.Sprintf(`type = user AND user.fullname ~ "%s"`, strings.ReplaceAll(name, `"`, `\"`)),
+			"limit": 25,
+		}),
+	)
+	if err != nil {
+		return "", ratelimitData, err
+	}
+
+	var response *ConfluenceSearchList
+	ratelimitData, err = c.get(ctx, searchUrl, &response)
+	if err != nil {
+		return "", ratelimitData, err
+	}
+
+	accountId := ""
+	for _, result := range response.Results {
+		if result.User.DisplayName != name {
+			continue
+		}
+		if accountId != "" {
+			return "", ratelimitData, nil
+		}
+		accountId = result.User.AccountId
+	}
+	return accountId, ratelimitData, nil
+}
+
+// FindSpaceByName searches for the space with the given name and returns its
+// ID, or "" when there is none. On Data Center the ID is the space key.
+func (c *ConfluenceClient) FindSpaceByName(
+	ctx context.Context,
+	name string,
+) (string, *v2.RateLimitDescription, error) {
+	path := ContentSearchCqlUrlPath
+	if c.dataCenter {
+		path = DataCenterSearchUrlPath
+	}
+
+	searchUrl, err := c.parse(
+		path,
+		withQueryParameters(map[string]interface{}{
+			"cql":   fmt.Sprintf(`type = space AND title = "%s"`, strings.ReplaceAll(name, `"`, `\"`)),
+			"limit": 25,
+		}),
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var response *ConfluenceSearchList
+	ratelimitData, err := c.get(ctx, searchUrl, &response)
+	if err != nil {
+		return "", ratelimitData, err
+	}
+
+	for _, result := range response.Results {
+		if result.Space == nil || result.Space.Name != name {
+			continue
+		}
+		if c.dataCenter {
+			return result.Space.Key, ratelimitData, nil
+		}
+		return result.Space.Id.String(), ratelimitData, nil
+	}
+	return "", ratelimitData, nil
+}
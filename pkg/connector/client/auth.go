@@ -0,0 +1,221 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// AtlassianTokenUrl is the token endpoint shared by OAuth 2.0 (3LO) apps
+	// and service account client credentials.
+	AtlassianTokenUrl = "https://auth.atlassian.com/oauth/token"
+
+	accessibleResourcesUrlPath = "/oauth/token/accessible-resources"
+	gatewayConfluenceUrlPath   = "/ex/confluence/%s"
+	tenantInfoUrlPath          = "/_edge/tenant_info"
+)
+
+// atlassianGatewayUrl is where OAuth 2.0 and scoped API token requests have
+// to be sent; the site URL itself only accepts unscoped API tokens. It is a
+// variable so tests can point it at a fake server.
+var atlassianGatewayUrl = "https://api.atlassian.com"
+
+// NewOAuth2RefreshTokenCredentials returns credentials for an OAuth 2.0
+// authorization-code (3LO) app. The refresh token is exchanged for an access
+// token on the first request and again whenever the access token expires.
+//
+// Atlassian rotates refresh tokens: every refresh returns a new one and the
+// previous one stops working. When tokenFile is set, the refresh token is read
+// from it if it exists, and every rotated token is written back to it, so the
+// next run starts from a valid token. Without it, refreshToken only lasts for
+// one run.
+func NewOAuth2RefreshTokenCredentials(clientId, clientSecret, refreshToken, tokenFile string) uhttp.AuthCredentials {
+	return &refreshTokenCredentials{
+		config: &oauth2.Config{
+			ClientID:     clientId,
+			ClientSecret: clientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: AtlassianTokenUrl},
+		},
+		refreshToken: refreshToken,
+		tokenFile:    tokenFile,
+	}
+}
+
+type refreshTokenCredentials struct {
+	config       *oauth2.Config
+	refreshToken string
+	tokenFile    string
+}
+
+func (r *refreshTokenCredentials) GetClient(ctx context.Context, options ...uhttp.Option) (*http.Client, error) {
+	httpClient, err := uhttp.NewClient(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := r.currentRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	return oauth2.NewClient(ctx, &rotatingTokenSource{
+		source:       r.config.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}),
+		tokenFile:    r.tokenFile,
+		refreshToken: refreshToken,
+	}), nil
+}
+
+// currentRefreshToken prefers the token saved in the token file over the
+// configured one, which has been rotated away after the first run.
+func (r *refreshTokenCredentials) currentRefreshToken() (string, error) {
+	if r.tokenFile == "" {
+		return r.refreshToken, nil
+	}
+	saved, err := os.ReadFile(r.tokenFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return r.refreshToken, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("confluence-connector: failed to read the OAuth refresh token file: %w", err)
+	}
+	if token := strings.TrimSpace(string(saved)); token != "" {
+		return token, nil
+	}
+	return r.refreshToken, nil
+}
+
+// rotatingTokenSource saves every refresh token the token endpoint rotates
+// to the token file.
+type rotatingTokenSource struct {
+	source    oauth2.TokenSource
+	tokenFile string
+
+	mu           sync.Mutex
+	refreshToken string
+}
+
+func (s *rotatingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, err := s.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	if s.tokenFile == "" || token.RefreshToken == "" || token.RefreshToken == s.refreshToken {
+		return token, nil
+	}
+
+	// Write then rename, so that a crash never leaves a truncated token.
+	tmpFile := filepath.Join(filepath.Dir(s.tokenFile), "."+filepath.Base(s.tokenFile)+".tmp")
+	err = os.WriteFile(tmpFile, []byte(token.RefreshToken+"\n"), 0o600)
+	if err == nil {
+		err = os.Rename(tmpFile, s.tokenFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("confluence-connector: failed to save the rotated OAuth refresh token: %w", err)
+	}
+	s.refreshToken = token.RefreshToken
+	return token, nil
+}
+
+// NewOAuth2ClientCredentials returns credentials for an Atlassian service
+// account using the OAuth 2.0 client credentials grant.
+func NewOAuth2ClientCredentials(clientId, clientSecret string) (uhttp.AuthCredentials, error) {
+	tokenUrl, err := url.Parse(AtlassianTokenUrl)
+	if err != nil {
+		return nil, err
+	}
+	return uhttp.NewOAuth2ClientCredentials(clientId, clientSecret, tokenUrl, nil), nil
+}
+
+type tenantInfo struct {
+	CloudId string `json:"cloudId"`
+}
+
+// lookupCloudId reads the cloud ID from the site's public tenant info. It does
+// not depend on what the credentials have been granted, so it also works for
+// scoped API tokens.
+func (c *ConfluenceClient) lookupCloudId(ctx context.Context) (string, error) {
+	tenantInfoUrl := c.site.ResolveReference(&url.URL{Path: tenantInfoUrlPath})
+
+	var response *tenantInfo
+	_, err := c.get(ctx, tenantInfoUrl, &response)
+	if err != nil {
+		return "", fmt.Errorf("confluence-connector: failed to look up the cloud ID of %s: %w", c.site.Host, err)
+	}
+	if response == nil || response.CloudId == "" {
+		return "", fmt.Errorf("confluence-connector: %s did not report a cloud ID", c.site.Host)
+	}
+	return response.CloudId, nil
+}
+
+type accessibleResource struct {
+	Id     string   `json:"id"`
+	Url    string   `json:"url"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// discoverCloudId looks up the cloud ID of the site among the resources the
+// access token has been granted to.
+func (c *ConfluenceClient) discoverCloudId(ctx context.Context, site *url.URL) (string, error) {
+	resourcesUrl, err := url.Parse(atlassianGatewayUrl + accessibleResourcesUrlPath)
+	if err != nil {
+		return "", err
+	}
+
+	var response []accessibleResource
+	_, err = c.get(ctx, resourcesUrl, &response)
+	if err != nil {
+		return "", fmt.Errorf("confluence-connector: failed to list accessible resources: %w", err)
+	}
+
+	for _, resource := range response {
+		resourceUrl, err := url.Parse(resource.Url)
+		if err != nil {
+			continue
+		}
+		if strings.EqualFold(resourceUrl.Host, site.Host) {
+			return resource.Id, nil
+		}
+	}
+
+	return "", fmt.Errorf("confluence-connector: the OAuth credentials have not been granted access to %s", site.Host)
+}
+
+// UsesGateway reports whether requests are routed through the Atlassian API
+// gateway, in which case they are subject to the credentials' scopes.
+func (c *ConfluenceClient) UsesGateway() bool {
+	return c.pathPrefix != "" && !c.dataCenter
+}
+
+// Site returns the URL of the Confluence site, even when requests go through
+// the Atlassian API gateway.
+func (c *ConfluenceClient) Site() string {
+	return c.site.String()
+}
+
+// useGateway routes every subsequent request through the Atlassian API gateway
+// for the given cloud ID.
+func (c *ConfluenceClient) useGateway(cloudId string) error {
+	gateway, err := url.Parse(atlassianGatewayUrl)
+	if err != nil {
+		return err
+	}
+	c.apiBase = gateway
+	c.cloudId = cloudId
+	c.pathPrefix = fmt.Sprintf(gatewayConfluenceUrlPath, url.PathEscape(cloudId))
+	return nil
+}
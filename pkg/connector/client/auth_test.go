@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuth2ConfluenceClient(t *testing.T) {
+	ctx := context.Background()
+
+	var requestedPaths []string
+	gateway := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requestedPaths = append(requestedPaths, request.URL.Path)
+		if request.Header.Get("Authorization") != "Bearer access-token" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writer.Header().Set(uhttp.ContentType, "application/json")
+		switch request.URL.Path {
+		case accessibleResourcesUrlPath:
+			_, _ = writer.Write([]byte(`[
+				{"id": "other-cloud", "url": "https://other.atlassian.net", "name": "other"},
+				{"id": "cloud-123", "url": "https://example.atlassian.net", "name": "example",
+				 "scopes": ["read:confluence-user", "write:confluence-groups"]}
+			]`))
+		case "/ex/confluence/cloud-123" + CurrentUserUrlPath:
+			_, _ = writer.Write([]byte(`{"accountId": "abc", "accountType": "app"}`))
+		case "/ex/confluence/cloud-123" + SpacesListUrlPath,
+			"/ex/confluence/cloud-123" + SearchUrlPath,
+			"/ex/confluence/cloud-123" + GroupsListUrlPath:
+			_, _ = writer.Write([]byte(`{"results": []}`))
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer gateway.Close()
+
+	originalGatewayUrl := atlassianGatewayUrl
+	atlassianGatewayUrl = gateway.URL
+	defer func() { atlassianGatewayUrl = originalGatewayUrl }()
+
+	t.Run("should discover the cloud ID and route through the gateway", func(t *testing.T) {
+		requestedPaths = nil
+		c, err := NewOAuth2ConfluenceClient(ctx, "example.atlassian.net", "", uhttp.NewBearerAuth("access-token"))
+		require.Nil(t, err)
+
+		err = c.Verify(ctx)
+		require.Nil(t, err)
+		require.Equal(t, []string{accessibleResourcesUrlPath, "/ex/confluence/cloud-123" + CurrentUserUrlPath}, requestedPaths)
+	})
+
+	t.Run("should skip discovery when the cloud ID is configured", func(t *testing.T) {
+		requestedPaths = nil
+		c, err := NewOAuth2ConfluenceClient(ctx, "example.atlassian.net", "cloud-123", uhttp.NewBearerAuth("access-token"))
+		require.Nil(t, err)
+
+		err = c.Verify(ctx)
+		require.Nil(t, err)
+		require.Equal(t, []string{"/ex/confluence/cloud-123" + CurrentUserUrlPath}, requestedPaths)
+	})
+
+	t.Run("should read provisioning scopes from the scopes granted to the token", func(t *testing.T) {
+		c, err := NewOAuth2ConfluenceClient(ctx, "example.atlassian.net", "cloud-123", uhttp.NewBearerAuth("access-token"))
+		require.Nil(t, err)
+
+		scopes, err := c.CheckScopes(ctx)
+		require.Nil(t, err)
+		require.Empty(t, scopes.MissingSyncScopes)
+		require.True(t, scopes.ProvisionScopesChecked)
+		require.Equal(t, []string{"write:space.permission:confluence"}, scopes.MissingProvisionScopes)
+	})
+
+	t.Run("should fail when the site was not granted", func(t *testing.T) {
+		_, err := NewOAuth2ConfluenceClient(ctx, "missing.atlassian.net", "", uhttp.NewBearerAuth("access-token"))
+		require.ErrorContains(t, err, "missing.atlassian.net")
+	})
+}
+
+func TestRotatedRefreshToken(t *testing.T) {
+	ctx := context.Background()
+
+	var usedRefreshTokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set(uhttp.ContentType, "application/json")
+		switch request.URL.Path {
+		case "/oauth/token":
+			_ = request.ParseForm()
+			usedRefreshTokens = append(usedRefreshTokens, request.PostForm.Get("refresh_token"))
+			_, _ = writer.Write([]byte(`{"access_token": "access-token", "refresh_token": "rotated-token", "token_type": "Bearer", "expires_in": 3600}`))
+		case CurrentUserUrlPath:
+			if request.Header.Get("Authorization") != "Bearer access-token" {
+				writer.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = writer.Write([]byte(`{"accountId": "abc", "accountType": "app"}`))
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	newCredentials := func(tokenFile string) *refreshTokenCredentials {
+		credentials := NewOAuth2RefreshTokenCredentials("client-id", "client-secret", "configured-token", tokenFile).(*refreshTokenCredentials)
+		credentials.config.Endpoint.TokenURL = server.URL + "/oauth/token"
+		return credentials
+	}
+
+	t.Run("should save the rotated refresh token and use it on the next run", func(t *testing.T) {
+		usedRefreshTokens = nil
+		tokenFile := filepath.Join(t.TempDir(), "refresh-token")
+
+		for range 2 {
+			c, err := newConfluenceClient(ctx, server.URL, newCredentials(tokenFile))
+			require.Nil(t, err)
+			require.Nil(t, c.Verify(ctx))
+		}
+		require.Equal(t, []string{"configured-token", "rotated-token"}, usedRefreshTokens)
+
+		saved, err := os.ReadFile(tokenFile)
+		require.Nil(t, err)
+		require.Equal(t, "rotated-token\n", string(saved))
+	})
+
+	t.Run("should use the configured refresh token without a token file", func(t *testing.T) {
+		usedRefreshTokens = nil
+		c, err := newConfluenceClient(ctx, server.URL, newCredentials(""))
+		require.Nil(t, err)
+		require.Nil(t, c.Verify(ctx))
+		require.Equal(t, []string{"configured-token"}, usedRefreshTokens)
+	})
+}
+
+func TestScopedTokenConfluenceClient(t *testing.T) {
+	ctx := context.Background()
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set(uhttp.ContentType, "application/json")
+		switch request.URL.Path {
+		case tenantInfoUrlPath:
+			_, _ = writer.Write([]byte(`{"cloudId": "cloud-456"}`))
+			return
+		case "/wiki" + CurrentUserUrlPath:
+			// Scoped tokens are refused by the site itself.
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		requests = append(requests, request.Method+" "+request.URL.Path)
+		username, password, ok := request.BasicAuth()
+		if !ok || username != "user@example.com" || password != "scoped-token" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch request.Method + " " + request.URL.Path {
+		case "GET /ex/confluence/cloud-456" + CurrentUserUrlPath:
+			_, _ = writer.Write([]byte(`{"accountId": "abc", "accountType": "atlassian"}`))
+		case "GET /ex/confluence/cloud-456" + SpacesListUrlPath:
+			_, _ = writer.Write([]byte(`{"results": [{"id": "678", "key": "PM"}], "_links": {}}`))
+		case "GET /ex/confluence/cloud-456/wiki/api/v2/spaces/678/permissions":
+			_, _ = writer.Write([]byte(`{"results": [], "_links": {}}`))
+		case "GET /ex/confluence/cloud-456" + SearchUrlPath:
+			_, _ = writer.Write([]byte(`{"results": []}`))
+		default:
+			// Anything else is outside the token's scopes.
+			writer.WriteHeader(http.StatusUnauthorized)
+			_, _ = writer.Write([]byte(`{"code": 401, "message": "Unauthorized; scope does not match"}`))
+		}
+	}))
+	defer server.Close()
+
+	originalGatewayUrl := atlassianGatewayUrl
+	atlassianGatewayUrl = server.URL
+	defer func() { atlassianGatewayUrl = originalGatewayUrl }()
+
+	c, err := NewScopedTokenConfluenceClient(ctx, "user@example.com", "scoped-token", server.URL, "")
+	require.Nil(t, err)
+	require.True(t, c.UsesGateway())
+
+	t.Run("should route through the gateway using the tenant's cloud ID", func(t *testing.T) {
+		require.Nil(t, c.Verify(ctx))
+	})
+
+	t.Run("should report missing scopes with read requests only", func(t *testing.T) {
+		scopes, err := c.CheckScopes(ctx)
+		require.Nil(t, err)
+		require.Equal(t, []string{"read:group:confluence"}, scopes.MissingSyncScopes)
+		// Scoped API tokens cannot list the scopes they were granted.
+		require.False(t, scopes.ProvisionScopesChecked)
+		require.Empty(t, scopes.MissingProvisionScopes)
+		for _, request := range requests {
+			require.True(t, strings.HasPrefix(request, "GET "), request)
+		}
+	})
+
+	t.Run("should fail when the credentials are rejected", func(t *testing.T) {
+		c, err := NewScopedTokenConfluenceClient(ctx, "user@example.com", "wrong-token", server.URL, "cloud-456")
+		require.Nil(t, err)
+		_, err = c.CheckScopes(ctx)
+		require.Error(t, err)
+	})
+}
@@ -36,10 +36,22 @@ func (r *RequestError) Error() string {
 }
 
 type ConfluenceClient struct {
-	user    string
-	apiKey  string
 	apiBase *url.URL
+	// pathPrefix is prepended to every request path: the gateway path of the
+	// site when requests are routed through the Atlassian API gateway, or the
+	// context path of a Data Center site, such as "/confluence".
+	pathPrefix string
+	// cloudId is the site's cloud ID when requests go through the gateway.
+	cloudId string
+	site    *url.URL
 	wrapper *uhttp.BaseHttpClient
+	// dataCenter routes requests to the Confluence Data Center endpoints.
+	dataCenter bool
+	limiter    *rateLimiter
+	// concurrent is set when requests may be sent from several goroutines at
+	// once. The response cache of the SDK is then bypassed, as some of its
+	// backends are not safe for concurrent use.
+	concurrent bool
 }
 
 // fallBackToHTTPS checks to domain and tacks on "https://" if no scheme is
@@ -59,26 +71,101 @@ func fallBackToHTTPS(domain string) (*url.URL, error) {
 	return parsed, nil
 }
 
+// NewConfluenceClient returns a client that authenticates against the site
+// with basic auth, using the account's email address and API token.
 func NewConfluenceClient(ctx context.Context, user, apiKey, domain string) (*ConfluenceClient, error) {
-	apiBase, err := fallBackToHTTPS(domain)
+	return newConfluenceClient(ctx, domain, uhttp.NewBasicAuth(user, apiKey))
+}
+
+// NewOAuth2ConfluenceClient returns a client that authenticates with an OAuth
+// 2.0 access token. Those tokens are only accepted by the Atlassian API
+// gateway, so requests go to api.atlassian.com/ex/confluence/{cloudId}. If
+// cloudId is empty it is discovered from the resources the token can access.
+func NewOAuth2ConfluenceClient(
+	ctx context.Context,
+	domain string,
+	cloudId string,
+	credentials uhttp.AuthCredentials,
+) (*ConfluenceClient, error) {
+	c, err := newConfluenceClient(ctx, domain, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	if cloudId == "" {
+		cloudId, err = c.discoverCloudId(ctx, c.site)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = c.useGateway(cloudId)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewScopedTokenConfluenceClient returns a client for a scoped API token.
+// Scoped tokens still use basic auth but are only accepted by the Atlassian
+// API gateway. If cloudId is empty it is read from the site's tenant info.
+func NewScopedTokenConfluenceClient(ctx context.Context, user, apiKey, domain, cloudId string) (*ConfluenceClient, error) {
+	c, err := newConfluenceClient(ctx, domain, uhttp.NewBasicAuth(user, apiKey))
+	if err != nil {
+		return nil, err
+	}
+
+	if cloudId == "" {
+		cloudId, err = c.lookupCloudId(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = c.useGateway(cloudId)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func newConfluenceClient(ctx context.Context, domain string, credentials uhttp.AuthCredentials) (*ConfluenceClient, error) {
+	site, err := fallBackToHTTPS(domain)
 	if err != nil {
 		return nil, err
 	}
 
-	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, nil))
+	httpClient, err := credentials.GetClient(ctx, uhttp.WithLogger(true, nil))
 	if err != nil {
 		return nil, err
 	}
 
 	return &ConfluenceClient{
-		apiBase: apiBase,
-		apiKey:  apiKey,
-		user:    user,
+		apiBase: site,
+		site:    site,
 		wrapper: uhttp.NewBaseHttpClient(httpClient),
+		limiter: newRateLimiter(),
 	}, nil
 }
 
+// SetRequestsPerSecond caps the rate of requests sent to Confluence. Zero
+// removes the cap; requests are still slowed down when Confluence reports that
+// the site is near its rate limits.
+func (c *ConfluenceClient) SetRequestsPerSecond(rate int) {
+	c.limiter.setRequestsPerSecond(rate)
+}
+
+// SetConcurrency tells the client how many requests may be in flight at once.
+// Above one, GET responses are no longer read from the SDK's response cache.
+func (c *ConfluenceClient) SetConcurrency(concurrency int) {
+	c.concurrent = concurrency > 1
+}
+
 func (c *ConfluenceClient) Verify(ctx context.Context) error {
+	if c.dataCenter {
+		return c.verifyDataCenter(ctx)
+	}
+
 	currentUserUrl, err := c.parse(CurrentUserUrlPath)
 	if err != nil {
 		return err
@@ -102,13 +189,11 @@ func (c *ConfluenceClient) Verify(ctx context.Context) error {
 // Use this instead of Verify when the connector is configured for RBAC mode,
 // as those credentials may only have access to the v2 API.
 func (c *ConfluenceClient) VerifyRbac(ctx context.Context) error {
-	spaceRoleModeUrl, err := c.parse(SpaceRoleModeUrlPath)
-	if err != nil {
-		return err
+	if c.dataCenter {
+		return errRbacUnsupportedOnDataCenter()
 	}
 
-	var response *SpaceRoleModeResponse
-	_, err = c.get(ctx, spaceRoleModeUrl, &response)
+	mode, _, err := c.GetSpaceRoleMode(ctx)
 	if err != nil {
 		var reqErr *RequestError
 		if errors.As(err, &reqErr) && reqErr.Status == http.StatusNotFound {
@@ -118,10 +203,9 @@ func (c *ConfluenceClient) VerifyRbac(ctx context.Context) error {
 	}
 
 	// Valid values: ROLES_TRANSITION, ROLES.
-	// PRE_ROLE means the instance has the endpoint but RBAC has not been activated.
-	// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-space-roles/#api-space-role-mode-get
-	if response.Mode == "PRE_ROLES" {
-		return status.Error(codes.FailedPrecondition, fmt.Sprintf("confluence-connector: space role mode is %q — RBAC is not enabled for this Confluence instance", response.Mode))
+	// PRE_ROLES means the instance has the endpoint but RBAC has not been activated.
+	if mode == SpaceRoleModePreRoles {
+		return status.Error(codes.FailedPrecondition, fmt.Sprintf("confluence-connector: space role mode is %q — RBAC is not enabled for this Confluence instance", mode))
 	}
 
 	// space-role-mode can keep reporting ROLES even after the instance has
@@ -155,29 +239,21 @@ func (c *ConfluenceClient) GetGroups(
 	*v2.RateLimitDescription,
 	error,
 ) {
-	groupsUrl, err := c.parse(
-		GroupsListUrlPath,
-		withLimitAndOffset(pageToken, pageSize),
-	)
-	if err != nil {
-		return nil, "", nil, err
+	path := GroupsListUrlPath
+	if c.dataCenter {
+		path = DataCenterGroupsListUrlPath
 	}
 
-	var response *confluenceGroupList
-	ratelimitData, err := c.get(ctx, groupsUrl, &response)
+	groups, nextToken, ratelimitData, err := getOffsetPage[ConfluenceGroup](ctx, c, path, pageToken, pageSize)
 	if err != nil {
 		return nil, "", ratelimitData, err
 	}
 
-	groups := response.Results
-
-	if !isThereAnotherPage(response.Links) {
-		return groups, "", ratelimitData, nil
+	if c.dataCenter {
+		groups = normalizeDataCenterGroups(groups)
 	}
 
-	token := incToken(pageToken, len(groups))
-
-	return groups, token, ratelimitData, nil
+	return groups, nextToken, ratelimitData, nil
 }
 
 func (c *ConfluenceClient) GetGroupMembers(
@@ -191,32 +267,20 @@ func (c *ConfluenceClient) GetGroupMembers(
 	*v2.RateLimitDescription,
 	error,
 ) {
-	getUsersUrl, err := c.parse(
+	if c.dataCenter {
+		return c.getGroupMembersDataCenter(ctx, pageToken, pageSize, groupId)
+	}
+
+	return getOffsetPage[ConfluenceUser](
+		ctx,
+		c,
 		fmt.Sprintf(getUsersByGroupIdUrlPath, groupId),
-		withLimitAndOffset(pageToken, pageSize),
+		pageToken,
+		pageSize,
 		withQueryParameters(map[string]interface{}{
 			"expand": "operations",
 		}),
 	)
-	if err != nil {
-		return nil, "", nil, err
-	}
-
-	var response *confluenceUserList
-	ratelimitData, err := c.get(ctx, getUsersUrl, &response)
-	if err != nil {
-		return nil, "", ratelimitData, err
-	}
-
-	users := response.Results
-
-	if !isThereAnotherPage(response.Links) {
-		return users, "", ratelimitData, nil
-	}
-
-	token := incToken(pageToken, len(users))
-
-	return users, token, ratelimitData, nil
 }
 
 func (c *ConfluenceClient) AddUserToGroup(
@@ -224,6 +288,10 @@ func (c *ConfluenceClient) AddUserToGroup(
 	accountID string,
 	groupId string,
 ) (*v2.RateLimitDescription, error) {
+	if c.dataCenter {
+		return c.addUserToGroupDataCenter(ctx, accountID, groupId)
+	}
+
 	getUsersUrl, err := c.parse(
 		groupBaseUrlPath,
 		withQueryParameters(map[string]interface{}{"groupId": groupId}),
@@ -254,6 +322,10 @@ func (c *ConfluenceClient) RemoveUserFromGroup(
 	accountID string,
 	groupId string,
 ) (*v2.RateLimitDescription, error) {
+	if c.dataCenter {
+		return c.removeUserFromGroupDataCenter(ctx, accountID, groupId)
+	}
+
 	getUsersUrl, err := c.parse(
 		groupBaseUrlPath,
 		withQueryParameters(map[string]interface{}{
@@ -272,6 +344,54 @@ func (c *ConfluenceClient) RemoveUserFromGroup(
 	return ratelimitData, nil
 }
 
+// CreateGroup creates a group with the given name.
+func (c *ConfluenceClient) CreateGroup(
+	ctx context.Context,
+	name string,
+) (*ConfluenceGroup, *v2.RateLimitDescription, error) {
+	if c.dataCenter {
+		return c.createGroupDataCenter(ctx, name)
+	}
+
+	groupsUrl, err := c.parse(GroupsListUrlPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bodyBytes, err := json.Marshal(CreateGroupRequestBody{Name: name})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ConfluenceGroup
+	ratelimitData, err := c.post(ctx, groupsUrl, &response, strings.NewReader(string(bodyBytes)))
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	return &response, ratelimitData, nil
+}
+
+// DeleteGroup deletes a group. Its members are not deleted.
+func (c *ConfluenceClient) DeleteGroup(
+	ctx context.Context,
+	groupId string,
+) (*v2.RateLimitDescription, error) {
+	if c.dataCenter {
+		return c.deleteGroupDataCenter(ctx, groupId)
+	}
+
+	groupUrl, err := c.parse(
+		groupByIdUrlPath,
+		withQueryParameters(map[string]interface{}{"id": groupId}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The group is deleted with 204 No Content.
+	return c.delete(ctx, groupUrl, nil)
+}
+
 func incToken(pageToken string, count int) string {
 	token := strToInt(pageToken)
 
@@ -302,24 +422,42 @@ func (c *ConfluenceClient) GetSpaces(
 	*v2.RateLimitDescription,
 	error,
 ) {
-	spacesListUrl, err := c.parse(
-		SpacesListUrlPath,
-		withPaginationCursor(pageSize, paginationCursor),
-	)
-	if err != nil {
-		return nil, "", nil, err
-	}
+	return c.GetFilteredSpaces(ctx, SpaceFilter{}, pageSize, paginationCursor)
+}
 
-	var response *confluenceSpaceList
-	ratelimitData, err := c.get(ctx, spacesListUrl, &response)
-	if err != nil {
-		return nil, "", ratelimitData, err
+// GetFilteredSpaces gets a page of the spaces that match the filter.
+func (c *ConfluenceClient) GetFilteredSpaces(
+	ctx context.Context,
+	filter SpaceFilter,
+	pageSize int,
+	paginationCursor string,
+) (
+	[]ConfluenceSpace,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	if c.dataCenter {
+		return c.getSpacesDataCenter(ctx, filter, pageSize, paginationCursor)
 	}
 
-	cursor := extractPaginationCursor(response.Links)
-	spaces := response.Results
+	parameters := map[string]interface{}{
+		"description-format": "plain",
+	}
+	if len(filter.Keys) > 0 {
+		parameters["keys"] = strings.Join(filter.Keys, ",")
+	}
+	if filter.Type != "" {
+		parameters["type"] = filter.Type
+	}
+	if filter.Status != "" {
+		parameters["status"] = filter.Status
+	}
+	if len(filter.Labels) > 0 {
+		parameters["labels"] = strings.Join(filter.Labels, ",")
+	}
 
-	return spaces, cursor, ratelimitData, nil
+	return getCursorPage[ConfluenceSpace](ctx, c, SpacesListUrlPath, paginationCursor, pageSize, withQueryParameters(parameters))
 }
 
 func (c *ConfluenceClient) ConfluenceSpaceOperations(
@@ -374,28 +512,17 @@ func (c *ConfluenceClient) GetSpacePermissions(
 	*v2.RateLimitDescription,
 	error,
 ) {
-	spacePermissionsListUrl, err := c.parse(
-		fmt.Sprintf(SpacePermissionsListUrlPath, spaceId),
-		withPaginationCursor(pageSize, pageToken),
-	)
-	if err != nil {
-		return nil, "", nil, err
+	if c.dataCenter {
+		return c.getSpacePermissionsDataCenter(ctx, spaceId)
 	}
 
-	var response *ConfluenceSpacePermissionResponse
-	ratelimitData, err := c.get(
+	return getCursorPage[ConfluenceSpacePermission](
 		ctx,
-		spacePermissionsListUrl,
-		&response,
+		c,
+		fmt.Sprintf(SpacePermissionsListUrlPath, spaceId),
+		pageToken,
+		pageSize,
 	)
-	if err != nil {
-		return nil, "", ratelimitData, err
-	}
-	cursor := extractPaginationCursor(response.Links)
-	permissions := make([]ConfluenceSpacePermission, 0)
-	permissions = append(permissions, response.Results...)
-
-	return permissions, cursor, ratelimitData, nil
 }
 
 // getSubjectTypeFromPrincipalType map between ConductorOne representation and
@@ -414,7 +541,7 @@ func getSubjectTypeFromPrincipalType(principalType string) (string, error) {
 
 func (c *ConfluenceClient) AddSpacePermission(
 	ctx context.Context,
-	spaceName string,
+	spaceId string,
 	key string,
 	target string,
 	principalId string,
@@ -423,8 +550,17 @@ func (c *ConfluenceClient) AddSpacePermission(
 	*v2.RateLimitDescription,
 	error,
 ) {
+	if c.dataCenter {
+		return c.updateSpacePermissionDataCenter(ctx, "grant", spaceId, key, target, principalId, principalType)
+	}
+
+	space, ratelimitData, err := c.findSpace(ctx, spaceId)
+	if err != nil {
+		return ratelimitData, err
+	}
+
 	spacePermissionsListUrl, err := c.parse(
-		fmt.Sprintf(spacePermissionsCreateUrlPath, spaceName),
+		fmt.Sprintf(spacePermissionsCreateUrlPath, space.Key),
 	)
 	if err != nil {
 		return nil, err
@@ -453,11 +589,10 @@ func (c *ConfluenceClient) AddSpacePermission(
 
 	body := strings.NewReader(string(bodyBytes))
 
-	var response bool
-	ratelimitData, err := c.post(
+	ratelimitData, err = c.post(
 		ctx,
 		spacePermissionsListUrl,
-		&response,
+		nil,
 		body,
 	)
 	if err != nil {
@@ -482,43 +617,21 @@ func (c *ConfluenceClient) findSpacePermission(
 	error,
 ) {
 	// We need to list _all_ permissions in order to figure out the permission's ID.
-	cursor := ""
-	for {
-		listPermissionsUrl, err := c.parse(
-			fmt.Sprintf(
-				SpacePermissionsListUrlPath,
-				spaceId,
-			),
-			withPaginationCursor(maxResults, cursor),
-		)
-		if err != nil {
-			return nil, nil, err
-		}
-
-		var response *ConfluenceSpacePermissionResponse
-		ratelimitData, err := c.get(
-			ctx,
-			listPermissionsUrl,
-			&response,
-		)
+	permissions := c.SpacePermissions(spaceId)
+	for permission, err := range permissions.All(ctx) {
 		if err != nil {
-			return nil, ratelimitData, err
+			return nil, permissions.RateLimit(), err
 		}
-		for _, permission := range response.Results {
-			if permission.Principal.Id == principalId &&
-				permission.Principal.Type == principalType &&
-				permission.Operation.Key == key &&
-				permission.Operation.TargetType == target {
-				return &permission, ratelimitData, nil
-			}
-		}
-		cursor = extractPaginationCursor(response.Links)
-		if cursor == "" {
-			break
+		// Anonymous permissions have no principal ID.
+		if (permission.Principal.Id == principalId || principalType == SpacePermissionPrincipalAnonymous) &&
+			permission.Principal.Type == principalType &&
+			permission.Operation.Key == key &&
+			permission.Operation.TargetType == target {
+			return &permission, permissions.RateLimit(), nil
 		}
 	}
 
-	return nil, nil, fmt.Errorf("space permission not found")
+	return nil, permissions.RateLimit(), fmt.Errorf("space permission not found")
 }
 
 // findSpace - The v1 and v2 API are slightly different. The former uses "space
@@ -564,6 +677,10 @@ func (c *ConfluenceClient) RemoveSpacePermission(
 	*v2.RateLimitDescription,
 	error,
 ) {
+	if c.dataCenter {
+		return c.updateSpacePermissionDataCenter(ctx, "revoke", spaceId, key, target, principalId, principalType)
+	}
+
 	permission, ratelimitData, err := c.findSpacePermission(
 		ctx,
 		spaceId,
@@ -593,11 +710,11 @@ func (c *ConfluenceClient) RemoveSpacePermission(
 		return nil, err
 	}
 
-	var response bool
+	// The permission is deleted with 204 No Content.
 	ratelimitData, err = c.delete(
 		ctx,
 		deletePermissionUrl,
-		&response,
+		nil,
 	)
 	if err != nil {
 		return ratelimitData, err
@@ -621,7 +738,14 @@ func (c *ConfluenceClient) GetSpaceById(
 	ctx context.Context,
 	spaceId string,
 ) (*ConfluenceSpace, *v2.RateLimitDescription, error) {
-	spaceUrl, err := c.parse(fmt.Sprintf(spacesGetUrlPath, spaceId))
+	if c.dataCenter {
+		return c.getSpaceDataCenter(ctx, spaceId)
+	}
+
+	spaceUrl, err := c.parse(
+		fmt.Sprintf(spacesGetUrlPath, spaceId),
+		withQueryParameters(map[string]interface{}{"description-format": "plain"}),
+	)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -633,6 +757,23 @@ func (c *ConfluenceClient) GetSpaceById(
 	return &response, ratelimitData, nil
 }
 
+// GetSpaceRoleMode returns whether the site manages space access with
+// granular permissions (PRE_ROLES), space roles (ROLES), or both while it
+// migrates (ROLES_TRANSITION).
+func (c *ConfluenceClient) GetSpaceRoleMode(ctx context.Context) (string, *v2.RateLimitDescription, error) {
+	spaceRoleModeUrl, err := c.parse(SpaceRoleModeUrlPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var response *SpaceRoleModeResponse
+	ratelimitData, err := c.get(ctx, spaceRoleModeUrl, &response)
+	if err != nil {
+		return "", ratelimitData, err
+	}
+	return response.Mode, ratelimitData, nil
+}
+
 // GetSpaceRoles fetches space roles from the v2 API, optionally filtered by spaceId.
 func (c *ConfluenceClient) GetSpaceRoles(
 	ctx context.Context,
@@ -645,24 +786,91 @@ func (c *ConfluenceClient) GetSpaceRoles(
 	*v2.RateLimitDescription,
 	error,
 ) {
-	options := []Option{withPaginationCursor(pageSize, cursor)}
+	var options []Option
 	if spaceId != "" {
 		options = append(options, withQueryParameters(map[string]interface{}{"space-id": spaceId}))
 	}
 
-	spaceRolesUrl, err := c.parse(SpaceRolesUrlPath, options...)
+	return getCursorPage[SpaceRole](ctx, c, SpaceRolesUrlPath, cursor, pageSize, options...)
+}
+
+// GetSpaceRole fetches a single space role by its ID.
+func (c *ConfluenceClient) GetSpaceRole(
+	ctx context.Context,
+	roleId string,
+) (*SpaceRole, *v2.RateLimitDescription, error) {
+	roleUrl, err := c.parse(fmt.Sprintf(spaceRoleUrlPath, url.PathEscape(roleId)))
 	if err != nil {
-		return nil, "", nil, err
+		return nil, nil, err
+	}
+	var response SpaceRole
+	ratelimitData, err := c.get(ctx, roleUrl, &response)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	return &response, ratelimitData, nil
+}
+
+// CreateSpaceRole defines a custom space role.
+func (c *ConfluenceClient) CreateSpaceRole(
+	ctx context.Context,
+	role SpaceRoleRequest,
+) (*SpaceRole, *v2.RateLimitDescription, error) {
+	rolesUrl, err := c.parse(SpaceRolesUrlPath)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	var response *SpaceRolesResponse
-	ratelimitData, err := c.get(ctx, spaceRolesUrl, &response)
+	bodyBytes, err := json.Marshal(role)
 	if err != nil {
-		return nil, "", ratelimitData, err
+		return nil, nil, err
 	}
 
-	nextCursor := extractPaginationCursor(response.Links)
-	return response.Results, nextCursor, ratelimitData, nil
+	var response SpaceRole
+	ratelimitData, err := c.post(ctx, rolesUrl, &response, strings.NewReader(string(bodyBytes)))
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	return &response, ratelimitData, nil
+}
+
+// UpdateSpaceRole replaces the name, description and space permissions of a
+// custom space role.
+func (c *ConfluenceClient) UpdateSpaceRole(
+	ctx context.Context,
+	roleId string,
+	role SpaceRoleRequest,
+) (*SpaceRole, *v2.RateLimitDescription, error) {
+	roleUrl, err := c.parse(fmt.Sprintf(spaceRoleUrlPath, url.PathEscape(roleId)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bodyBytes, err := json.Marshal(role)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response SpaceRole
+	ratelimitData, err := c.put(ctx, roleUrl, &response, strings.NewReader(string(bodyBytes)))
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	return &response, ratelimitData, nil
+}
+
+// DeleteSpaceRole deletes a custom space role.
+func (c *ConfluenceClient) DeleteSpaceRole(
+	ctx context.Context,
+	roleId string,
+) (*v2.RateLimitDescription, error) {
+	roleUrl, err := c.parse(fmt.Sprintf(spaceRoleUrlPath, url.PathEscape(roleId)))
+	if err != nil {
+		return nil, err
+	}
+
+	// The role is deleted with 204 No Content.
+	return c.delete(ctx, roleUrl, nil)
 }
 
 // GetSpaceRoleAssignments fetches role assignments for a given space.
@@ -681,7 +889,7 @@ func (c *ConfluenceClient) GetSpaceRoleAssignments(
 	*v2.RateLimitDescription,
 	error,
 ) {
-	options := []Option{withPaginationCursor(pageSize, cursor)}
+	var options []Option
 	if roleId != "" {
 		options = append(options, withQueryParameters(map[string]interface{}{"role-id": roleId}))
 	}
@@ -692,22 +900,14 @@ func (c *ConfluenceClient) GetSpaceRoleAssignments(
 		options = append(options, withQueryParameters(map[string]interface{}{"principal-type": principalType}))
 	}
 
-	assignmentsUrl, err := c.parse(
+	return getCursorPage[SpaceRoleAssignment](
+		ctx,
+		c,
 		fmt.Sprintf(SpaceRoleAssignmentsUrlPath, url.PathEscape(spaceId)),
+		cursor,
+		pageSize,
 		options...,
 	)
-	if err != nil {
-		return nil, "", nil, err
-	}
-
-	var response *SpaceRoleAssignmentsResponse
-	ratelimitData, err := c.get(ctx, assignmentsUrl, &response)
-	if err != nil {
-		return nil, "", ratelimitData, err
-	}
-
-	nextCursor := extractPaginationCursor(response.Links)
-	return response.Results, nextCursor, ratelimitData, nil
 }
 
 // SetSpaceRoleAssignment adds role assignments for a space.
@@ -752,6 +952,10 @@ func (c *ConfluenceClient) GetUsersFromSearch(
 	*v2.RateLimitDescription,
 	error,
 ) {
+	if c.dataCenter {
+		return c.getUsersFromSearchDataCenter(ctx, pageToken, pageSize)
+	}
+
 	getUsersUrl, err := c.parse(
 		SearchUrlPath,
 		withLimitAndOffset(pageToken, pageSize),
@@ -775,8 +979,9 @@ func (c *ConfluenceClient) GetUsersFromSearch(
 		users = append(users, user.User)
 	}
 
-	// The only way we can tell that we've hit the end of the list is if we get
-	// back fewer results than we asked for. If we get the last page but there
+	// Unlike other v1 listings, search results have no "next" link. The only
+	// way we can tell that we've hit the end of the list is if we get back
+	// fewer results than we asked for. If we get the last page but there
 	// are `pageSize`, then `.List()` still has to fetch the blank next page.
 	if len(users) < pageSize {
 		return users, "", ratelimitData, nil
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// Content restrictions are only in the v1 REST API.
+const (
+	ContentTypePage     = "page"
+	ContentTypeBlogpost = "blogpost"
+
+	ContentOperationRead   = "read"
+	ContentOperationUpdate = "update"
+)
+
+// contentRestrictionExpansions expands the restrictions of each piece of
+// content, and the read restrictions of its ancestors, which are inherited.
+var contentRestrictionExpansions = strings.Join([]string{
+	"restrictions.read.restrictions.user",
+	"restrictions.read.restrictions.group",
+	"restrictions.update.restrictions.user",
+	"restrictions.update.restrictions.group",
+	"ancestors.restrictions.read.restrictions.user",
+	"ancestors.restrictions.read.restrictions.group",
+}, ",")
+
+// GetSpaceContent fetches a page of the pages or blog posts of a space, with
+// their restrictions. Confluence cannot search by restriction, so every piece
+// of content is returned.
+func (c *ConfluenceClient) GetSpaceContent(
+	ctx context.Context,
+	spaceId string,
+	contentType string,
+	pageToken string,
+	pageSize int,
+) (
+	[]ConfluenceContent,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	return getCursorPage[ConfluenceContent](
+		ctx,
+		c,
+		ContentSearchUrlPath,
+		pageToken,
+		pageSize,
+		withQueryParameters(map[string]interface{}{
+			"cql":    fmt.Sprintf("space.id = %s AND type = %s", spaceId, contentType),
+			"expand": contentRestrictionExpansions,
+		}),
+	)
+}
+
+// SpaceContent lists the pages or blog posts of a space.
+func (c *ConfluenceClient) SpaceContent(spaceId string, contentType string) *Pager[ConfluenceContent] {
+	return newPager(defaultSize, func(ctx context.Context, pageToken string, pageSize int) ([]ConfluenceContent, string, *v2.RateLimitDescription, error) {
+		return c.GetSpaceContent(ctx, spaceId, contentType, pageToken, pageSize)
+	})
+}
+
+// GetContentRestriction fetches the restriction of an operation on a page or
+// blog post, with every user and group it names. Confluence pages the users
+// and groups together, with the same start and limit, so pages are read until
+// both run out.
+func (c *ConfluenceClient) GetContentRestriction(
+	ctx context.Context,
+	contentId string,
+	operation string,
+) (
+	*ConfluenceContentRestriction,
+	*v2.RateLimitDescription,
+	error,
+) {
+	rv := &ConfluenceContentRestriction{Operation: operation}
+	start := 0
+	for {
+		restrictionUrl, err := c.parse(
+			fmt.Sprintf(contentRestrictionUrlPath, url.PathEscape(contentId), url.PathEscape(operation)),
+			withQueryParameters(map[string]interface{}{
+				"expand": "restrictions.user,restrictions.group",
+				"start":  start,
+				"limit":  defaultSize,
+			}),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var response *ConfluenceContentRestriction
+		ratelimitData, err := c.get(ctx, restrictionUrl, &response)
+		if err != nil {
+			return nil, ratelimitData, err
+		}
+		users, groups := response.Restrictions.User, response.Restrictions.Group
+		rv.Restrictions.User.Results = append(rv.Restrictions.User.Results, users.Results...)
+		rv.Restrictions.Group.Results = append(rv.Restrictions.Group.Results, groups.Results...)
+		if !users.full() && !groups.full() {
+			return rv, ratelimitData, nil
+		}
+		start += max(users.Limit, groups.Limit)
+	}
+}
+
+// contentRestrictionUrl addresses a user or group in the restriction of an
+// operation on a page or blog post. Users are passed as a query parameter
+// and groups in the path.
+func (c *ConfluenceClient) contentRestrictionUrl(
+	contentId string,
+	operation string,
+	principalId string,
+	principalType string,
+) (*url.URL, error) {
+	subjectType, err := getSubjectTypeFromPrincipalType(principalType)
+	if err != nil {
+		return nil, err
+	}
+	if subjectType == "group" {
+		return c.parse(fmt.Sprintf(
+			contentRestrictionGroupUrlPath,
+			url.PathEscape(contentId),
+			url.PathEscape(operation),
+			url.PathEscape(principalId),
+		))
+	}
+	return c.parse(
+		fmt.Sprintf(contentRestrictionUserUrlPath, url.PathEscape(contentId), url.PathEscape(operation)),
+		withQueryParameters(map[string]interface{}{"accountId": principalId}),
+	)
+}
+
+// AddContentRestriction adds a user or group to the restriction of an
+// operation on a page or blog post.
+func (c *ConfluenceClient) AddContentRestriction(
+	ctx context.Context,
+	contentId string,
+	operation string,
+	principalId string,
+	principalType string,
+) (*v2.RateLimitDescription, error) {
+	restrictionUrl, err := c.contentRestrictionUrl(contentId, operation, principalId, principalType)
+	if err != nil {
+		return nil, err
+	}
+	return c.put(ctx, restrictionUrl, nil, nil)
+}
+
+// RemoveContentRestriction removes a user or group from the restriction of
+// an operation on a page or blog post.
+func (c *ConfluenceClient) RemoveContentRestriction(
+	ctx context.Context,
+	contentId string,
+	operation string,
+	principalId string,
+	principalType string,
+) (*v2.RateLimitDescription, error) {
+	restrictionUrl, err := c.contentRestrictionUrl(contentId, operation, principalId, principalType)
+	if err != nil {
+		return nil, err
+	}
+	return c.delete(ctx, restrictionUrl, nil)
+}
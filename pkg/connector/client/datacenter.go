@@ -0,0 +1,432 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// dataCenterAccountType stands in for Cloud's account type. Data Center has no
+// app accounts, so every "known" user is a person.
+const dataCenterAccountType = "atlassian"
+
+// NewDataCenterConfluenceClient returns a client for Confluence Data Center or
+// Server. Those deployments only have the v1 REST API, so every list and
+// provisioning method is routed to its Data Center counterpart and the
+// responses are normalized into the Cloud models. Users are identified by
+// their user key, groups by their name and spaces by their key.
+func NewDataCenterConfluenceClient(
+	ctx context.Context,
+	domain string,
+	credentials uhttp.AuthCredentials,
+) (*ConfluenceClient, error) {
+	c, err := newConfluenceClient(ctx, domain, credentials)
+	if err != nil {
+		return nil, err
+	}
+	c.dataCenter = true
+	// Data Center is commonly served under a context path, e.g. /confluence.
+	c.pathPrefix = strings.TrimSuffix(c.site.Path, "/")
+	return c, nil
+}
+
+// IsDataCenter reports whether the client talks to Confluence Data Center.
+func (c *ConfluenceClient) IsDataCenter() bool {
+	return c.dataCenter
+}
+
+func (c *ConfluenceClient) verifyDataCenter(ctx context.Context) error {
+	currentUserUrl, err := c.parse(DataCenterCurrentUserUrlPath)
+	if err != nil {
+		return err
+	}
+
+	var response *ConfluenceUser
+	_, err = c.get(ctx, currentUserUrl, &response)
+	if err != nil {
+		return err
+	}
+
+	// Data Center answers anonymously instead of failing when the token is
+	// not accepted.
+	if response.UserKey == "" {
+		return errors.New("failed to find new user")
+	}
+
+	return nil
+}
+
+func normalizeDataCenterUser(user ConfluenceUser) ConfluenceUser {
+	user.AccountId = user.UserKey
+	user.AccountType = dataCenterAccountType
+	return user
+}
+
+func normalizeDataCenterGroups(groups []ConfluenceGroup) []ConfluenceGroup {
+	for i := range groups {
+		groups[i].Id = groups[i].Name
+	}
+	return groups
+}
+
+func (c *ConfluenceClient) getGroupMembersDataCenter(
+	ctx context.Context,
+	pageToken string,
+	pageSize int,
+	groupName string,
+) (
+	[]ConfluenceUser,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	members, nextToken, ratelimitData, err := getOffsetPage[ConfluenceUser](
+		ctx,
+		c,
+		fmt.Sprintf(dataCenterGroupMembersUrlPath, url.PathEscape(groupName)),
+		pageToken,
+		pageSize,
+	)
+	if err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	users := make([]ConfluenceUser, 0, len(members))
+	for _, user := range members {
+		users = append(users, normalizeDataCenterUser(user))
+	}
+
+	return users, nextToken, ratelimitData, nil
+}
+
+func (c *ConfluenceClient) getUsersFromSearchDataCenter(
+	ctx context.Context,
+	pageToken string,
+	pageSize int,
+) (
+	[]ConfluenceUser,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	searchUrl, err := c.parse(
+		DataCenterSearchUrlPath,
+		withLimitAndOffset(pageToken, pageSize),
+		withQueryParameters(map[string]interface{}{
+			"cql": "type=user",
+		}),
+	)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var response *ConfluenceSearchList
+	ratelimitData, err := c.get(ctx, searchUrl, &response)
+	if err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	users := make([]ConfluenceUser, 0, len(response.Results))
+	for _, result := range response.Results {
+		users = append(users, normalizeDataCenterUser(result.User))
+	}
+
+	if len(users) < pageSize {
+		return users, "", ratelimitData, nil
+	}
+
+	return users, incToken(pageToken, len(users)), ratelimitData, nil
+}
+
+// findUsernameDataCenter resolves a user key to the username that the group
+// membership endpoints expect.
+func (c *ConfluenceClient) findUsernameDataCenter(
+	ctx context.Context,
+	userKey string,
+) (string, *v2.RateLimitDescription, error) {
+	userUrl, err := c.parse(
+		DataCenterUserUrlPath,
+		withQueryParameters(map[string]interface{}{"key": userKey}),
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var response *ConfluenceUser
+	ratelimitData, err := c.get(ctx, userUrl, &response)
+	if err != nil {
+		return "", ratelimitData, err
+	}
+	if response.Username == "" {
+		return "", ratelimitData, fmt.Errorf("confluence-connector: no username found for user key %s", userKey)
+	}
+	return response.Username, ratelimitData, nil
+}
+
+// findUserKeyDataCenter resolves a username to the user key users are
+// identified by, or "" if there is no such user.
+func (c *ConfluenceClient) findUserKeyDataCenter(
+	ctx context.Context,
+	username string,
+) (string, *v2.RateLimitDescription, error) {
+	userUrl, err := c.parse(
+		DataCenterUserUrlPath,
+		withQueryParameters(map[string]interface{}{"username": username}),
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var response *ConfluenceUser
+	ratelimitData, err := c.get(ctx, userUrl, &response)
+	if err != nil {
+		var reqErr *RequestError
+		if errors.As(err, &reqErr) && reqErr.Status == http.StatusNotFound {
+			return "", ratelimitData, nil
+		}
+		return "", ratelimitData, err
+	}
+	return response.UserKey, ratelimitData, nil
+}
+
+func (c *ConfluenceClient) userGroupUrlDataCenter(
+	ctx context.Context,
+	userKey string,
+	groupName string,
+) (*url.URL, *v2.RateLimitDescription, error) {
+	username, ratelimitData, err := c.findUsernameDataCenter(ctx, userKey)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+
+	membershipUrl, err := c.parse(
+		fmt.Sprintf(
+			dataCenterUserGroupUrlPath,
+			url.PathEscape(username),
+			url.PathEscape(groupName),
+		),
+	)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	return membershipUrl, ratelimitData, nil
+}
+
+func (c *ConfluenceClient) addUserToGroupDataCenter(
+	ctx context.Context,
+	userKey string,
+	groupName string,
+) (*v2.RateLimitDescription, error) {
+	membershipUrl, ratelimitData, err := c.userGroupUrlDataCenter(ctx, userKey, groupName)
+	if err != nil {
+		return ratelimitData, err
+	}
+	return c.put(ctx, membershipUrl, nil, nil)
+}
+
+func (c *ConfluenceClient) removeUserFromGroupDataCenter(
+	ctx context.Context,
+	userKey string,
+	groupName string,
+) (*v2.RateLimitDescription, error) {
+	membershipUrl, ratelimitData, err := c.userGroupUrlDataCenter(ctx, userKey, groupName)
+	if err != nil {
+		return ratelimitData, err
+	}
+	return c.delete(ctx, membershipUrl, nil)
+}
+
+func (c *ConfluenceClient) createGroupDataCenter(
+	ctx context.Context,
+	name string,
+) (*ConfluenceGroup, *v2.RateLimitDescription, error) {
+	groupsUrl, err := c.parse(dataCenterAdminGroupsUrlPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bodyBytes, err := json.Marshal(CreateGroupRequestBody{Name: name})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ConfluenceGroup
+	ratelimitData, err := c.post(ctx, groupsUrl, &response, strings.NewReader(string(bodyBytes)))
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	// Data Center groups have no ID, they are identified by name.
+	response.Id = response.Name
+	return &response, ratelimitData, nil
+}
+
+func (c *ConfluenceClient) deleteGroupDataCenter(
+	ctx context.Context,
+	groupName string,
+) (*v2.RateLimitDescription, error) {
+	groupUrl, err := c.parse(fmt.Sprintf(dataCenterAdminGroupUrlPath, url.PathEscape(groupName)))
+	if err != nil {
+		return nil, err
+	}
+	return c.delete(ctx, groupUrl, nil)
+}
+
+func (c *ConfluenceClient) getSpacesDataCenter(
+	ctx context.Context,
+	filter SpaceFilter,
+	pageSize int,
+	pageToken string,
+) (
+	[]ConfluenceSpace,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	parameters := map[string]interface{}{
+		"expand": dataCenterSpaceExpand,
+	}
+	if filter.Type != "" {
+		parameters["type"] = filter.Type
+	}
+	if filter.Status != "" {
+		parameters["status"] = filter.Status
+	}
+
+	results, nextToken, ratelimitData, err := getOffsetPage[dataCenterSpace](
+		ctx,
+		c,
+		DataCenterSpacesListUrlPath,
+		pageToken,
+		pageSize,
+		withQueryParameters(parameters),
+		withQueryValues("spaceKey", filter.Keys),
+		withQueryValues("label", filter.Labels),
+	)
+	if err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	spaces := make([]ConfluenceSpace, 0, len(results))
+	for _, space := range results {
+		spaces = append(spaces, *space.toSpace())
+	}
+
+	return spaces, nextToken, ratelimitData, nil
+}
+
+// getSpacePermissionsDataCenter expands the permissions of a single space. Data
+// Center returns them all at once, grouped by operation, so there is never a
+// next page.
+func (c *ConfluenceClient) getSpacePermissionsDataCenter(
+	ctx context.Context,
+	spaceKey string,
+) (
+	[]ConfluenceSpacePermission,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	spaceUrl, err := c.parse(
+		fmt.Sprintf(dataCenterSpaceGetUrlPath, url.PathEscape(spaceKey)),
+		withQueryParameters(map[string]interface{}{"expand": "permissions"}),
+	)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var response *dataCenterSpaceWithPermissions
+	ratelimitData, err := c.get(ctx, spaceUrl, &response)
+	if err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	permissions := make([]ConfluenceSpacePermission, 0)
+	for _, permission := range response.Permissions {
+		operation := ConfluenceSpacePermissionOperation{
+			Key:        permission.Operation.Operation,
+			TargetType: permission.Operation.TargetType,
+		}
+		for _, user := range permission.Subjects.User.Results {
+			permissions = append(permissions, ConfluenceSpacePermission{
+				Principal: ConfluenceSpacePermissionPrincipal{Id: user.UserKey, Type: "user"},
+				Operation: operation,
+			})
+		}
+		for _, group := range permission.Subjects.Group.Results {
+			permissions = append(permissions, ConfluenceSpacePermission{
+				Principal: ConfluenceSpacePermissionPrincipal{Id: group.Name, Type: "group"},
+				Operation: operation,
+			})
+		}
+		if permission.AnonymousAccess {
+			permissions = append(permissions, ConfluenceSpacePermission{
+				Principal: ConfluenceSpacePermissionPrincipal{Type: SpacePermissionPrincipalAnonymous},
+				Operation: operation,
+			})
+		}
+	}
+
+	return permissions, "", ratelimitData, nil
+}
+
+// updateSpacePermissionDataCenter grants or revokes a single operation. Data
+// Center addresses permissions by subject, so unlike Cloud there is no need to
+// look up a permission ID before revoking it.
+func (c *ConfluenceClient) updateSpacePermissionDataCenter(
+	ctx context.Context,
+	action string,
+	spaceKey string,
+	key string,
+	target string,
+	principalId string,
+	principalType string,
+) (*v2.RateLimitDescription, error) {
+	var path string
+	if principalType == SpacePermissionPrincipalAnonymous {
+		path = fmt.Sprintf(dataCenterSpaceAnonymousPermissionsUrlPath, url.PathEscape(spaceKey), action)
+	} else {
+		subjectType, err := getSubjectTypeFromPrincipalType(principalType)
+		if err != nil {
+			return nil, err
+		}
+		path = fmt.Sprintf(
+			dataCenterSpacePermissionsUrlPath,
+			url.PathEscape(spaceKey),
+			subjectType,
+			url.PathEscape(principalId),
+			action,
+		)
+	}
+
+	permissionUrl, err := c.parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyBytes, err := json.Marshal(
+		[]dataCenterSpacePermissionOperation{
+			{
+				OperationKey: key,
+				TargetType:   target,
+			},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.put(ctx, permissionUrl, nil, strings.NewReader(string(bodyBytes)))
+}
+
+func errRbacUnsupportedOnDataCenter() error {
+	return status.Error(codes.FailedPrecondition, "confluence-connector: RBAC space roles are only available on Confluence Cloud")
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataCenterConfluenceClient(t *testing.T) {
+	ctx := context.Background()
+
+	var lastBody string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer pat" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writer.Header().Set(uhttp.ContentType, "application/json")
+		switch request.Method + " " + request.URL.EscapedPath() {
+		case "GET /confluence/rest/api/user/current":
+			_, _ = writer.Write([]byte(`{"type": "known", "username": "admin", "userKey": "key-admin"}`))
+		case "GET /confluence/rest/api/user":
+			if request.URL.Query().Get("username") != "jdoe" {
+				writer.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = writer.Write([]byte(`{"type": "known", "username": "jdoe", "userKey": "key-jdoe"}`))
+		case "GET /confluence/rest/api/group":
+			_, _ = writer.Write([]byte(`{"results": [{"type": "group", "name": "confluence-users"}], "_links": {}}`))
+		case "GET /confluence/rest/api/group/confluence-users/member":
+			_, _ = writer.Write([]byte(`{"results": [{"type": "known", "username": "jdoe", "userKey": "key-jdoe", "displayName": "J Doe"}], "_links": {}}`))
+		case "GET /confluence/rest/api/space":
+			_, _ = writer.Write([]byte(`{"results": [{"id": 98305, "key": "DS", "name": "Demo", "type": "global", "status": "current", "history": {"createdDate": "2024-01-15T10:00:00.000Z", "createdBy": {"type": "known", "username": "jdoe", "userKey": "8a7f808a"}}}], "_links": {"next": "/rest/api/space?start=1"}}`))
+		case "GET /confluence/rest/api/space/DS":
+			_, _ = writer.Write([]byte(`{"key": "DS", "permissions": [
+				{"operation": {"operation": "read", "targetType": "space"}, "subjects": {
+					"user": {"results": [{"type": "known", "userKey": "key-jdoe"}]},
+					"group": {"results": [{"type": "group", "name": "confluence-users"}]}
+				}},
+				{"operation": {"operation": "read", "targetType": "space"}, "anonymousAccess": true}
+			]}`))
+		case "PUT /confluence/rest/api/space/DS/permissions/group/confluence-users/grant":
+			body, _ := io.ReadAll(request.Body)
+			lastBody = string(body)
+			writer.WriteHeader(http.StatusNoContent)
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewDataCenterConfluenceClient(ctx, server.URL+"/confluence/", uhttp.NewBearerAuth("pat"))
+	require.Nil(t, err)
+	require.True(t, c.IsDataCenter())
+
+	t.Run("should verify with the current user", func(t *testing.T) {
+		require.Nil(t, c.Verify(ctx))
+	})
+
+	t.Run("should refuse RBAC", func(t *testing.T) {
+		require.Error(t, c.VerifyRbac(ctx))
+	})
+
+	t.Run("should identify groups by name and users by key", func(t *testing.T) {
+		groups, token, _, err := c.GetGroups(ctx, "", 10)
+		require.Nil(t, err)
+		require.Equal(t, "", token)
+		require.Len(t, groups, 1)
+		require.Equal(t, "confluence-users", groups[0].Id)
+
+		users, token, _, err := c.GetGroupMembers(ctx, "", 10, groups[0].Id)
+		require.Nil(t, err)
+		require.Equal(t, "", token)
+		require.Len(t, users, 1)
+		require.Equal(t, "key-jdoe", users[0].AccountId)
+		require.Equal(t, "atlassian", users[0].AccountType)
+	})
+
+	t.Run("should identify spaces by key and paginate by offset", func(t *testing.T) {
+		spaces, token, _, err := c.GetSpaces(ctx, 1, "")
+		require.Nil(t, err)
+		require.Equal(t, "1", token)
+		require.Len(t, spaces, 1)
+		require.Equal(t, "DS", spaces[0].Id)
+	})
+
+	t.Run("should read the creator of a space from its history", func(t *testing.T) {
+		spaces, _, _, err := c.GetSpaces(ctx, 1, "")
+		require.Nil(t, err)
+		require.Equal(t, "8a7f808a", spaces[0].AuthorId)
+		require.Equal(t, "2024-01-15T10:00:00.000Z", spaces[0].CreatedAt)
+	})
+
+	t.Run("should find the owner of a personal space by username", func(t *testing.T) {
+		owner, _, err := c.GetPersonalSpaceOwner(ctx, &ConfluenceSpace{Id: "~jdoe", Key: "~jdoe", Type: "personal"})
+		require.Nil(t, err)
+		require.Equal(t, "key-jdoe", owner)
+
+		owner, _, err = c.GetPersonalSpaceOwner(ctx, &ConfluenceSpace{Id: "~gone", Key: "~gone", Type: "personal"})
+		require.Nil(t, err)
+		require.Empty(t, owner)
+	})
+
+	t.Run("should flatten space permissions by subject", func(t *testing.T) {
+		permissions, token, _, err := c.GetSpacePermissions(ctx, "", 10, "DS")
+		require.Nil(t, err)
+		require.Equal(t, "", token)
+		require.Len(t, permissions, 3)
+		require.Equal(t, ConfluenceSpacePermissionPrincipal{Id: "key-jdoe", Type: "user"}, permissions[0].Principal)
+		require.Equal(t, ConfluenceSpacePermissionPrincipal{Id: "confluence-users", Type: "group"}, permissions[1].Principal)
+		require.Equal(t, "read", permissions[1].Operation.Key)
+		require.Equal(t, ConfluenceSpacePermissionPrincipal{Type: SpacePermissionPrincipalAnonymous}, permissions[2].Principal)
+	})
+
+	t.Run("should grant space permissions by subject", func(t *testing.T) {
+		_, err := c.AddSpacePermission(ctx, "DS", "read", "space", "confluence-users", "group")
+		require.Nil(t, err)
+		require.JSONEq(t, `[{"operationKey": "read", "targetType": "space"}]`, lastBody)
+	})
+}
@@ -0,0 +1,323 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxSlowdown caps how much the limiter stretches the request interval
+	// after Confluence reports that the site is near its limits.
+	maxSlowdown = 16.0
+	// slowdownRecovery is the factor the slowdown shrinks by with every
+	// response that reports no pressure.
+	slowdownRecovery = 0.9
+	// minNearLimitInterval is the request interval used while the site is near
+	// its limits and no fixed rate is configured.
+	minNearLimitInterval = 50 * time.Millisecond
+	// maxPause bounds how long a single Retry-After or reset can hold requests,
+	// so that a bogus header cannot stall a sync.
+	maxPause = 5 * time.Minute
+)
+
+// rateLimiter paces requests so that they stay inside Confluence's limits
+// instead of running into 429s. It combines three signals:
+//
+//   - a fixed requests-per-second cap, if one is configured;
+//   - the request-based limit headers (X-RateLimit-Remaining and -Reset,
+//     X-RateLimit-NearLimit and Retry-After), which spread the remaining
+//     requests until the reset and pause after a 429;
+//   - the points-based quota headers (RateLimit and Beta-RateLimit, e.g.
+//     `"global-app-quota";r=950;t=1200`), for which it budgets the estimated
+//     points cost of each request against the points remaining.
+//
+// https://developer.atlassian.com/cloud/confluence/rate-limiting/
+type rateLimiter struct {
+	mu sync.Mutex
+
+	// interval is the spacing from the configured requests-per-second cap.
+	interval time.Duration
+	// slowdown stretches the interval after NearLimit or Retry-After.
+	slowdown float64
+	// pacing is the spacing that spends the remaining budget evenly until
+	// the window resets.
+	pacing time.Duration
+	// next is the earliest time the next request may start.
+	next time.Time
+	// pausedUntil holds every request after a Retry-After or an exhausted
+	// budget.
+	pausedUntil time.Time
+
+	// budgetPoints is set for Confluence, whose points-based quota applies to
+	// the site and not to other Atlassian APIs.
+	budgetPoints bool
+	points       pointsBudget
+	pointsKnown  bool
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// pointsBudget tracks the points-based quota. cost is a running estimate of
+// the points a request costs, learned from how fast the budget drains.
+type pointsBudget struct {
+	remaining float64
+	resetAt   time.Time
+	cost      float64
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		slowdown:     1,
+		budgetPoints: true,
+		points:       pointsBudget{cost: 1},
+		now:          time.Now,
+		sleep:        sleepContext,
+	}
+}
+
+// newOrgRateLimiter returns a limiter for the organization admin API, which
+// has limits of its own, separate from the site's: it is paced by 429s,
+// Retry-After and the request-based limit headers only.
+func newOrgRateLimiter() *rateLimiter {
+	l := newRateLimiter()
+	l.budgetPoints = false
+	return l
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// setRequestsPerSecond caps the request rate. Zero or less removes the cap,
+// leaving only the adaptive pacing.
+func (l *rateLimiter) setRequestsPerSecond(rate int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rate <= 0 {
+		l.interval = 0
+		return
+	}
+	l.interval = time.Second / time.Duration(rate)
+}
+
+// wait blocks until the next request may be sent and reserves its slot.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := l.now()
+	start := latest(now, l.next, l.pausedUntil)
+
+	if l.pointsKnown && now.Before(l.points.resetAt) {
+		if l.points.remaining < l.points.cost {
+			start = latest(start, l.points.resetAt)
+		} else {
+			l.points.remaining -= l.points.cost
+		}
+	}
+
+	l.next = start.Add(l.spacing())
+	l.mu.Unlock()
+
+	if delay := start.Sub(now); delay > 0 {
+		return l.sleep(ctx, delay)
+	}
+	return nil
+}
+
+// spacing is the interval to keep between two request starts.
+func (l *rateLimiter) spacing() time.Duration {
+	interval := l.interval
+	if l.slowdown > 1 && interval == 0 {
+		interval = minNearLimitInterval
+	}
+	return max(time.Duration(float64(interval)*l.slowdown), l.pacing)
+}
+
+// observe updates the pacing from the rate limit headers of a response.
+func (l *rateLimiter) observe(response *http.Response) {
+	if response == nil {
+		return
+	}
+	header := response.Header
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+
+	retryAfter, hasRetryAfter := parseRetryAfter(header.Get("Retry-After"), now)
+	nearLimit := strings.EqualFold(header.Get("X-RateLimit-NearLimit"), "true")
+	throttled := response.StatusCode == http.StatusTooManyRequests || hasRetryAfter
+
+	switch {
+	case throttled:
+		l.slowdown = min(l.slowdown*2, maxSlowdown)
+	case nearLimit:
+		l.slowdown = min(l.slowdown*1.5, maxSlowdown)
+	default:
+		l.slowdown = max(l.slowdown*slowdownRecovery, 1)
+	}
+
+	if hasRetryAfter {
+		l.pause(now, now.Add(retryAfter))
+	}
+
+	// Only spread the remaining requests once the budget runs low, so that a
+	// site with plenty of headroom is synced at full speed.
+	l.pacing = 0
+	remaining, resetAt, ok := parseRequestBudget(header)
+	if ok {
+		limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+		runningLow := nearLimit || (err == nil && remaining*5 < limit)
+		switch {
+		case remaining <= 0:
+			l.pause(now, resetAt)
+		case runningLow && resetAt.After(now):
+			l.pacing = resetAt.Sub(now) / time.Duration(remaining)
+		}
+	}
+
+	if l.budgetPoints {
+		l.observePoints(header, now)
+	}
+}
+
+// observePoints reads the points-based quota. When several policies are
+// reported, the one closest to exhaustion wins.
+func (l *rateLimiter) observePoints(header http.Header, now time.Time) {
+	values := header.Values("RateLimit")
+	values = append(values, header.Values("Beta-RateLimit")...)
+
+	found := false
+	var budget pointsBudget
+	for _, value := range values {
+		for _, policy := range strings.Split(value, ",") {
+			remaining, resetAt, ok := parseRateLimitPolicy(policy, now)
+			if !ok {
+				continue
+			}
+			if !found || remaining < budget.remaining {
+				budget = pointsBudget{remaining: remaining, resetAt: resetAt}
+				found = true
+			}
+		}
+	}
+	if !found {
+		return
+	}
+
+	// Learn the cost of a request from how much of the same window's budget
+	// it used. The estimate is smoothed so that one expensive page does not
+	// throttle the rest.
+	budget.cost = l.points.cost
+	if l.pointsKnown && sameWindow(budget.resetAt, l.points.resetAt) {
+		if spent := l.points.remaining + l.points.cost - budget.remaining; spent > 0 {
+			budget.cost = max(0.8*l.points.cost+0.2*spent, 1)
+		}
+	}
+	l.points = budget
+	l.pointsKnown = true
+
+	if budget.remaining < budget.cost {
+		l.pause(now, budget.resetAt)
+		return
+	}
+	if untilReset := budget.resetAt.Sub(now); untilReset > 0 {
+		requestsLeft := budget.remaining / budget.cost
+		l.pacing = max(l.pacing, time.Duration(float64(untilReset)/requestsLeft))
+	}
+}
+
+func (l *rateLimiter) pause(now time.Time, until time.Time) {
+	until = earliest(until, now.Add(maxPause))
+	l.pausedUntil = latest(l.pausedUntil, until)
+}
+
+// parseRequestBudget reads the requests remaining in the current window and
+// when the window resets.
+func parseRequestBudget(header http.Header) (int, time.Time, bool) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	resetAt, err := time.Parse(time.RFC3339, header.Get("X-RateLimit-Reset"))
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	return remaining, resetAt, true
+}
+
+// parseRetryAfter accepts both forms of the header: delay seconds and an
+// HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// parseRateLimitPolicy parses one policy of a RateLimit header, e.g.
+// `"global-app-quota";r=950;t=1200`, where r is the remaining points and t the
+// seconds until the window resets.
+func parseRateLimitPolicy(policy string, now time.Time) (float64, time.Time, bool) {
+	var remaining, seconds float64
+	var hasRemaining, hasReset bool
+	for _, parameter := range strings.Split(policy, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(parameter), "=")
+		if !ok {
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "r":
+			remaining, hasRemaining = number, true
+		case "t":
+			seconds, hasReset = number, true
+		}
+	}
+	if !hasRemaining || !hasReset {
+		return 0, time.Time{}, false
+	}
+	return remaining, now.Add(time.Duration(seconds * float64(time.Second))), true
+}
+
+// sameWindow tolerates the rounding of the seconds-until-reset parameter.
+func sameWindow(a time.Time, b time.Time) bool {
+	difference := a.Sub(b)
+	return difference < 2*time.Second && difference > -2*time.Second
+}
+
+func latest(t time.Time, others ...time.Time) time.Time {
+	for _, other := range others {
+		if other.After(t) {
+			t = other
+		}
+	}
+	return t
+}
+
+func earliest(t time.Time, other time.Time) time.Time {
+	if other.Before(t) {
+		return other
+	}
+	return t
+}
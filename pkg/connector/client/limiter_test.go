@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRateLimiter returns a limiter on a fake clock that advances when the
+// limiter sleeps, and a function that reports how long it slept in total.
+func newTestRateLimiter() (*rateLimiter, func() time.Duration) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var slept time.Duration
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+	l.sleep = func(_ context.Context, d time.Duration) error {
+		now = now.Add(d)
+		slept += d
+		return nil
+	}
+	return l, func() time.Duration {
+		total := slept
+		slept = 0
+		return total
+	}
+}
+
+func responseWithHeaders(statusCode int, headers map[string]string) *http.Response {
+	header := http.Header{}
+	for key, value := range headers {
+		header.Set(key, value)
+	}
+	return &http.Response{StatusCode: statusCode, Header: header}
+}
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should not wait without a cap or rate limit headers", func(t *testing.T) {
+		l, slept := newTestRateLimiter()
+		for range 10 {
+			require.Nil(t, l.wait(ctx))
+			l.observe(responseWithHeaders(http.StatusOK, nil))
+		}
+		require.Zero(t, slept())
+	})
+
+	t.Run("should space requests by the configured rate", func(t *testing.T) {
+		l, slept := newTestRateLimiter()
+		l.setRequestsPerSecond(4)
+		for range 5 {
+			require.Nil(t, l.wait(ctx))
+		}
+		require.Equal(t, time.Second, slept())
+	})
+
+	t.Run("should pause for Retry-After and slow down afterwards", func(t *testing.T) {
+		l, slept := newTestRateLimiter()
+		require.Nil(t, l.wait(ctx))
+		l.observe(responseWithHeaders(http.StatusTooManyRequests, map[string]string{"Retry-After": "3"}))
+
+		require.Nil(t, l.wait(ctx))
+		require.Equal(t, 3*time.Second, slept())
+
+		require.Nil(t, l.wait(ctx))
+		require.Equal(t, 2*minNearLimitInterval, slept())
+	})
+
+	t.Run("should slow down when near the limit and recover", func(t *testing.T) {
+		l, slept := newTestRateLimiter()
+		l.setRequestsPerSecond(10)
+		l.observe(responseWithHeaders(http.StatusOK, map[string]string{"X-RateLimit-NearLimit": "true"}))
+		require.Nil(t, l.wait(ctx))
+		require.Nil(t, l.wait(ctx))
+		require.Equal(t, 150*time.Millisecond, slept())
+
+		for range 10 {
+			l.observe(responseWithHeaders(http.StatusOK, nil))
+		}
+		// The slot reserved while slowed down still has to elapse.
+		require.Nil(t, l.wait(ctx))
+		require.Equal(t, 150*time.Millisecond, slept())
+		require.Nil(t, l.wait(ctx))
+		require.Equal(t, 100*time.Millisecond, slept())
+	})
+
+	t.Run("should spread the remaining requests until the reset when running low", func(t *testing.T) {
+		l, slept := newTestRateLimiter()
+		resetAt := l.now().Add(10 * time.Second).Format(time.RFC3339)
+
+		l.observe(responseWithHeaders(http.StatusOK, map[string]string{
+			"X-RateLimit-Limit":     "1000",
+			"X-RateLimit-Remaining": "500",
+			"X-RateLimit-Reset":     resetAt,
+		}))
+		require.Nil(t, l.wait(ctx))
+		require.Nil(t, l.wait(ctx))
+		require.Zero(t, slept())
+
+		l.observe(responseWithHeaders(http.StatusOK, map[string]string{
+			"X-RateLimit-Limit":     "1000",
+			"X-RateLimit-Remaining": "5",
+			"X-RateLimit-Reset":     resetAt,
+		}))
+		require.Nil(t, l.wait(ctx))
+		require.Nil(t, l.wait(ctx))
+		require.Equal(t, 2*time.Second, slept())
+	})
+
+	t.Run("should wait for the reset once the request budget is spent", func(t *testing.T) {
+		l, slept := newTestRateLimiter()
+		l.observe(responseWithHeaders(http.StatusOK, map[string]string{
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     l.now().Add(30 * time.Second).Format(time.RFC3339),
+		}))
+		require.Nil(t, l.wait(ctx))
+		require.Equal(t, 30*time.Second, slept())
+	})
+
+	t.Run("should budget points and learn the cost of a request", func(t *testing.T) {
+		l, slept := newTestRateLimiter()
+		l.observe(responseWithHeaders(http.StatusOK, map[string]string{
+			"Beta-RateLimit": `"global-app-quota";r=1000;t=1000`,
+		}))
+		require.Equal(t, 1.0, l.points.cost)
+
+		require.Nil(t, l.wait(ctx))
+		slept()
+		// The request cost 11 points instead of the estimated 1.
+		l.observe(responseWithHeaders(http.StatusOK, map[string]string{
+			"Beta-RateLimit": `"global-app-quota";r=989;t=1000`,
+		}))
+		require.InDelta(t, 3.0, l.points.cost, 0.001)
+
+		// Only 2 points are left in the most constrained policy: not enough
+		// for another request until the window resets.
+		l.observe(responseWithHeaders(http.StatusOK, map[string]string{
+			"Beta-RateLimit": `"global-app-quota";r=900;t=1000, "burst";r=2;t=20`,
+		}))
+		require.Nil(t, l.wait(ctx))
+		require.Equal(t, 20*time.Second, slept())
+	})
+
+	t.Run("should not budget Confluence points for the organization admin API", func(t *testing.T) {
+		l, slept := newTestRateLimiter()
+		l.budgetPoints = newOrgRateLimiter().budgetPoints
+		l.observe(responseWithHeaders(http.StatusOK, map[string]string{"RateLimit": `"global-app-quota";r=0;t=60`}))
+		require.Nil(t, l.wait(ctx))
+		require.Zero(t, slept())
+
+		l.observe(responseWithHeaders(http.StatusTooManyRequests, map[string]string{"Retry-After": "2"}))
+		require.Nil(t, l.wait(ctx))
+		require.Equal(t, 2*time.Second, slept())
+	})
+
+	t.Run("should stop waiting when the context is done", func(t *testing.T) {
+		l := newRateLimiter()
+		l.observe(responseWithHeaders(http.StatusTooManyRequests, map[string]string{"Retry-After": "60"}))
+
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		require.ErrorIs(t, l.wait(ctx), context.Canceled)
+	})
+}
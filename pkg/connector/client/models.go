@@ -1,5 +1,7 @@
 package client
 
+import "encoding/json"
+
 type ConfluenceLink struct {
 	Base string `json:"base"`
 	Next string `json:"next,omitempty"`
@@ -11,6 +13,9 @@ type ConfluenceUser struct {
 	DisplayName string                `json:"displayName"`
 	Email       string                `json:"email,omitempty"`
 	Operations  []ConfluenceOperation `json:"operations,omitempty"`
+	// UserKey and Username are only returned by Confluence Data Center.
+	UserKey  string `json:"userKey,omitempty"`
+	Username string `json:"username,omitempty"`
 }
 
 type ConfluenceOperation struct {
@@ -18,19 +23,20 @@ type ConfluenceOperation struct {
 	TargetType string `json:"targetType"`
 }
 
-type confluenceUserList struct {
-	Start   int              `json:"start"`
-	Limit   int              `json:"limit"`
-	Size    int              `json:"size"`
-	Links   ConfluenceLink   `json:"_links"`
-	Results []ConfluenceUser `json:"results"`
+type ConfluenceSearch struct {
+	EntityType string                 `json:"entityType"`
+	Score      float64                `json:"score"`
+	Title      string                 `json:"title"`
+	User       ConfluenceUser         `json:"user"`
+	Space      *ConfluenceSearchSpace `json:"space"`
 }
 
-type ConfluenceSearch struct {
-	EntityType string         `json:"entityType"`
-	Score      float64        `json:"score"`
-	Title      string         `json:"title"`
-	User       ConfluenceUser `json:"user"`
+// ConfluenceSearchSpace is the space of a space search result. Its ID is the
+// same as in the v2 API.
+type ConfluenceSearchSpace struct {
+	Id   json.Number `json:"id"`
+	Key  string      `json:"key"`
+	Name string      `json:"name"`
 }
 
 type ConfluenceSearchList struct {
@@ -47,14 +53,6 @@ type ConfluenceGroup struct {
 	Id   string
 }
 
-type confluenceGroupList struct {
-	Start   int               `json:"start"`
-	Limit   int               `json:"limit"`
-	Size    int               `json:"size"`
-	Links   ConfluenceLink    `json:"_links"`
-	Results []ConfluenceGroup `json:"results"`
-}
-
 type ConfluenceSpaceDescriptionValue struct {
 	Value          string `json:"value"`
 	Representation string `json:"representation"`
@@ -91,6 +89,10 @@ type ConfluenceSpacePermissionPrincipal struct {
 	Type string `json:"type"`
 }
 
+// SpacePermissionPrincipalAnonymous is the type of the principal of the
+// permissions given to anonymous users, i.e. the public. It has no ID.
+const SpacePermissionPrincipalAnonymous = "anonymous"
+
 type ConfluenceSpaceOperation struct {
 	Operation  string `json:"operation"`
 	TargetType string `json:"targetType"`
@@ -115,9 +117,47 @@ type ConfluenceSpace struct {
 	Type        string                            `json:"type"`
 }
 
-type confluenceSpaceList struct {
-	Links   ConfluenceLink    `json:"_links"`
-	Results []ConfluenceSpace `json:"results"`
+// Space statuses. Archived spaces are read-only and hidden from search.
+const (
+	SpaceStatusCurrent  = "current"
+	SpaceStatusArchived = "archived"
+)
+
+// personalSpaceKeyPrefix starts the key of every personal space.
+const personalSpaceKeyPrefix = "~"
+
+// SpaceFilter narrows down the spaces listed. Empty fields do not filter.
+type SpaceFilter struct {
+	// Keys are exact space keys.
+	Keys   []string
+	Type   string
+	Status string
+	// Labels keeps the spaces with any of the labels.
+	Labels []string
+}
+
+// ConfluenceLabel is a label of a space or of content.
+type ConfluenceLabel struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Prefix string `json:"prefix"`
+}
+
+// CreateSpaceRequest is what is needed to create a space.
+type CreateSpaceRequest struct {
+	Key         string
+	Name        string
+	Description string
+}
+
+type createSpaceRequestBody struct {
+	Key         string                           `json:"key"`
+	Name        string                           `json:"name"`
+	Description *ConfluenceSpaceDescriptionValue `json:"description,omitempty"`
+}
+
+type updateSpaceStatusRequestBody struct {
+	Status string `json:"status"`
 }
 
 type SpacePermissionSubject struct {
@@ -139,6 +179,10 @@ type AddUserToGroupRequestBody struct {
 	AccountId string `json:"accountId"`
 }
 
+type CreateGroupRequestBody struct {
+	Name string `json:"name"`
+}
+
 type SpaceRole struct {
 	Id               string   `json:"id"`
 	Type             string   `json:"type"`
@@ -147,6 +191,17 @@ type SpaceRole struct {
 	SpacePermissions []string `json:"spacePermissions"`
 }
 
+// SpaceRoleTypeCustom is the type of the roles defined by site admins. The
+// other roles are Confluence's defaults, which cannot be changed.
+const SpaceRoleTypeCustom = "CUSTOM"
+
+// SpaceRoleRequest is the body of the space role create and update requests.
+type SpaceRoleRequest struct {
+	Name             string   `json:"name"`
+	Description      string   `json:"description"`
+	SpacePermissions []string `json:"spacePermissions"`
+}
+
 type SpaceRoleAssignmentPrincipal struct {
 	PrincipalType string `json:"principalType"`
 	PrincipalId   string `json:"principalId"`
@@ -167,6 +222,18 @@ type SpaceRoleAssignmentsResponse struct {
 	Links   ConfluenceLink        `json:"_links"`
 }
 
+// Space role modes reported by the space-role-mode endpoint.
+// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-space-roles/#api-space-role-mode-get
+const (
+	// SpaceRoleModePreRoles means RBAC has not been activated.
+	SpaceRoleModePreRoles = "PRE_ROLES"
+	// SpaceRoleModeRolesTransition means granular permissions and space
+	// roles are both in use while the site migrates.
+	SpaceRoleModeRolesTransition = "ROLES_TRANSITION"
+	// SpaceRoleModeRoles means access is managed with space roles only.
+	SpaceRoleModeRoles = "ROLES"
+)
+
 type SpaceRoleModeResponse struct {
 	Mode string `json:"mode"`
 }
@@ -175,3 +242,185 @@ type SetSpaceRoleAssignmentRequest struct {
 	Principal SpaceRoleAssignmentPrincipal `json:"principal"`
 	RoleId    string                       `json:"roleId,omitempty"`
 }
+
+// dataCenterSpaceExpand asks Data Center for the space fields that the v2
+// API returns by default.
+const dataCenterSpaceExpand = "description.plain,homepage,history.createdBy"
+
+type dataCenterSpace struct {
+	Id          json.Number                `json:"id"`
+	Key         string                     `json:"key"`
+	Name        string                     `json:"name"`
+	Type        string                     `json:"type"`
+	Status      string                     `json:"status"`
+	Description ConfluenceSpaceDescription `json:"description"`
+	Homepage    struct {
+		Id string `json:"id"`
+	} `json:"homepage"`
+	History struct {
+		CreatedBy   ConfluenceUser `json:"createdBy"`
+		CreatedDate string         `json:"createdDate"`
+	} `json:"history"`
+}
+
+// toSpace converts a Data Center space. Its key stands in for the ID, and the
+// user key of its creator for the author ID.
+func (s dataCenterSpace) toSpace() *ConfluenceSpace {
+	return &ConfluenceSpace{
+		AuthorId:    s.History.CreatedBy.UserKey,
+		CreatedAt:   s.History.CreatedDate,
+		Id:          s.Key,
+		Key:         s.Key,
+		Name:        s.Name,
+		Type:        s.Type,
+		Status:      s.Status,
+		Description: s.Description,
+		HomepageId:  s.Homepage.Id,
+	}
+}
+
+type dataCenterCreateSpaceRequestBody struct {
+	Key         string                      `json:"key"`
+	Name        string                      `json:"name"`
+	Description *ConfluenceSpaceDescription `json:"description,omitempty"`
+}
+
+type dataCenterSpacePermissionSubjects struct {
+	User struct {
+		Results []ConfluenceUser `json:"results"`
+	} `json:"user"`
+	Group struct {
+		Results []ConfluenceGroup `json:"results"`
+	} `json:"group"`
+}
+
+type dataCenterSpacePermission struct {
+	Operation       ConfluenceOperation               `json:"operation"`
+	Subjects        dataCenterSpacePermissionSubjects `json:"subjects"`
+	AnonymousAccess bool                              `json:"anonymousAccess"`
+}
+
+type dataCenterSpaceWithLabels struct {
+	Metadata struct {
+		Labels struct {
+			Results []ConfluenceLabel `json:"results"`
+		} `json:"labels"`
+	} `json:"metadata"`
+}
+
+type dataCenterSpaceWithPermissions struct {
+	Key         string                      `json:"key"`
+	Permissions []dataCenterSpacePermission `json:"permissions"`
+}
+
+type dataCenterSpacePermissionOperation struct {
+	OperationKey string `json:"operationKey"`
+	TargetType   string `json:"targetType"`
+}
+
+// ConfluenceRestrictionSubjects are the users and groups a content
+// restriction is limited to.
+type ConfluenceRestrictionSubjects struct {
+	User  ConfluenceRestrictionList[ConfluenceUser]  `json:"user"`
+	Group ConfluenceRestrictionList[ConfluenceGroup] `json:"group"`
+}
+
+// ConfluenceRestrictionList is a page of the users or groups of a content
+// restriction.
+type ConfluenceRestrictionList[T any] struct {
+	Results []T `json:"results"`
+	Start   int `json:"start"`
+	Limit   int `json:"limit"`
+	Size    int `json:"size"`
+}
+
+// full reports whether the page holds as many subjects as it can, in which
+// case more may follow.
+func (l ConfluenceRestrictionList[T]) full() bool {
+	return l.Limit > 0 && len(l.Results) >= l.Limit
+}
+
+// ConfluenceContentRestriction limits an operation on a page or blog post to
+// a set of users and groups. The operation is unrestricted when both are
+// empty.
+type ConfluenceContentRestriction struct {
+	Operation    string                        `json:"operation"`
+	Restrictions ConfluenceRestrictionSubjects `json:"restrictions"`
+}
+
+// IsRestricted reports whether the restriction names anyone.
+func (r ConfluenceContentRestriction) IsRestricted() bool {
+	return len(r.Restrictions.User.Results) > 0 || len(r.Restrictions.Group.Results) > 0
+}
+
+// Subjects returns how many users and groups the restriction names.
+func (r ConfluenceContentRestriction) Subjects() int {
+	return len(r.Restrictions.User.Results) + len(r.Restrictions.Group.Results)
+}
+
+// Includes reports whether the restriction names a user ("user") or a group
+// ("group").
+func (r ConfluenceContentRestriction) Includes(principalType string, principalId string) bool {
+	switch principalType {
+	case "user":
+		for _, user := range r.Restrictions.User.Results {
+			if user.AccountId == principalId {
+				return true
+			}
+		}
+	case "group":
+		for _, group := range r.Restrictions.Group.Results {
+			if group.Id == principalId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ConfluenceContentRestrictions are the restrictions of a page or blog post,
+// by operation.
+type ConfluenceContentRestrictions struct {
+	Read   ConfluenceContentRestriction `json:"read"`
+	Update ConfluenceContentRestriction `json:"update"`
+}
+
+// ConfluenceContent is a page or a blog post.
+type ConfluenceContent struct {
+	Id           string                        `json:"id"`
+	Type         string                        `json:"type"`
+	Status       string                        `json:"status"`
+	Title        string                        `json:"title"`
+	Ancestors    []ConfluenceContent           `json:"ancestors"`
+	Restrictions ConfluenceContentRestrictions `json:"restrictions"`
+}
+
+// AuditObject is an object an audit record is about. Audit records only name
+// objects, they carry no IDs.
+type AuditObject struct {
+	Name       string `json:"name"`
+	ObjectType string `json:"objectType"`
+}
+
+// AuditChangedValue is a value an audited change set or cleared.
+type AuditChangedValue struct {
+	Name     string `json:"name"`
+	OldValue string `json:"oldValue"`
+	NewValue string `json:"newValue"`
+}
+
+// AuditRecord is an entry of the site's audit log.
+type AuditRecord struct {
+	Author struct {
+		AccountId   string `json:"accountId"`
+		DisplayName string `json:"displayName"`
+	} `json:"author"`
+	RemoteAddress     string              `json:"remoteAddress"`
+	CreationDate      int64               `json:"creationDate"`
+	Summary           string              `json:"summary"`
+	Description       string              `json:"description"`
+	Category          string              `json:"category"`
+	AffectedObject    AuditObject         `json:"affectedObject"`
+	ChangedValues     []AuditChangedValue `json:"changedValues"`
+	AssociatedObjects []AuditObject       `json:"associatedObjects"`
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+)
+
+const (
+	// Account statuses reported by the organization admin API. Inactive
+	// (suspended or deactivated) accounts keep their data but cannot log in;
+	// closed accounts are deleted.
+	OrgAccountStatusActive      = "active"
+	OrgAccountStatusInactive    = "inactive"
+	OrgAccountStatusSuspended   = "suspended"
+	OrgAccountStatusDeactivated = "deactivated"
+	OrgAccountStatusClosed      = "closed"
+
+	// confluenceProductKey prefixes the product access keys of Confluence,
+	// e.g. "confluence.ondemand".
+	confluenceProductKey = "confluence"
+)
+
+// OrgUser is an account managed by an Atlassian organization, i.e. one whose
+// email domain the organization has verified.
+type OrgUser struct {
+	AccountId     string             `json:"account_id"`
+	AccountType   string             `json:"account_type"`
+	AccountStatus string             `json:"account_status"`
+	Name          string             `json:"name"`
+	Email         string             `json:"email"`
+	EmailVerified bool               `json:"email_verified"`
+	LastActive    string             `json:"last_active,omitempty"`
+	ProductAccess []OrgProductAccess `json:"product_access"`
+}
+
+// OrgProductAccess is a product a managed account has access to.
+type OrgProductAccess struct {
+	Key        string `json:"key"`
+	Name       string `json:"name"`
+	Url        string `json:"url"`
+	LastActive string `json:"last_active,omitempty"`
+}
+
+// ConfluenceLastActive returns when the account last used Confluence on the
+// given site. It falls back to the last activity of the account in any
+// product when the organization does not report it per product.
+func (u *OrgUser) ConfluenceLastActive(site string) string {
+	for _, product := range u.ProductAccess {
+		if !strings.HasPrefix(product.Key, confluenceProductKey) || product.LastActive == "" {
+			continue
+		}
+		if site == "" || product.Url == "" || sameHost(product.Url, site) {
+			return product.LastActive
+		}
+	}
+	return u.LastActive
+}
+
+func sameHost(a, b string) bool {
+	aUrl, err := fallBackToHTTPS(a)
+	if err != nil {
+		return false
+	}
+	bUrl, err := fallBackToHTTPS(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(aUrl.Host, bUrl.Host)
+}
+
+type orgUserList struct {
+	Data  []OrgUser `json:"data"`
+	Links struct {
+		Next string `json:"next"`
+	} `json:"links"`
+}
+
+// OrgAdminClient reads the accounts of an Atlassian organization with an
+// organization admin API key. It shares the request handling of
+// ConfluenceClient but talks to api.atlassian.com instead of the site, with a
+// rate limiter of its own.
+type OrgAdminClient struct {
+	client *ConfluenceClient
+	orgId  string
+}
+
+// NewOrgAdminClient returns a client for the organization orgId. If apiUrl is
+// empty, requests go to the Atlassian API gateway.
+func NewOrgAdminClient(ctx context.Context, orgId, apiKey, apiUrl string) (*OrgAdminClient, error) {
+	if apiUrl == "" {
+		apiUrl = atlassianGatewayUrl
+	}
+	c, err := newConfluenceClient(ctx, apiUrl, uhttp.NewBearerAuth(apiKey))
+	if err != nil {
+		return nil, err
+	}
+	c.limiter = newOrgRateLimiter()
+	return &OrgAdminClient{client: c, orgId: orgId}, nil
+}
+
+// Verify checks that the API key can read the organization.
+func (o *OrgAdminClient) Verify(ctx context.Context) error {
+	orgUrl, err := o.client.parse(fmt.Sprintf(orgUrlPath, url.PathEscape(o.orgId)))
+	if err != nil {
+		return err
+	}
+
+	var response map[string]interface{}
+	_, err = o.client.get(ctx, orgUrl, &response)
+	if err != nil {
+		return fmt.Errorf("confluence-connector: failed to read organization %s: %w", o.orgId, err)
+	}
+	return nil
+}
+
+// GetUsers fetches a page of the managed accounts of the organization. The
+// endpoint has a fixed page size; the page token is the cursor of the page.
+func (o *OrgAdminClient) GetUsers(
+	ctx context.Context,
+	pageToken string,
+) ([]OrgUser, string, *v2.RateLimitDescription, error) {
+	var options []Option
+	if pageToken != "" {
+		options = append(options, withQueryParameters(map[string]interface{}{
+			"cursor": pageToken,
+		}))
+	}
+	usersUrl, err := o.client.parse(fmt.Sprintf(orgUsersUrlPath, url.PathEscape(o.orgId)), options...)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var response *orgUserList
+	ratelimitData, err := o.client.get(ctx, usersUrl, &response)
+	if err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	return response.Data, orgCursor(response.Links.Next), ratelimitData, nil
+}
+
+// orgCursor reads the cursor of the next page, which is either given as is or
+// as the URL of the next page.
+func orgCursor(next string) string {
+	if cursor := extractPaginationCursor(ConfluenceLink{Next: next}); cursor != "" {
+		return cursor
+	}
+	if strings.Contains(next, "/") {
+		return ""
+	}
+	return next
+}
+
+// Users lists every managed account of the organization.
+func (o *OrgAdminClient) Users() *Pager[OrgUser] {
+	return newPager(0, func(ctx context.Context, pageToken string, _ int) ([]OrgUser, string, *v2.RateLimitDescription, error) {
+		return o.GetUsers(ctx, pageToken)
+	})
+}
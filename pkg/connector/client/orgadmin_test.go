@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrgAdminClient(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer admin-key" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writer.Header().Set(uhttp.ContentType, "application/json")
+		switch request.URL.Path + "?" + request.URL.RawQuery {
+		case "/admin/v1/orgs/org-1?":
+			_, _ = writer.Write([]byte(`{"data": {"id": "org-1", "type": "orgs"}}`))
+		case "/admin/v1/orgs/org-1/users?":
+			_, _ = writer.Write([]byte(`{
+				"data": [{"account_id": "alice", "account_status": "active", "email": "alice@example.com", "email_verified": true}],
+				"links": {"next": "https://api.atlassian.com/admin/v1/orgs/org-1/users?cursor=page-2"}
+			}`))
+		case "/admin/v1/orgs/org-1/users?cursor=page-2":
+			_, _ = writer.Write([]byte(`{
+				"data": [{"account_id": "bob", "account_status": "inactive"}],
+				"links": {"next": "page-3"}
+			}`))
+		case "/admin/v1/orgs/org-1/users?cursor=page-3":
+			_, _ = writer.Write([]byte(`{"data": [{"account_id": "carol", "account_status": "closed"}], "links": {}}`))
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("should verify access to the organization", func(t *testing.T) {
+		c, err := NewOrgAdminClient(ctx, "org-1", "admin-key", server.URL)
+		require.Nil(t, err)
+		require.Nil(t, c.Verify(ctx))
+
+		c, err = NewOrgAdminClient(ctx, "org-1", "wrong-key", server.URL)
+		require.Nil(t, err)
+		require.ErrorContains(t, c.Verify(ctx), "failed to read organization org-1")
+	})
+
+	t.Run("should list every managed account", func(t *testing.T) {
+		c, err := NewOrgAdminClient(ctx, "org-1", "admin-key", server.URL)
+		require.Nil(t, err)
+
+		statuses := make(map[string]string)
+		for user, err := range c.Users().All(ctx) {
+			require.Nil(t, err)
+			statuses[user.AccountId] = user.AccountStatus
+		}
+		require.Equal(t, map[string]string{
+			"alice": OrgAccountStatusActive,
+			"bob":   OrgAccountStatusInactive,
+			"carol": OrgAccountStatusClosed,
+		}, statuses)
+	})
+}
+
+func TestConfluenceLastActive(t *testing.T) {
+	user := OrgUser{
+		LastActive: "2024-05-01",
+		ProductAccess: []OrgProductAccess{
+			{Key: "jira-software", Url: "example.atlassian.net", LastActive: "2024-05-01"},
+			{Key: "confluence.ondemand", Url: "other.atlassian.net", LastActive: "2024-03-01"},
+			{Key: "confluence.ondemand", Url: "example.atlassian.net", LastActive: "2024-04-01"},
+		},
+	}
+	require.Equal(t, "2024-04-01", user.ConfluenceLastActive("https://example.atlassian.net"))
+	require.Equal(t, "2024-05-01", user.ConfluenceLastActive("unknown.atlassian.net"))
+
+	user.ProductAccess = nil
+	require.Equal(t, "2024-05-01", user.ConfluenceLastActive("example.atlassian.net"))
+}
@@ -0,0 +1,212 @@
+package client
+
+import (
+	"context"
+	"iter"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// pageFunc fetches the page that starts at pageToken and returns the token of
+// the next page, or "" after the last page.
+type pageFunc[T any] func(
+	ctx context.Context,
+	pageToken string,
+	pageSize int,
+) ([]T, string, *v2.RateLimitDescription, error)
+
+// Pager lists a paginated collection. It hides whether the endpoint behind it
+// uses v1 offset or v2 cursor pagination: page tokens are opaque and "" is
+// both the first page and the end of the listing.
+type Pager[T any] struct {
+	fetch     pageFunc[T]
+	pageSize  int
+	rateLimit *v2.RateLimitDescription
+}
+
+func newPager[T any](pageSize int, fetch pageFunc[T]) *Pager[T] {
+	return &Pager[T]{
+		fetch:    fetch,
+		pageSize: pageSize,
+	}
+}
+
+// WithPageSize sets how many items are requested per page. Values below 1
+// keep the endpoint's default.
+func (p *Pager[T]) WithPageSize(pageSize int) *Pager[T] {
+	if pageSize > 0 {
+		p.pageSize = pageSize
+	}
+	return p
+}
+
+// Page fetches a single page and returns the token of the next one, or ""
+// after the last page.
+func (p *Pager[T]) Page(
+	ctx context.Context,
+	pageToken string,
+) ([]T, string, *v2.RateLimitDescription, error) {
+	items, nextToken, ratelimitData, err := p.fetch(ctx, pageToken, p.pageSize)
+	if ratelimitData != nil {
+		p.rateLimit = ratelimitData
+	}
+	return items, nextToken, ratelimitData, err
+}
+
+// All walks every page, starting from the first, and yields each item. The
+// first error is yielded with the zero value and ends the iteration.
+func (p *Pager[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		pageToken := ""
+		for {
+			items, nextToken, _, err := p.Page(ctx, pageToken)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if nextToken == "" || nextToken == pageToken {
+				return
+			}
+			pageToken = nextToken
+		}
+	}
+}
+
+// RateLimit returns the rate limit data of the most recent page fetched.
+func (p *Pager[T]) RateLimit() *v2.RateLimitDescription {
+	return p.rateLimit
+}
+
+// pageList is the envelope shared by v1 and v2 list responses.
+type pageList[T any] struct {
+	Links   ConfluenceLink `json:"_links"`
+	Results []T            `json:"results"`
+}
+
+// getOffsetPage fetches a page of a v1 endpoint paginated with `start` and
+// `limit`. The page token is the offset of the page.
+func getOffsetPage[T any](
+	ctx context.Context,
+	c *ConfluenceClient,
+	path string,
+	pageToken string,
+	pageSize int,
+	options ...Option,
+) ([]T, string, *v2.RateLimitDescription, error) {
+	pageUrl, err := c.parse(
+		path,
+		append([]Option{withLimitAndOffset(pageToken, pageSize)}, options...)...,
+	)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var response *pageList[T]
+	ratelimitData, err := c.get(ctx, pageUrl, &response)
+	if err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	if !isThereAnotherPage(response.Links) {
+		return response.Results, "", ratelimitData, nil
+	}
+
+	return response.Results, incToken(pageToken, len(response.Results)), ratelimitData, nil
+}
+
+// getCursorPage fetches a page of a v2 endpoint paginated with an opaque
+// cursor. The page token is the cursor of the page.
+func getCursorPage[T any](
+	ctx context.Context,
+	c *ConfluenceClient,
+	path string,
+	pageToken string,
+	pageSize int,
+	options ...Option,
+) ([]T, string, *v2.RateLimitDescription, error) {
+	pageUrl, err := c.parse(
+		path,
+		append([]Option{withPaginationCursor(pageSize, pageToken)}, options...)...,
+	)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var response *pageList[T]
+	ratelimitData, err := c.get(ctx, pageUrl, &response)
+	if err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	return response.Results, extractPaginationCursor(response.Links), ratelimitData, nil
+}
+
+// Groups lists every group.
+func (c *ConfluenceClient) Groups() *Pager[ConfluenceGroup] {
+	return newPager(defaultSize, c.GetGroups)
+}
+
+// GroupMembers lists the members of a group.
+func (c *ConfluenceClient) GroupMembers(groupId string) *Pager[ConfluenceUser] {
+	return newPager(defaultSize, func(ctx context.Context, pageToken string, pageSize int) ([]ConfluenceUser, string, *v2.RateLimitDescription, error) {
+		return c.GetGroupMembers(ctx, pageToken, pageSize, groupId)
+	})
+}
+
+// UsersFromSearch lists the users found by searching for every user.
+func (c *ConfluenceClient) UsersFromSearch() *Pager[ConfluenceUser] {
+	return newPager(defaultSize, c.GetUsersFromSearch)
+}
+
+// Spaces lists every space.
+func (c *ConfluenceClient) Spaces() *Pager[ConfluenceSpace] {
+	return c.FilteredSpaces(SpaceFilter{})
+}
+
+// FilteredSpaces lists the spaces that match the filter.
+func (c *ConfluenceClient) FilteredSpaces(filter SpaceFilter) *Pager[ConfluenceSpace] {
+	return newPager(maxResults, func(ctx context.Context, pageToken string, pageSize int) ([]ConfluenceSpace, string, *v2.RateLimitDescription, error) {
+		return c.GetFilteredSpaces(ctx, filter, pageSize, pageToken)
+	})
+}
+
+// SpaceOperations lists the operations Confluence supports on a space.
+func (c *ConfluenceClient) SpaceOperations(spaceId string) *Pager[ConfluenceSpaceOperation] {
+	return newPager(maxResults, func(ctx context.Context, pageToken string, pageSize int) ([]ConfluenceSpaceOperation, string, *v2.RateLimitDescription, error) {
+		return c.ConfluenceSpaceOperations(ctx, pageToken, pageSize, spaceId)
+	})
+}
+
+// SpacePermissions lists the permissions granted on a space.
+func (c *ConfluenceClient) SpacePermissions(spaceId string) *Pager[ConfluenceSpacePermission] {
+	return newPager(maxResults, func(ctx context.Context, pageToken string, pageSize int) ([]ConfluenceSpacePermission, string, *v2.RateLimitDescription, error) {
+		return c.GetSpacePermissions(ctx, pageToken, pageSize, spaceId)
+	})
+}
+
+// SpaceRoles lists the space roles, optionally only those available in a
+// space.
+func (c *ConfluenceClient) SpaceRoles(spaceId string) *Pager[SpaceRole] {
+	return newPager(maxResults, func(ctx context.Context, pageToken string, pageSize int) ([]SpaceRole, string, *v2.RateLimitDescription, error) {
+		return c.GetSpaceRoles(ctx, spaceId, pageToken, pageSize)
+	})
+}
+
+// SpaceRoleAssignments lists the role assignments of a space. roleId,
+// principalId and principalType are optional filters.
+func (c *ConfluenceClient) SpaceRoleAssignments(
+	spaceId string,
+	roleId string,
+	principalId string,
+	principalType string,
+) *Pager[SpaceRoleAssignment] {
+	return newPager(maxResults, func(ctx context.Context, pageToken string, pageSize int) ([]SpaceRoleAssignment, string, *v2.RateLimitDescription, error) {
+		return c.GetSpaceRoleAssignments(ctx, spaceId, roleId, principalId, principalType, pageToken, pageSize)
+	})
+}
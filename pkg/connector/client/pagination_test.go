@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPager(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "true")
+
+	rateLimited := false
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set(uhttp.ContentType, "application/json")
+		if rateLimited {
+			writer.Header().Set("Retry-After", "3")
+			writer.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		query := request.URL.Query()
+		switch request.URL.Path {
+		case GroupsListUrlPath:
+			// Three groups served by offset, two at a time.
+			start, _ := strconv.Atoi(query.Get("start"))
+			switch start {
+			case 0:
+				_, _ = writer.Write([]byte(`{"results": [{"id": "g1"}, {"id": "g2"}], "_links": {"next": "/wiki/rest/api/group?start=2"}}`))
+			default:
+				_, _ = writer.Write([]byte(`{"results": [{"id": "g3"}], "_links": {}}`))
+			}
+		case SpacesListUrlPath:
+			// Three spaces served by cursor, one at a time.
+			cursor, _ := strconv.Atoi(query.Get("cursor"))
+			next := ""
+			if cursor < 2 {
+				next = fmt.Sprintf("/wiki/api/v2/spaces?cursor=%d", cursor+1)
+			}
+			_, _ = fmt.Fprintf(writer, `{"results": [{"id": "s%d"}], "_links": {"next": %q}}`, cursor, next)
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewConfluenceClient(ctx, "username", "API Key", server.URL)
+	require.Nil(t, err)
+
+	t.Run("should walk every page of an offset listing", func(t *testing.T) {
+		ids := make([]string, 0)
+		for group, err := range c.Groups().WithPageSize(2).All(ctx) {
+			require.Nil(t, err)
+			ids = append(ids, group.Id)
+		}
+		require.Equal(t, []string{"g1", "g2", "g3"}, ids)
+	})
+
+	t.Run("should walk every page of a cursor listing", func(t *testing.T) {
+		ids := make([]string, 0)
+		for space, err := range c.Spaces().WithPageSize(1).All(ctx) {
+			require.Nil(t, err)
+			ids = append(ids, space.Id)
+		}
+		require.Equal(t, []string{"s0", "s1", "s2"}, ids)
+	})
+
+	t.Run("should stop when the caller breaks", func(t *testing.T) {
+		ids := make([]string, 0)
+		for space := range c.Spaces().WithPageSize(1).All(ctx) {
+			ids = append(ids, space.Id)
+			break
+		}
+		require.Equal(t, []string{"s0"}, ids)
+	})
+
+	t.Run("should yield errors with the rate limit data", func(t *testing.T) {
+		rateLimited = true
+		defer func() { rateLimited = false }()
+
+		groups := c.Groups()
+		var errs []error
+		for _, err := range groups.All(ctx) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		require.Equal(t, codes.Unavailable, status.Code(errs[0]))
+		require.Equal(t, v2.RateLimitDescription_STATUS_OVERLIMIT, groups.RateLimit().GetStatus())
+	})
+}
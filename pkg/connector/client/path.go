@@ -7,19 +7,53 @@ import (
 )
 
 const (
-	CurrentUserUrlPath            = "/wiki/rest/api/user/current"
-	GroupsListUrlPath             = "/wiki/rest/api/group"
-	getUsersByGroupIdUrlPath      = "/wiki/rest/api/group/%s/membersByGroupId"
-	groupBaseUrlPath              = "/wiki/rest/api/group/userByGroupId"
-	SearchUrlPath                 = "/wiki/rest/api/search/user"
-	spacePermissionsCreateUrlPath = "/wiki/rest/api/space/%s/permissions"
-	spacePermissionsUpdateUrlPath = "/wiki/rest/api/space/%s/permissions/%s"
-	SpacesListUrlPath             = "/wiki/api/v2/spaces"
-	spacesGetUrlPath              = "/wiki/api/v2/spaces/%s"
-	SpacePermissionsListUrlPath   = "/wiki/api/v2/spaces/%s/permissions"
-	SpaceRolesUrlPath             = "/wiki/api/v2/space-roles"
-	SpaceRoleAssignmentsUrlPath   = "/wiki/api/v2/spaces/%s/role-assignments"
-	SpaceRoleModeUrlPath          = "/wiki/api/v2/space-role-mode"
+	CurrentUserUrlPath             = "/wiki/rest/api/user/current"
+	userUrlPath                    = "/wiki/rest/api/user"
+	GroupsListUrlPath              = "/wiki/rest/api/group"
+	getUsersByGroupIdUrlPath       = "/wiki/rest/api/group/%s/membersByGroupId"
+	groupBaseUrlPath               = "/wiki/rest/api/group/userByGroupId"
+	SearchUrlPath                  = "/wiki/rest/api/search/user"
+	spacePermissionsCreateUrlPath  = "/wiki/rest/api/space/%s/permissions"
+	spacePermissionsUpdateUrlPath  = "/wiki/rest/api/space/%s/permissions/%s"
+	SpacesListUrlPath              = "/wiki/api/v2/spaces"
+	spacesGetUrlPath               = "/wiki/api/v2/spaces/%s"
+	spaceV1UrlPath                 = "/wiki/rest/api/space/%s"
+	SpacePermissionsListUrlPath    = "/wiki/api/v2/spaces/%s/permissions"
+	spaceLabelsUrlPath             = "/wiki/api/v2/spaces/%s/labels"
+	SpaceRolesUrlPath              = "/wiki/api/v2/space-roles"
+	spaceRoleUrlPath               = "/wiki/api/v2/space-roles/%s"
+	SpaceRoleAssignmentsUrlPath    = "/wiki/api/v2/spaces/%s/role-assignments"
+	SpaceRoleModeUrlPath           = "/wiki/api/v2/space-role-mode"
+	ContentSearchUrlPath           = "/wiki/rest/api/content/search"
+	ContentSearchCqlUrlPath        = "/wiki/rest/api/search"
+	AuditUrlPath                   = "/wiki/rest/api/audit"
+	groupByNameUrlPath             = "/wiki/rest/api/group/by-name"
+	groupByIdUrlPath               = "/wiki/rest/api/group/by-id"
+	contentRestrictionUrlPath      = "/wiki/rest/api/content/%s/restriction/byOperation/%s"
+	contentRestrictionUserUrlPath  = "/wiki/rest/api/content/%s/restriction/byOperation/%s/user"
+	contentRestrictionGroupUrlPath = "/wiki/rest/api/content/%s/restriction/byOperation/%s/byGroupId/%s"
+
+	// Confluence Data Center only has the v1 REST API, served without the
+	// "/wiki" prefix. Groups are addressed by name, users by user key and
+	// spaces by space key.
+	DataCenterCurrentUserUrlPath      = "/rest/api/user/current"
+	DataCenterUserUrlPath             = "/rest/api/user"
+	DataCenterGroupsListUrlPath       = "/rest/api/group"
+	dataCenterGroupMembersUrlPath     = "/rest/api/group/%s/member"
+	dataCenterUserGroupUrlPath        = "/rest/api/user/%s/group/%s"
+	dataCenterAdminGroupsUrlPath      = "/rest/api/admin/group"
+	dataCenterAdminGroupUrlPath       = "/rest/api/admin/group/%s"
+	DataCenterSearchUrlPath           = "/rest/api/search"
+	DataCenterSpacesListUrlPath       = "/rest/api/space"
+	DataCenterAuditUrlPath            = "/rest/api/audit"
+	dataCenterSpaceGetUrlPath         = "/rest/api/space/%s"
+	dataCenterSpacePermissionsUrlPath = "/rest/api/space/%s/permissions/%s/%s/%s"
+	// Anonymous permissions have no subject to address.
+	dataCenterSpaceAnonymousPermissionsUrlPath = "/rest/api/space/%s/permissions/anonymous/%s"
+
+	// The organization admin API is served by the Atlassian API gateway.
+	orgUrlPath      = "/admin/v1/orgs/%s"
+	orgUsersUrlPath = "/admin/v1/orgs/%s/users"
 
 	defaultSize = 100
 )
@@ -52,6 +86,19 @@ func withQueryParameters(parameters map[string]interface{}) Option {
 	}
 }
 
+// withQueryValues sets a query parameter that is repeated once per value.
+func withQueryValues(key string, values []string) Option {
+	return func(url *url.URL) (*url.URL, error) {
+		query := url.Query()
+		query.Del(key)
+		for _, value := range values {
+			query.Add(key, value)
+		}
+		url.RawQuery = query.Encode()
+		return url, nil
+	}
+}
+
 // withLimitAndOffset adds `start` and `limit` query parameters to a URL. This
 // pagination parameter is only used by the v1 REST API.
 func withLimitAndOffset(pageToken string, pageSize int) Option {
@@ -84,7 +131,7 @@ func (c *ConfluenceClient) parse(
 	path string,
 	options ...Option,
 ) (*url.URL, error) {
-	parsed, err := url.Parse(path)
+	parsed, err := url.Parse(c.pathPrefix + path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse request path '%s': %w", path, err)
 	}
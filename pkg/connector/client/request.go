@@ -29,6 +29,15 @@ func (c *ConfluenceClient) post(
 	return c.makeRequest(ctx, postUrl, target, http.MethodPost, requestBody)
 }
 
+func (c *ConfluenceClient) put(
+	ctx context.Context,
+	putUrl *url.URL,
+	target interface{},
+	requestBody io.Reader,
+) (*v2.RateLimitDescription, error) {
+	return c.makeRequest(ctx, putUrl, target, http.MethodPut, requestBody)
+}
+
 func (c *ConfluenceClient) delete(
 	ctx context.Context,
 	deleteUrl *url.URL,
@@ -49,9 +58,12 @@ func (c *ConfluenceClient) makeRequest(
 		return nil, err
 	}
 
-	req.SetBasicAuth(c.user, c.apiKey)
+	// Credentials are added by the underlying HTTP client's transport.
 	req.Header.Set("X-Atlassian-Token", "no-check")
 	req.Header.Set("Content-Type", "application/json")
+	if c.concurrent {
+		req.Header.Set("Cache-Control", "no-cache")
+	}
 
 	ratelimitData := v2.RateLimitDescription{}
 
@@ -62,10 +74,16 @@ func (c *ConfluenceClient) makeRequest(
 		doOpts = append(doOpts, uhttp.WithJSONResponse(target))
 	}
 
+	err = c.limiter.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	response, err := c.wrapper.Do(
 		req,
 		doOpts...,
 	)
+	c.limiter.observe(response)
 	if err == nil {
 		return &ratelimitData, nil
 	}
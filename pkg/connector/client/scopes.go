@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ScopeCheck lists the granular scopes the credentials lack, split by what
+// they are needed for. Provisioning scopes can only be read from the scopes
+// granted to an OAuth 2.0 token; for other credentials they are not checked
+// and ProvisionScopesChecked is false.
+type ScopeCheck struct {
+	MissingSyncScopes      []string
+	MissingProvisionScopes []string
+	ProvisionScopesChecked bool
+}
+
+type scopeProbe struct {
+	scope string
+	path  func(spaceId string) string
+	// needsSpace probes are skipped on sites without any space.
+	needsSpace bool
+	options    []Option
+}
+
+// scopeProbes are read-only requests, one per scope needed to sync.
+var scopeProbes = []scopeProbe{
+	{
+		scope: "read:user:confluence",
+		path:  func(string) string { return CurrentUserUrlPath },
+	},
+	{
+		scope:   "read:content-details:confluence",
+		path:    func(string) string { return SearchUrlPath },
+		options: []Option{withQueryParameters(map[string]interface{}{"cql": "type=user", "limit": 1})},
+	},
+	{
+		scope:   "read:group:confluence",
+		path:    func(string) string { return GroupsListUrlPath },
+		options: []Option{withQueryParameters(map[string]interface{}{"limit": 1})},
+	},
+	{
+		scope:      "read:space.permission:confluence",
+		path:       func(spaceId string) string { return fmt.Sprintf(SpacePermissionsListUrlPath, url.PathEscape(spaceId)) },
+		needsSpace: true,
+		options:    []Option{withQueryParameters(map[string]interface{}{"limit": 1})},
+	},
+}
+
+// provisionScopes are the scopes needed to provision, each with the classic
+// scope that grants the same access to OAuth 2.0 apps.
+var provisionScopes = [][2]string{
+	{"write:group:confluence", "write:confluence-groups"},
+	{"write:space.permission:confluence", "write:confluence-space"},
+}
+
+// CheckScopes reports the scopes the credentials lack without changing
+// anything on the site. Sync scopes are probed with one read request each;
+// the gateway answers 401 when a token lacks the scope an endpoint needs.
+// Provisioning scopes are compared with the scopes granted to the token when
+// the gateway lists them, and otherwise left to fail when provisioning.
+func (c *ConfluenceClient) CheckScopes(ctx context.Context) (*ScopeCheck, error) {
+	rv := &ScopeCheck{}
+
+	// Listing spaces is both a probe and the way to find a space to probe
+	// the permissions endpoint with.
+	spaces, _, _, err := c.GetSpaces(ctx, 1, "")
+	switch {
+	case isMissingScope(err):
+		rv.MissingSyncScopes = append(rv.MissingSyncScopes, "read:space:confluence")
+	case err != nil:
+		return nil, err
+	}
+
+	spaceId := ""
+	if len(spaces) > 0 {
+		spaceId = spaces[0].Id
+	}
+	for _, probe := range scopeProbes {
+		if probe.needsSpace && spaceId == "" {
+			continue
+		}
+
+		probeUrl, err := c.parse(probe.path(spaceId), probe.options...)
+		if err != nil {
+			return nil, err
+		}
+		_, err = c.get(ctx, probeUrl, nil)
+		switch {
+		case isMissingScope(err):
+			rv.MissingSyncScopes = append(rv.MissingSyncScopes, probe.scope)
+		case isUnauthorized(err):
+			return nil, fmt.Errorf("confluence-connector: the credentials were rejected: %w", err)
+		}
+	}
+
+	granted, ok := c.grantedScopes(ctx)
+	if ok {
+		rv.ProvisionScopesChecked = true
+		for _, scopes := range provisionScopes {
+			if !slices.Contains(granted, scopes[0]) && !slices.Contains(granted, scopes[1]) {
+				rv.MissingProvisionScopes = append(rv.MissingProvisionScopes, scopes[0])
+			}
+		}
+	}
+
+	return rv, nil
+}
+
+// grantedScopes returns the scopes granted to the credentials on the site,
+// as listed among the resources they can access. Only OAuth 2.0 tokens can
+// list them.
+func (c *ConfluenceClient) grantedScopes(ctx context.Context) ([]string, bool) {
+	if c.cloudId == "" {
+		return nil, false
+	}
+	resourcesUrl, err := url.Parse(atlassianGatewayUrl + accessibleResourcesUrlPath)
+	if err != nil {
+		return nil, false
+	}
+
+	var response []accessibleResource
+	_, err = c.get(ctx, resourcesUrl, &response)
+	if err != nil {
+		return nil, false
+	}
+	for _, resource := range response {
+		if resource.Id == c.cloudId {
+			return resource.Scopes, true
+		}
+	}
+	return nil, false
+}
+
+func isUnauthorized(err error) bool {
+	var reqErr *RequestError
+	return errors.As(err, &reqErr) && reqErr.Status == http.StatusUnauthorized
+}
+
+// isMissingScope tells a token without the scope an endpoint needs apart
+// from credentials that are rejected altogether: the gateway answers both
+// with 401, but only the former mentions the scope.
+func isMissingScope(err error) bool {
+	var reqErr *RequestError
+	return errors.As(err, &reqErr) &&
+		reqErr.Status == http.StatusUnauthorized &&
+		strings.Contains(strings.ToLower(reqErr.Body), "scope")
+}
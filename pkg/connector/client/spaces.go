@@ -0,0 +1,207 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// CreateSpace creates a space. The caller becomes its first admin.
+func (c *ConfluenceClient) CreateSpace(
+	ctx context.Context,
+	space CreateSpaceRequest,
+) (*ConfluenceSpace, *v2.RateLimitDescription, error) {
+	if c.dataCenter {
+		return c.createSpaceDataCenter(ctx, space)
+	}
+
+	spacesUrl, err := c.parse(SpacesListUrlPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body := createSpaceRequestBody{Key: space.Key, Name: space.Name}
+	if space.Description != "" {
+		body.Description = &ConfluenceSpaceDescriptionValue{Value: space.Description, Representation: "plain"}
+	}
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ConfluenceSpace
+	ratelimitData, err := c.post(ctx, spacesUrl, &response, strings.NewReader(string(bodyBytes)))
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	return &response, ratelimitData, nil
+}
+
+// DeleteSpace deletes a space and everything in it. Confluence deletes
+// spaces in the background, so the space can still be listed for a while.
+func (c *ConfluenceClient) DeleteSpace(
+	ctx context.Context,
+	spaceId string,
+) (*v2.RateLimitDescription, error) {
+	spaceUrl, ratelimitData, err := c.spaceV1Url(ctx, spaceId)
+	if err != nil {
+		return ratelimitData, err
+	}
+	return c.delete(ctx, spaceUrl, nil)
+}
+
+// SetSpaceStatus archives a space or restores an archived one.
+func (c *ConfluenceClient) SetSpaceStatus(
+	ctx context.Context,
+	spaceId string,
+	status string,
+) (*v2.RateLimitDescription, error) {
+	spaceUrl, ratelimitData, err := c.spaceV1Url(ctx, spaceId)
+	if err != nil {
+		return ratelimitData, err
+	}
+
+	bodyBytes, err := json.Marshal(updateSpaceStatusRequestBody{Status: status})
+	if err != nil {
+		return nil, err
+	}
+
+	// The v1 space in the response has a numeric ID, it is not decoded.
+	return c.put(ctx, spaceUrl, nil, strings.NewReader(string(bodyBytes)))
+}
+
+// spaceV1Url returns the v1 URL of a space, which is addressed by key. On
+// Data Center the space ID is the key already.
+func (c *ConfluenceClient) spaceV1Url(
+	ctx context.Context,
+	spaceId string,
+) (*url.URL, *v2.RateLimitDescription, error) {
+	if c.dataCenter {
+		spaceUrl, err := c.parse(fmt.Sprintf(dataCenterSpaceGetUrlPath, url.PathEscape(spaceId)))
+		return spaceUrl, nil, err
+	}
+
+	space, ratelimitData, err := c.findSpace(ctx, spaceId)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	spaceUrl, err := c.parse(fmt.Sprintf(spaceV1UrlPath, url.PathEscape(space.Key)))
+	return spaceUrl, ratelimitData, err
+}
+
+func (c *ConfluenceClient) createSpaceDataCenter(
+	ctx context.Context,
+	space CreateSpaceRequest,
+) (*ConfluenceSpace, *v2.RateLimitDescription, error) {
+	spacesUrl, err := c.parse(DataCenterSpacesListUrlPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body := dataCenterCreateSpaceRequestBody{Key: space.Key, Name: space.Name}
+	if space.Description != "" {
+		body.Description = &ConfluenceSpaceDescription{
+			Plain: ConfluenceSpaceDescriptionValue{Value: space.Description, Representation: "plain"},
+		}
+	}
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response dataCenterSpace
+	ratelimitData, err := c.post(ctx, spacesUrl, &response, strings.NewReader(string(bodyBytes)))
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	return response.toSpace(), ratelimitData, nil
+}
+
+func (c *ConfluenceClient) getSpaceDataCenter(
+	ctx context.Context,
+	spaceKey string,
+) (*ConfluenceSpace, *v2.RateLimitDescription, error) {
+	spaceUrl, err := c.parse(
+		fmt.Sprintf(dataCenterSpaceGetUrlPath, url.PathEscape(spaceKey)),
+		withQueryParameters(map[string]interface{}{"expand": dataCenterSpaceExpand}),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response dataCenterSpace
+	ratelimitData, err := c.get(ctx, spaceUrl, &response)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	return response.toSpace(), ratelimitData, nil
+}
+
+// GetSpaceLabels returns the names of every label of a space.
+func (c *ConfluenceClient) GetSpaceLabels(
+	ctx context.Context,
+	spaceId string,
+) ([]string, *v2.RateLimitDescription, error) {
+	if c.dataCenter {
+		return c.getSpaceLabelsDataCenter(ctx, spaceId)
+	}
+
+	labels := newPager(maxResults, func(ctx context.Context, pageToken string, pageSize int) ([]ConfluenceLabel, string, *v2.RateLimitDescription, error) {
+		return getCursorPage[ConfluenceLabel](ctx, c, fmt.Sprintf(spaceLabelsUrlPath, url.PathEscape(spaceId)), pageToken, pageSize)
+	})
+	var names []string
+	for label, err := range labels.All(ctx) {
+		if err != nil {
+			return nil, labels.RateLimit(), err
+		}
+		names = append(names, label.Name)
+	}
+	return names, labels.RateLimit(), nil
+}
+
+func (c *ConfluenceClient) getSpaceLabelsDataCenter(
+	ctx context.Context,
+	spaceKey string,
+) ([]string, *v2.RateLimitDescription, error) {
+	spaceUrl, err := c.parse(
+		fmt.Sprintf(dataCenterSpaceGetUrlPath, url.PathEscape(spaceKey)),
+		withQueryParameters(map[string]interface{}{"expand": "metadata.labels"}),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response dataCenterSpaceWithLabels
+	ratelimitData, err := c.get(ctx, spaceUrl, &response)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	names := make([]string, 0, len(response.Metadata.Labels.Results))
+	for _, label := range response.Metadata.Labels.Results {
+		names = append(names, label.Name)
+	}
+	return names, ratelimitData, nil
+}
+
+// GetPersonalSpaceOwner returns the account ID of the user a personal space
+// belongs to, or "" if it cannot be told. On Cloud, personal spaces are
+// created by their owner. On Data Center, their key is "~" followed by the
+// owner's username, which is resolved to a user key.
+func (c *ConfluenceClient) GetPersonalSpaceOwner(
+	ctx context.Context,
+	space *ConfluenceSpace,
+) (string, *v2.RateLimitDescription, error) {
+	if !c.dataCenter {
+		return space.AuthorId, nil, nil
+	}
+
+	username, ok := strings.CutPrefix(space.Key, personalSpaceKeyPrefix)
+	if !ok || username == "" {
+		return "", nil, nil
+	}
+	return c.findUserKeyDataCenter(ctx, username)
+}
@@ -5,12 +5,21 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"strings"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 
+	cfg "github.com/conductorone/baton-confluence/pkg/config"
 	"github.com/conductorone/baton-confluence/pkg/connector/client"
 )
 
@@ -20,46 +29,79 @@ const (
 )
 
 type Config struct {
-	UserName string
-	ApiKey   string
-	Domain   string
+	UserName          string
+	ApiKey            string
+	Domain            string
+	AuthMethod        string
+	OAuthClientId     string
+	OAuthClientSecret string
+	OAuthRefreshToken string
+	// OAuthRefreshTokenFile keeps the rotated 3LO refresh token across runs.
+	OAuthRefreshTokenFile string
+	CloudId               string
+	PersonalAccessToken   string
+	DeploymentType        string
+	SkipPersonalSpaces    bool
+	// PersonalSpaceOwners limits the personal spaces synced to those of these
+	// account IDs or Data Center usernames.
+	PersonalSpaceOwners []string
+	// IncludeSpaceKeys and ExcludeSpaceKeys are glob patterns of space keys.
+	IncludeSpaceKeys []string
+	ExcludeSpaceKeys []string
+	SpaceTypes       []string
+	SpaceStatuses    []string
+	// IncludeSpaceLabels and ExcludeSpaceLabels match spaces with any of the
+	// labels.
+	IncludeSpaceLabels []string
+	ExcludeSpaceLabels []string
+	UseRbac            bool
+	// SpaceAccessMode selects granular permissions, RBAC space roles or both.
+	// UseRbac is a shorthand for the rbac mode.
+	SpaceAccessMode string
+	Nouns           []string
+	Verbs           []string
+	// DiscoverSpaceEntitlements builds space entitlements from the operations
+	// Confluence reports for each space rather than from Nouns and Verbs.
+	DiscoverSpaceEntitlements bool
+	// SyncContentRestrictions syncs restricted pages and blog posts.
+	SyncContentRestrictions bool
+	// MaxRequestsPerSecond caps the request rate. Zero leaves only the
+	// adaptive pacing driven by Confluence's rate limit headers.
+	MaxRequestsPerSecond int
+	// SyncConcurrency is how many group member and space permission pages
+	// are fetched at once.
+	SyncConcurrency int
+	// OrgId and OrgAdminApiKey read managed accounts from the Atlassian
+	// organization admin API. Both or neither are set.
+	OrgId          string
+	OrgAdminApiKey string
 }
 
 type Confluence struct {
-	client             *client.ConfluenceClient
-	domain             string
-	apiKey             string
-	userName           string
-	skipPersonalSpaces bool
-	useRbac            bool
-	nouns              []string
-	verbs              []string
-}
-
-var defaultNouns = []string{
-	"attachment",
-	"blogpost",
-	"comment",
-	"page",
-	resourceTypeSpaceID,
-}
-
-var defaultVerbs = []string{
-	"administer",
-	"archive",
-	"create",
-	"delete",
-	"export",
-	"read",
-	"restrict_content",
-	"update",
-}
-
-func filterArgs(args, defaults []string) ([]string, error) {
+	client          *client.ConfluenceClient
+	domain          string
+	apiKey          string
+	userName        string
+	spaceFilter     *spaceFilter
+	spaceAccessMode string
+	// spaceRoleMode is the site's space role mode, when it was detected.
+	spaceRoleMode     string
+	nouns             []string
+	verbs             []string
+	discoverSpaceEnts bool
+	syncContent       bool
+	syncConcurrency   int
+	// orgAdmin is set when an Atlassian organization is configured.
+	orgAdmin *client.OrgAdminClient
+}
+
+// filterArgs validates the configured nouns or verbs against the valid ones
+// and returns them in catalog order, or the defaults if none are configured.
+func filterArgs(args, valid, defaults []string) ([]string, error) {
 	var validArgs []string
 
 	argsSet := mapset.NewSet(args...)
-	defaultsSet := mapset.NewSet(defaults...)
+	validSet := mapset.NewSet(valid...)
 
 	// If there were no args at all then use the defaults
 	if argsSet.Cardinality() == 0 {
@@ -68,13 +110,13 @@ func filterArgs(args, defaults []string) ([]string, error) {
 
 	// Validate that all args are valid
 	for _, arg := range args {
-		if !defaultsSet.Contains(arg) {
+		if !validSet.Contains(arg) {
 			return nil, fmt.Errorf("invalid input: %s", arg)
 		}
 	}
 
-	// Otherwise, grab from the defaults in the right order
-	for _, arg := range defaults {
+	// Otherwise, grab from the valid args in the right order
+	for _, arg := range valid {
 		if argsSet.Contains(arg) {
 			validArgs = append(validArgs, arg)
 		}
@@ -88,40 +130,178 @@ func filterArgs(args, defaults []string) ([]string, error) {
 	return validArgs, nil
 }
 
-func New(
-	ctx context.Context,
-	apiKey string,
-	domainUrl string,
-	username string,
-	skipPersonalSpaces bool,
-	useRbac bool,
-	nouns []string,
-	verbs []string,
-) (*Confluence, error) {
-	client, err := client.NewConfluenceClient(ctx, username, apiKey, domainUrl)
+func newClient(ctx context.Context, config Config) (*client.ConfluenceClient, error) {
+	if config.DeploymentType == cfg.DeploymentTypeDataCenter {
+		return newDataCenterClient(ctx, config)
+	}
+
+	switch config.AuthMethod {
+	case "", cfg.AuthMethodAPIToken:
+		return client.NewConfluenceClient(ctx, config.UserName, config.ApiKey, config.Domain)
+	case cfg.AuthMethodScopedAPIToken:
+		return client.NewScopedTokenConfluenceClient(ctx, config.UserName, config.ApiKey, config.Domain, config.CloudId)
+	case cfg.AuthMethodOAuth2:
+		if config.OAuthRefreshTokenFile == "" {
+			ctxzap.Extract(ctx).Warn(
+				"confluence-connector: oauth-refresh-token-file is not set; Atlassian rotates refresh tokens, " +
+					"so the configured refresh token will not work for the next run",
+			)
+		}
+		credentials := client.NewOAuth2RefreshTokenCredentials(
+			config.OAuthClientId,
+			config.OAuthClientSecret,
+			config.OAuthRefreshToken,
+			config.OAuthRefreshTokenFile,
+		)
+		return client.NewOAuth2ConfluenceClient(ctx, config.Domain, config.CloudId, credentials)
+	case cfg.AuthMethodOAuth2ClientCredentials:
+		credentials, err := client.NewOAuth2ClientCredentials(config.OAuthClientId, config.OAuthClientSecret)
+		if err != nil {
+			return nil, err
+		}
+		return client.NewOAuth2ConfluenceClient(ctx, config.Domain, config.CloudId, credentials)
+	}
+	return nil, fmt.Errorf("confluence-connector: unsupported auth method for Confluence Cloud: %s", config.AuthMethod)
+}
+
+// resolveSpaceAccessMode returns the space access mode to sync, with use-rbac
+// standing for the rbac mode when no other mode is selected.
+func resolveSpaceAccessMode(config Config) (string, error) {
+	switch config.SpaceAccessMode {
+	case "", cfg.SpaceAccessModePermissions:
+		if config.UseRbac {
+			return cfg.SpaceAccessModeRbac, nil
+		}
+		return cfg.SpaceAccessModePermissions, nil
+	case cfg.SpaceAccessModeRbac, cfg.SpaceAccessModeHybrid, cfg.SpaceAccessModeAuto:
+		return config.SpaceAccessMode, nil
+	}
+	return "", fmt.Errorf("confluence-connector: unsupported space access mode: %s", config.SpaceAccessMode)
+}
+
+// detectSpaceAccessMode picks the space access mode that matches the site's
+// space role mode, and returns the role mode it detected. Sites without
+// space roles, including Data Center, sync granular permissions.
+func detectSpaceAccessMode(ctx context.Context, c *client.ConfluenceClient) (string, string, error) {
+	if c.IsDataCenter() {
+		return cfg.SpaceAccessModePermissions, "", nil
+	}
+
+	roleMode, _, err := c.GetSpaceRoleMode(ctx)
+	if err != nil {
+		var reqErr *client.RequestError
+		if errors.As(err, &reqErr) && reqErr.Status == http.StatusNotFound {
+			return cfg.SpaceAccessModePermissions, "", nil
+		}
+		return "", "", fmt.Errorf("confluence-connector: failed to detect the space role mode: %w", err)
+	}
+
+	switch roleMode {
+	case client.SpaceRoleModeRoles:
+		return cfg.SpaceAccessModeRbac, roleMode, nil
+	case client.SpaceRoleModeRolesTransition:
+		return cfg.SpaceAccessModeHybrid, roleMode, nil
+	}
+	return cfg.SpaceAccessModePermissions, roleMode, nil
+}
+
+func newDataCenterClient(ctx context.Context, config Config) (*client.ConfluenceClient, error) {
+	if config.SpaceAccessMode == cfg.SpaceAccessModeRbac || config.SpaceAccessMode == cfg.SpaceAccessModeHybrid {
+		return nil, errors.New("confluence-connector: space roles (use-rbac) are not supported on Confluence Data Center")
+	}
+	if config.DiscoverSpaceEntitlements {
+		return nil, errors.New("confluence-connector: discover-space-entitlements is not supported on Confluence Data Center")
+	}
+	if config.SyncContentRestrictions {
+		return nil, errors.New("confluence-connector: sync-content-restrictions is not supported on Confluence Data Center")
+	}
+	if config.OrgId != "" || config.OrgAdminApiKey != "" {
+		return nil, errors.New("confluence-connector: Atlassian organizations (org-id) are not supported on Confluence Data Center")
+	}
+
+	switch config.AuthMethod {
+	case "", cfg.AuthMethodAPIToken:
+		return client.NewDataCenterConfluenceClient(ctx, config.Domain, uhttp.NewBasicAuth(config.UserName, config.ApiKey))
+	case cfg.AuthMethodPersonalAccessToken:
+		return client.NewDataCenterConfluenceClient(ctx, config.Domain, uhttp.NewBearerAuth(config.PersonalAccessToken))
+	}
+	return nil, fmt.Errorf("confluence-connector: unsupported auth method for Confluence Data Center: %s", config.AuthMethod)
+}
+
+// newOrgAdminClient returns a client for the configured Atlassian
+// organization, or nil when none is configured.
+func newOrgAdminClient(ctx context.Context, config Config) (*client.OrgAdminClient, error) {
+	if config.OrgId == "" && config.OrgAdminApiKey == "" {
+		return nil, nil
+	}
+	if config.OrgId == "" || config.OrgAdminApiKey == "" {
+		return nil, errors.New("confluence-connector: org-id and org-admin-api-key must be set together")
+	}
+	return client.NewOrgAdminClient(ctx, config.OrgId, config.OrgAdminApiKey, "")
+}
+
+func New(ctx context.Context, config Config) (*Confluence, error) {
+	spaceAccessMode, err := resolveSpaceAccessMode(config)
+	if err != nil {
+		return nil, err
+	}
+	config.SpaceAccessMode = spaceAccessMode
+
+	client, err := newClient(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	client.SetRequestsPerSecond(config.MaxRequestsPerSecond)
+	client.SetConcurrency(config.SyncConcurrency)
+
+	orgAdmin, err := newOrgAdminClient(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	spaceRoleMode := ""
+	if spaceAccessMode == cfg.SpaceAccessModeAuto {
+		spaceAccessMode, spaceRoleMode, err = detectSpaceAccessMode(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		ctxzap.Extract(ctx).Info(
+			"confluence-connector: detected space access mode",
+			zap.String("space_role_mode", spaceRoleMode),
+			zap.String("space_access_mode", spaceAccessMode),
+		)
+	}
+
+	spaceFilter, err := newSpaceFilter(config)
 	if err != nil {
 		return nil, err
 	}
 
-	filteredNouns, err := filterArgs(nouns, defaultNouns)
+	filteredNouns, err := filterArgs(config.Nouns, catalogTargets(), cfg.DefaultNouns)
 	if err != nil {
 		return nil, err
 	}
 
-	filteredVerbs, err := filterArgs(verbs, defaultVerbs)
+	filteredVerbs, err := filterArgs(config.Verbs, validVerbs(), cfg.DefaultVerbs)
 	if err != nil {
 		return nil, err
 	}
+	warnDeprecatedVerbs(ctx, filteredVerbs)
 
 	rv := &Confluence{
-		domain:             domainUrl,
-		apiKey:             apiKey,
-		userName:           username,
-		client:             client,
-		skipPersonalSpaces: skipPersonalSpaces,
-		useRbac:            useRbac,
-		nouns:              filteredNouns,
-		verbs:              filteredVerbs,
+		domain:            config.Domain,
+		apiKey:            config.ApiKey,
+		userName:          config.UserName,
+		client:            client,
+		spaceFilter:       spaceFilter,
+		spaceAccessMode:   spaceAccessMode,
+		spaceRoleMode:     spaceRoleMode,
+		nouns:             filteredNouns,
+		verbs:             filteredVerbs,
+		discoverSpaceEnts: config.DiscoverSpaceEntitlements,
+		syncContent:       config.SyncContentRestrictions,
+		syncConcurrency:   config.SyncConcurrency,
+		orgAdmin:          orgAdmin,
 	}
 	return rv, nil
 }
@@ -132,37 +312,148 @@ func (c *Confluence) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error
 		Url: c.domain,
 	})
 
+	profile, err := structpb.NewStruct(map[string]interface{}{
+		"space_access_mode": c.spaceAccessMode,
+		"space_role_mode":   c.spaceRoleMode,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &v2.ConnectorMetadata{
 		DisplayName: "Confluence",
 		Description: "Connector syncing Confluence users and groups to Baton",
 		Annotations: annos,
+		Profile:     profile,
 	}, nil
 }
 
 func (c *Confluence) Validate(ctx context.Context) (annotations.Annotations, error) {
 	var err error
-	if c.useRbac {
+	switch c.spaceAccessMode {
+	case cfg.SpaceAccessModeRbac:
 		err = c.client.VerifyRbac(ctx)
-	} else {
+	case cfg.SpaceAccessModeHybrid:
+		// Hybrid syncs both through the v1 and the v2 APIs.
+		err = c.client.Verify(ctx)
+		if err == nil {
+			err = c.client.VerifyRbac(ctx)
+		}
+	default:
 		err = c.client.Verify(ctx)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("confluence-connector: failed to validate API keys: %w", err)
 	}
 
+	if c.client.UsesGateway() {
+		err = c.validateScopes(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.orgAdmin != nil {
+		err = c.orgAdmin.Verify(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("confluence-connector: failed to validate the organization admin API key: %w", err)
+		}
+	}
+
 	return nil, nil
 }
 
+// validateScopes fails when the credentials lack a scope needed to sync. A
+// missing provisioning scope is only logged, since sync-only deployments are
+// expected to leave out the write scopes.
+func (c *Confluence) validateScopes(ctx context.Context) error {
+	l := ctxzap.Extract(ctx)
+
+	scopes, err := c.client.CheckScopes(ctx)
+	if err != nil {
+		return fmt.Errorf("confluence-connector: failed to check credential scopes: %w", err)
+	}
+
+	if !scopes.ProvisionScopesChecked {
+		l.Info("confluence-connector: provisioning scopes cannot be read from these credentials; a missing scope fails when provisioning")
+	}
+	if len(scopes.MissingProvisionScopes) > 0 {
+		l.Warn(
+			"confluence-connector: credentials are missing scopes required for provisioning",
+			zap.Strings("missing_scopes", scopes.MissingProvisionScopes),
+		)
+	}
+
+	if len(scopes.MissingSyncScopes) > 0 {
+		return status.Errorf(
+			codes.PermissionDenied,
+			"confluence-connector: credentials are missing scopes required for sync: %s (missing for provisioning: %s)",
+			strings.Join(scopes.MissingSyncScopes, ", "),
+			formatScopes(scopes.MissingProvisionScopes),
+		)
+	}
+
+	return nil
+}
+
+func formatScopes(scopes []string) string {
+	if len(scopes) == 0 {
+		return "none"
+	}
+	return strings.Join(scopes, ", ")
+}
+
 func (c *Confluence) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.ReadCloser, error) {
 	return "", nil, nil
 }
 
+// EventFeeds returns the audit log feed, which reports the memberships and
+// space permissions granted or revoked, and the groups and spaces that changed,
+// since the last sync. Data Center has no audit log REST API, so it has no
+// feed. Cloud only lets Confluence admins read the audit log; the feed reports
+// no events until the credentials can.
+func (c *Confluence) EventFeeds(ctx context.Context) []connectorbuilder.EventFeed {
+	if c.client.IsDataCenter() {
+		return nil
+	}
+	return []connectorbuilder.EventFeed{
+		newAuditEventFeed(c.client, c.spaceFilter, c.spaceBuilderOptions()),
+	}
+}
+
+// spaceBuilderOptions returns the configured options of the space builder.
+func (c *Confluence) spaceBuilderOptions() spaceBuilderOptions {
+	return spaceBuilderOptions{
+		accessMode:              c.spaceAccessMode,
+		nouns:                   c.nouns,
+		verbs:                   c.verbs,
+		discoverEntitlements:    c.discoverSpaceEnts,
+		syncContentRestrictions: c.syncContent,
+		concurrency:             c.syncConcurrency,
+	}
+}
+
 func (c *Confluence) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncerV2 {
-	return []connectorbuilder.ResourceSyncerV2{
-		groupBuilder(c.client),
-		userBuilder(c.client),
-		newSpaceBuilder(c.client, c.skipPersonalSpaces, c.useRbac, c.nouns, c.verbs),
+	// Data Center reports neither the operations of users nor the site-admins
+	// group, so there is nothing to sync the site from.
+	var globalPermissions *globalPermissionHolders
+	if !c.client.IsDataCenter() {
+		globalPermissions = newGlobalPermissionHolders()
+	}
+
+	syncers := []connectorbuilder.ResourceSyncerV2{
+		groupBuilder(c.client, c.syncConcurrency, c.spaceAccessMode),
+		userBuilder(c.client, c.orgAdmin, globalPermissions, c.syncConcurrency),
+	}
+	if globalPermissions != nil {
+		syncers = append(syncers, newSiteBuilder(c.client, c.domain, globalPermissions))
+	}
+	return append(syncers,
+		newSpaceBuilder(c.client, c.spaceFilter, c.spaceBuilderOptions()),
+		newContentBuilder(c.client, pageResourceType),
+		newContentBuilder(c.client, blogpostResourceType),
+		newAccessClassBuilder(),
 		newSpaceRoleBuilder(c.client),
 		newSpaceRoleAssignmentBuilder(c.client),
-	}
+	)
 }
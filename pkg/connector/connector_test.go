@@ -0,0 +1,81 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cfg "github.com/conductorone/baton-confluence/pkg/config"
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+	"github.com/conductorone/baton-confluence/test"
+)
+
+func TestAutoSpaceAccessMode(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		roleMode        string
+		spaceAccessMode string
+	}{
+		{client.SpaceRoleModePreRoles, cfg.SpaceAccessModePermissions},
+		{client.SpaceRoleModeRolesTransition, cfg.SpaceAccessModeHybrid},
+		{client.SpaceRoleModeRoles, cfg.SpaceAccessModeRbac},
+	} {
+		t.Run("should sync "+tc.spaceAccessMode+" on a "+tc.roleMode+" site", func(t *testing.T) {
+			site, server := test.FakeServer(t)
+			site.SetSpaceRoleMode(tc.roleMode)
+
+			c, err := New(ctx, Config{
+				Domain:          server.URL,
+				UserName:        "admin",
+				ApiKey:          "API Key",
+				SpaceAccessMode: cfg.SpaceAccessModeAuto,
+			})
+			require.Nil(t, err)
+			require.Equal(t, tc.spaceAccessMode, c.spaceAccessMode)
+
+			metadata, err := c.Metadata(ctx)
+			require.Nil(t, err)
+			require.Equal(t, tc.roleMode, metadata.Profile.Fields["space_role_mode"].GetStringValue())
+			require.Equal(t, tc.spaceAccessMode, metadata.Profile.Fields["space_access_mode"].GetStringValue())
+		})
+	}
+
+	t.Run("should sync granular permissions when the site has no space roles", func(t *testing.T) {
+		site, server := test.FakeServer(t)
+		site.SetSpaceRoleMode("")
+
+		c, err := New(ctx, Config{
+			Domain:          server.URL,
+			UserName:        "admin",
+			ApiKey:          "API Key",
+			SpaceAccessMode: cfg.SpaceAccessModeAuto,
+		})
+		require.Nil(t, err)
+		require.Equal(t, cfg.SpaceAccessModePermissions, c.spaceAccessMode)
+	})
+}
+
+func TestOrgAdminConfig(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+	site.SetOrg("org-1")
+
+	_, err := New(ctx, Config{Domain: server.URL, UserName: "admin", ApiKey: "API Key", OrgId: "org-1"})
+	require.ErrorContains(t, err, "must be set together")
+
+	_, err = New(ctx, Config{
+		Domain:         server.URL,
+		DeploymentType: cfg.DeploymentTypeDataCenter,
+		UserName:       "admin",
+		ApiKey:         "API Key",
+		OrgId:          "org-1",
+		OrgAdminApiKey: "Admin Key",
+	})
+	require.ErrorContains(t, err, "not supported on Confluence Data Center")
+
+	c, err := New(ctx, Config{Domain: server.URL, UserName: "admin", ApiKey: "API Key", OrgId: "org-1", OrgAdminApiKey: "Admin Key"})
+	require.Nil(t, err)
+	require.NotNil(t, c.orgAdmin)
+}
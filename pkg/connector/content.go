@@ -0,0 +1,271 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grantSdk "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+)
+
+// contentOperations are the operations a page or blog post can be restricted
+// on. Each is an entitlement of the content.
+var contentOperations = []string{
+	client.ContentOperationRead,
+	client.ContentOperationUpdate,
+}
+
+// contentBuilder syncs the restricted pages or blog posts of each space. The
+// page and blog post resource types share it.
+type contentBuilder struct {
+	client       *client.ConfluenceClient
+	resourceType *v2.ResourceType
+}
+
+func (b *contentBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return b.resourceType
+}
+
+// List returns the content of a space that is restricted, directly or through
+// one of its ancestors. Unrestricted content is left out, so a page of
+// results may be empty.
+func (b *contentBuilder) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	opts rs.SyncOpAttrs,
+) ([]*v2.Resource, *rs.SyncOpResults, error) {
+	if parentResourceID == nil || parentResourceID.ResourceType != spaceResourceType.Id {
+		return nil, nil, nil
+	}
+
+	contents, nextToken, ratelimitData, err := b.client.SpaceContent(parentResourceID.Resource, b.resourceType.Id).
+		WithPageSize(ResourcesPageSize).
+		Page(ctx, opts.PageToken.Token)
+	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+	if err != nil {
+		return nil, syncResults("", outputAnnotations), fmt.Errorf("confluence-connector: failed to list %s content: %w", b.resourceType.Id, err)
+	}
+
+	rv := make([]*v2.Resource, 0)
+	for _, content := range contents {
+		restrictedAncestorIds := make([]interface{}, 0)
+		for _, ancestor := range content.Ancestors {
+			if ancestor.Restrictions.Read.IsRestricted() {
+				restrictedAncestorIds = append(restrictedAncestorIds, ancestor.Id)
+			}
+		}
+		if !content.Restrictions.Read.IsRestricted() &&
+			!content.Restrictions.Update.IsRestricted() &&
+			len(restrictedAncestorIds) == 0 {
+			continue
+		}
+
+		r, err := contentResource(b.resourceType, &content, parentResourceID, restrictedAncestorIds)
+		if err != nil {
+			return nil, nil, err
+		}
+		rv = append(rv, r)
+	}
+
+	return rv, syncResults(nextToken, outputAnnotations), nil
+}
+
+func (b *contentBuilder) Entitlements(
+	_ context.Context,
+	res *v2.Resource,
+	_ rs.SyncOpAttrs,
+) ([]*v2.Entitlement, *rs.SyncOpResults, error) {
+	entitlements := make([]*v2.Entitlement, 0, len(contentOperations))
+	for _, operation := range contentOperations {
+		entitlements = append(entitlements, entitlement.NewPermissionEntitlement(
+			res,
+			operation,
+			entitlement.WithGrantableTo(resourceTypeUser),
+			entitlement.WithGrantableTo(resourceTypeGroup),
+			entitlement.WithDisplayName(fmt.Sprintf("Can %s %s", operation, res.DisplayName)),
+			entitlement.WithDescription(
+				fmt.Sprintf("Is named in the %s restriction of the %s %s in Confluence", operation, res.DisplayName, b.resourceType.DisplayName),
+			),
+		))
+	}
+	return entitlements, syncResults("", nil), nil
+}
+
+// Grants returns the users and groups named in the content's own
+// restrictions. Restrictions inherited from ancestors are granted on the
+// ancestors.
+func (b *contentBuilder) Grants(
+	ctx context.Context,
+	res *v2.Resource,
+	_ rs.SyncOpAttrs,
+) ([]*v2.Grant, *rs.SyncOpResults, error) {
+	var grants []*v2.Grant
+	var ratelimitData *v2.RateLimitDescription
+	for _, operation := range contentOperations {
+		var restriction *client.ConfluenceContentRestriction
+		var err error
+		restriction, ratelimitData, err = b.restriction(ctx, res.Id.Resource, operation)
+		if err != nil {
+			return nil, syncResults("", WithRateLimitAnnotations(ratelimitData)), err
+		}
+		for _, user := range restriction.Restrictions.User.Results {
+			grants = append(grants, grantSdk.NewGrant(
+				res,
+				operation,
+				&v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: user.AccountId},
+			))
+		}
+		for _, group := range restriction.Restrictions.Group.Results {
+			grants = append(grants, grantSdk.NewGrant(
+				res,
+				operation,
+				&v2.ResourceId{ResourceType: resourceTypeGroup.Id, Resource: group.Id},
+				grantSdk.WithAnnotation(&v2.GrantExpandable{
+					EntitlementIds: []string{
+						fmt.Sprintf("group:%s:member", group.Id),
+					},
+				}),
+			))
+		}
+	}
+
+	return grants, syncResults("", WithRateLimitAnnotations(ratelimitData)), nil
+}
+
+// Grant adds the principal to the restriction of the entitlement's operation.
+// Content whose operation is not restricted is refused: adding the first user
+// or group to a restriction would lock everyone else out.
+func (b *contentBuilder) Grant(
+	ctx context.Context,
+	principal *v2.Resource,
+	ent *v2.Entitlement,
+) ([]*v2.Grant, annotations.Annotations, error) {
+	contentId := ent.Resource.Id.Resource
+	restriction, ratelimitData, err := b.restriction(ctx, contentId, ent.Slug)
+	if err != nil {
+		return nil, WithRateLimitAnnotations(ratelimitData), err
+	}
+	if !restriction.IsRestricted() {
+		return nil, nil, status.Errorf(
+			codes.FailedPrecondition,
+			"confluence-connector: %s %s has no %s restriction to add %s to",
+			b.resourceType.Id,
+			contentId,
+			ent.Slug,
+			principal.Id.Resource,
+		)
+	}
+	if restriction.Includes(principal.Id.ResourceType, principal.Id.Resource) {
+		return nil, annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	ratelimitData, err = b.client.AddContentRestriction(
+		ctx,
+		contentId,
+		ent.Slug,
+		principal.Id.Resource,
+		principal.Id.ResourceType,
+	)
+	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+	if err != nil {
+		return nil, outputAnnotations, fmt.Errorf("confluence-connector: failed to add to content restriction: %w", err)
+	}
+	g := grantSdk.NewGrant(ent.Resource, ent.Slug, principal.Id)
+	return []*v2.Grant{g}, outputAnnotations, nil
+}
+
+// Revoke removes the principal from the restriction of the entitlement's
+// operation. Removing the last user or group is refused: it would lift the
+// restriction and open the content to everyone in the space.
+func (b *contentBuilder) Revoke(
+	ctx context.Context,
+	grant *v2.Grant,
+) (annotations.Annotations, error) {
+	contentId := grant.Entitlement.Resource.Id.Resource
+	operation := grant.Entitlement.Slug
+	restriction, ratelimitData, err := b.restriction(ctx, contentId, operation)
+	if err != nil {
+		return WithRateLimitAnnotations(ratelimitData), err
+	}
+	if !restriction.Includes(grant.Principal.Id.ResourceType, grant.Principal.Id.Resource) {
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+	if restriction.Subjects() == 1 {
+		return nil, status.Errorf(
+			codes.FailedPrecondition,
+			"confluence-connector: %s is the last user or group in the %s restriction of %s %s, removing it would lift the restriction",
+			grant.Principal.Id.Resource,
+			operation,
+			b.resourceType.Id,
+			contentId,
+		)
+	}
+
+	ratelimitData, err = b.client.RemoveContentRestriction(
+		ctx,
+		contentId,
+		operation,
+		grant.Principal.Id.Resource,
+		grant.Principal.Id.ResourceType,
+	)
+	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+	if err != nil {
+		return outputAnnotations, fmt.Errorf("confluence-connector: failed to remove from content restriction: %w", err)
+	}
+	return outputAnnotations, nil
+}
+
+// restriction fetches the current restriction of an operation on a piece of
+// content, with every user and group it names.
+func (b *contentBuilder) restriction(
+	ctx context.Context,
+	contentId string,
+	operation string,
+) (*client.ConfluenceContentRestriction, *v2.RateLimitDescription, error) {
+	if !slices.Contains(contentOperations, operation) {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "confluence-connector: invalid content restriction entitlement: %s", operation)
+	}
+	restriction, ratelimitData, err := b.client.GetContentRestriction(ctx, contentId, operation)
+	if err != nil {
+		return nil, ratelimitData, fmt.Errorf("confluence-connector: failed to get content restrictions: %w", err)
+	}
+	return restriction, ratelimitData, nil
+}
+
+func newContentBuilder(c *client.ConfluenceClient, resourceType *v2.ResourceType) *contentBuilder {
+	return &contentBuilder{
+		client:       c,
+		resourceType: resourceType,
+	}
+}
+
+// contentResource builds the resource of a page or blog post. Read
+// restrictions are inherited by child pages, so content under a page with a
+// read restriction is flagged with the IDs of the restricted ancestors.
+func contentResource(
+	resourceType *v2.ResourceType,
+	content *client.ConfluenceContent,
+	spaceId *v2.ResourceId,
+	restrictedAncestorIds []interface{},
+) (*v2.Resource, error) {
+	return rs.NewResource(
+		content.Title,
+		resourceType,
+		content.Id,
+		rs.WithParentResourceID(spaceId),
+		rs.WithResourceProfile(map[string]interface{}{
+			"space_id":                  spaceId.Resource,
+			"status":                    content.Status,
+			"inherits_read_restriction": len(restrictedAncestorIds) > 0,
+			"restricted_ancestor_ids":   restrictedAncestorIds,
+		}),
+	)
+}
@@ -0,0 +1,188 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cfg "github.com/conductorone/baton-confluence/pkg/config"
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+	"github.com/conductorone/baton-confluence/test"
+)
+
+func TestContentRestrictions(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	spaceId := &v2.ResourceId{ResourceType: spaceResourceType.Id, Resource: "100"}
+	pages := newContentBuilder(confluenceClient, pageResourceType)
+	blogposts := newContentBuilder(confluenceClient, blogpostResourceType)
+
+	listContent := func(t *testing.T, b *contentBuilder) map[string]*v2.Resource {
+		resources, results, err := b.List(ctx, spaceId, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Empty(t, results.NextPageToken)
+		rv := make(map[string]*v2.Resource)
+		for _, r := range resources {
+			require.Equal(t, spaceId.Resource, r.ParentResourceId.Resource)
+			rv[r.DisplayName] = r
+		}
+		return rv
+	}
+
+	t.Run("should sync content under spaces when enabled", func(t *testing.T) {
+		c := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{
+			accessMode:              cfg.SpaceAccessModePermissions,
+			nouns:                   []string{resourceTypeSpaceID},
+			verbs:                   []string{"read"},
+			syncContentRestrictions: true,
+			concurrency:             1,
+		})
+		spaces, _, err := c.List(ctx, nil, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.NotEmpty(t, spaces)
+		annos := annotations.Annotations(spaces[0].Annotations)
+		require.True(t, annos.Contains(&v2.ChildResourceType{}))
+	})
+
+	t.Run("should only list restricted content", func(t *testing.T) {
+		listed := listContent(t, pages)
+		require.Len(t, listed, 2)
+		require.Contains(t, listed, "HR")
+		require.Contains(t, listed, "Salaries")
+
+		listed = listContent(t, blogposts)
+		require.Len(t, listed, 1)
+		require.Contains(t, listed, "Release notes")
+	})
+
+	t.Run("should flag restrictions inherited from ancestors", func(t *testing.T) {
+		listed := listContent(t, pages)
+
+		profile := resource.GetProfile(listed["HR"])
+		require.False(t, profile.Fields["inherits_read_restriction"].GetBoolValue())
+
+		profile = resource.GetProfile(listed["Salaries"])
+		require.True(t, profile.Fields["inherits_read_restriction"].GetBoolValue())
+		ancestorIds := profile.Fields["restricted_ancestor_ids"].GetListValue().AsSlice()
+		require.Equal(t, []interface{}{"501"}, ancestorIds)
+	})
+
+	t.Run("should grant the users and groups named in restrictions", func(t *testing.T) {
+		hr := listContent(t, pages)["HR"]
+
+		entitlements, _, err := pages.Entitlements(ctx, hr, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Len(t, entitlements, 2)
+
+		grants, _, err := pages.Grants(ctx, hr, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Len(t, grants, 2)
+
+		require.Equal(t, "page:501:read", grants[0].Entitlement.Id)
+		require.Equal(t, "group-admins", grants[0].Principal.Id.Resource)
+		annos := annotations.Annotations(grants[0].Annotations)
+		require.True(t, annos.Contains(&v2.GrantExpandable{}))
+
+		require.Equal(t, "page:501:update", grants[1].Entitlement.Id)
+		require.Equal(t, "alice", grants[1].Principal.Id.Resource)
+	})
+
+	t.Run("should page through the users and groups of a restriction", func(t *testing.T) {
+		for _, accountId := range []string{"alice", "carol", "admin", "automation"} {
+			site.Restrict("510", client.ContentOperationUpdate, "user", accountId)
+		}
+		releaseNotes := listContent(t, blogposts)["Release notes"]
+
+		grants, _, err := blogposts.Grants(ctx, releaseNotes, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		principals := make([]string, 0, len(grants))
+		for _, grant := range grants {
+			principals = append(principals, grant.Principal.Id.Resource)
+		}
+		require.Equal(t, []string{"bob", "alice", "carol", "admin", "automation"}, principals)
+	})
+}
+
+func TestContentRestrictionProvisioning(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	pages := newContentBuilder(confluenceClient, pageResourceType)
+	spaceId := &v2.ResourceId{ResourceType: spaceResourceType.Id, Resource: "100"}
+	hr, err := contentResource(pageResourceType, &client.ConfluenceContent{Id: "501", Title: "HR"}, spaceId, nil)
+	require.Nil(t, err)
+	handbook, err := contentResource(pageResourceType, &client.ConfluenceContent{Id: "500", Title: "Handbook"}, spaceId, nil)
+	require.Nil(t, err)
+	readHr := entitlement.NewPermissionEntitlement(hr, client.ContentOperationRead)
+
+	bob, err := userResource(ctx, &client.ConfluenceUser{AccountId: "bob", DisplayName: "Bob Doe"}, nil)
+	require.Nil(t, err)
+	engineering, err := groupResource(ctx, &client.ConfluenceGroup{Id: "group-engineering", Name: "engineering"})
+	require.Nil(t, err)
+
+	t.Run("should add users and groups to a restriction", func(t *testing.T) {
+		grants, _, err := pages.Grant(ctx, bob, readHr)
+		require.Nil(t, err)
+		require.Len(t, grants, 1)
+
+		_, _, err = pages.Grant(ctx, engineering, readHr)
+		require.Nil(t, err)
+
+		restriction := site.ContentRestriction("501", client.ContentOperationRead)
+		require.Contains(t, restriction.Users, "bob")
+		require.Contains(t, restriction.Groups, "group-engineering")
+	})
+
+	t.Run("should not add a principal twice", func(t *testing.T) {
+		grants, annos, err := pages.Grant(ctx, bob, readHr)
+		require.Nil(t, err)
+		require.Empty(t, grants)
+		require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	})
+
+	t.Run("should refuse to restrict unrestricted content", func(t *testing.T) {
+		_, _, err := pages.Grant(ctx, bob, entitlement.NewPermissionEntitlement(handbook, client.ContentOperationRead))
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		require.Empty(t, site.ContentRestriction("500", client.ContentOperationRead).Users)
+	})
+
+	t.Run("should remove users and groups from a restriction", func(t *testing.T) {
+		for _, principal := range []*v2.Resource{bob, engineering} {
+			annos, err := pages.Revoke(ctx, &v2.Grant{Entitlement: readHr, Principal: principal})
+			require.Nil(t, err)
+			require.False(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+		}
+
+		restriction := site.ContentRestriction("501", client.ContentOperationRead)
+		require.NotContains(t, restriction.Users, "bob")
+		require.Equal(t, []string{"group-admins"}, restriction.Groups)
+	})
+
+	t.Run("should refuse to remove the last user or group of a restriction", func(t *testing.T) {
+		admins, err := groupResource(ctx, &client.ConfluenceGroup{Id: "group-admins", Name: "admins"})
+		require.Nil(t, err)
+		_, err = pages.Revoke(ctx, &v2.Grant{Entitlement: readHr, Principal: admins})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		require.Equal(t, []string{"group-admins"}, site.ContentRestriction("501", client.ContentOperationRead).Groups)
+	})
+
+	t.Run("should not remove a principal twice", func(t *testing.T) {
+		annos, err := pages.Revoke(ctx, &v2.Grant{Entitlement: readHr, Principal: bob})
+		require.Nil(t, err)
+		require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+	})
+}
@@ -0,0 +1,485 @@
+package connector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	cfg "github.com/conductorone/baton-confluence/pkg/config"
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+)
+
+const (
+	auditEventFeedID = "confluence_audit_log"
+
+	auditObjectTypeGroup = "group"
+	auditObjectTypeSpace = "space"
+	auditObjectTypeUser  = "user"
+)
+
+// auditPermissionKeys maps the permission keys audit records name space
+// permissions by to the operation and target of the permission.
+var auditPermissionKeys = map[string]spacePermission{
+	"VIEWSPACE":           {"read", resourceTypeSpaceID},
+	"SETSPACEPERMISSIONS": {"administer", resourceTypeSpaceID},
+	"EXPORTSPACE":         {"export", resourceTypeSpaceID},
+	"SETPAGEPERMISSIONS":  {"restrict_content", resourceTypeSpaceID},
+	"EDITSPACE":           {"create", "page"},
+	"REMOVEPAGE":          {"delete", "page"},
+	"ARCHIVEPAGE":         {"archive", "page"},
+	"EDITBLOG":            {"create", "blogpost"},
+	"REMOVEBLOG":          {"delete", "blogpost"},
+	"COMMENT":             {"create", "comment"},
+	"REMOVECOMMENT":       {"delete", "comment"},
+	"CREATEATTACHMENT":    {"create", "attachment"},
+	"REMOVEATTACHMENT":    {"delete", "attachment"},
+}
+
+// auditCursor is the resumable position of the audit event feed. Audit
+// records are listed newest first, so a window [Since, Until] is fixed when
+// it is first read and paged through by offset. Once it is exhausted, the
+// next window starts right after the newest record seen.
+type auditCursor struct {
+	Since  int64  `json:"since,omitempty"`
+	Until  int64  `json:"until,omitempty"`
+	Start  string `json:"start,omitempty"`
+	Latest int64  `json:"latest,omitempty"`
+}
+
+type auditEventFeed struct {
+	client *client.ConfluenceClient
+	// spaceFilter drops the events of spaces that are not synced.
+	spaceFilter *spaceFilter
+	// syncPermissions, nouns and verbs tell which space permissions are
+	// synced as entitlements, and so can be granted or revoked by an event.
+	// Discovered entitlements differ from space to space, so with
+	// discoverEntitlements no event grants or revokes space permissions.
+	syncPermissions      bool
+	discoverEntitlements bool
+	nouns                mapset.Set[string]
+	verbs                mapset.Set[string]
+	now                  func() time.Time
+}
+
+func (f *auditEventFeed) EventFeedMetadata(_ context.Context) *v2.EventFeedMetadata {
+	return v2.EventFeedMetadata_builder{
+		Id: auditEventFeedID,
+		SupportedEventTypes: []v2.EventType{
+			v2.EventType_EVENT_TYPE_RESOURCE_CHANGE,
+			v2.EventType_EVENT_TYPE_CREATE_GRANT,
+			v2.EventType_EVENT_TYPE_CREATE_REVOKE,
+		},
+	}.Build()
+}
+
+// ListEvents turns audit records into events. Users added to or removed from
+// a group, and space permissions given to or taken from a user or group,
+// become grant and revoke events. Other records become resource change events
+// for the groups and spaces they affect, so that their members and
+// permissions are synced again. Audit records only name objects, so each name
+// is looked up; objects that no longer exist are skipped.
+func (f *auditEventFeed) ListEvents(
+	ctx context.Context,
+	earliestEvent *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) ([]*v2.Event, *pagination.StreamState, annotations.Annotations, error) {
+	cursor, err := parseAuditCursor(pToken.Cursor)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if cursor.Until == 0 {
+		cursor.Until = f.now().UnixMilli()
+		if cursor.Since == 0 && earliestEvent != nil {
+			cursor.Since = earliestEvent.AsTime().UnixMilli()
+		}
+	}
+
+	records, nextToken, ratelimitData, err := f.client.AuditRecords(cursor.Since, cursor.Until).
+		WithPageSize(pToken.Size).
+		Page(ctx, cursor.Start)
+	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+	if auditLogUnreadable(err) {
+		// The credentials may be given access later, so the feed stays
+		// registered and tries again on the next call, from the same cursor.
+		ctxzap.Extract(ctx).Warn(
+			"confluence-connector: cannot read the audit log, no events are reported",
+			zap.Error(err),
+		)
+		return nil, &pagination.StreamState{Cursor: pToken.Cursor}, outputAnnotations, nil
+	}
+	if err != nil {
+		return nil, nil, outputAnnotations, fmt.Errorf("confluence-connector: failed to list audit records: %w", err)
+	}
+
+	resolver := newAuditObjectResolver(f.client, f.spaceFilter)
+	events := make([]*v2.Event, 0, len(records))
+	for _, record := range records {
+		cursor.Latest = max(cursor.Latest, record.CreationDate)
+
+		event, err := f.grantEvent(ctx, resolver, record)
+		if err != nil {
+			return nil, nil, outputAnnotations, err
+		}
+		if event != nil {
+			events = append(events, event)
+			continue
+		}
+
+		for _, object := range auditRecordObjects(record) {
+			resourceId, err := resolver.resolve(ctx, object)
+			if err != nil {
+				return nil, nil, outputAnnotations, err
+			}
+			if resourceId == nil {
+				continue
+			}
+			events = append(events, v2.Event_builder{
+				Id:         auditEventId(record, object),
+				OccurredAt: timestamppb.New(time.UnixMilli(record.CreationDate)),
+				ResourceChangeEvent: v2.ResourceChangeEvent_builder{
+					ResourceId: resourceId,
+				}.Build(),
+			}.Build())
+		}
+	}
+
+	hasMore := nextToken != ""
+	if hasMore {
+		cursor.Start = nextToken
+	} else {
+		since := cursor.Since
+		if cursor.Latest > 0 {
+			since = cursor.Latest + 1
+		}
+		cursor = auditCursor{Since: since}
+	}
+
+	nextCursor, err := json.Marshal(cursor)
+	if err != nil {
+		return nil, nil, outputAnnotations, err
+	}
+
+	return events, &pagination.StreamState{Cursor: string(nextCursor), HasMore: hasMore}, outputAnnotations, nil
+}
+
+// grantEvent returns the grant or revoke event of a record that adds a user to
+// a group, removes one from it, or changes a synced space permission of a
+// user or group. Records are classified from their objects and changed
+// values, not from their summary, which is free text in the site's language.
+// It returns nil for other records, for records whose objects cannot all be
+// resolved and for those whose changed values do not tell what was given or
+// taken away; they are reported as resource changes instead.
+func (f *auditEventFeed) grantEvent(
+	ctx context.Context,
+	resolver *auditObjectResolver,
+	record client.AuditRecord,
+) (*v2.Event, error) {
+	objects := make(map[string]client.AuditObject)
+	for _, object := range append([]client.AuditObject{record.AffectedObject}, record.AssociatedObjects...) {
+		objectType := strings.ToLower(object.ObjectType)
+		if _, ok := objects[objectType]; !ok && object.Name != "" {
+			objects[objectType] = object
+		}
+	}
+	group, hasGroup := objects[auditObjectTypeGroup]
+	space, hasSpace := objects[auditObjectTypeSpace]
+	user, hasUser := objects[auditObjectTypeUser]
+
+	var target client.AuditObject
+	var ent func(res *v2.Resource) *v2.Entitlement
+	var grant bool
+	switch {
+	case hasSpace && (hasUser || hasGroup):
+		// With discovery, which permissions are entitlements depends on the
+		// space, so the space is synced again instead.
+		if !f.syncPermissions || f.discoverEntitlements {
+			return nil, nil
+		}
+		permission, granted, ok := auditSpacePermission(record)
+		if !ok {
+			logUnclassifiedAuditRecord(ctx, record)
+			return nil, nil
+		}
+		if !checkSpacePermission(f.nouns, f.verbs, permission.operation, permission.target) {
+			return nil, nil
+		}
+		target, grant = space, granted
+		ent = func(res *v2.Resource) *v2.Entitlement {
+			return spacePermissionEntitlement(res, permission.operation, permission.target)
+		}
+	case hasGroup && hasUser:
+		granted, ok := auditMembershipChange(record)
+		if !ok {
+			logUnclassifiedAuditRecord(ctx, record)
+			return nil, nil
+		}
+		target, grant = group, granted
+		ent = func(res *v2.Resource) *v2.Entitlement {
+			return entitlement.NewAssignmentEntitlement(res, groupMemberEntitlement)
+		}
+	default:
+		return nil, nil
+	}
+
+	targetId, err := resolver.resolve(ctx, target)
+	if err != nil || targetId == nil {
+		return nil, err
+	}
+
+	var principalId *v2.ResourceId
+	if hasUser {
+		principalId, err = resolver.resolve(ctx, user)
+	} else {
+		principalId, err = resolver.resolve(ctx, group)
+	}
+	if err != nil || principalId == nil {
+		return nil, err
+	}
+
+	targetResource := &v2.Resource{Id: targetId, DisplayName: target.Name}
+	principal := &v2.Resource{Id: principalId}
+	event := v2.Event_builder{
+		Id:         auditEventId(record, target),
+		OccurredAt: timestamppb.New(time.UnixMilli(record.CreationDate)),
+	}
+	if grant {
+		event.CreateGrantEvent = v2.CreateGrantEvent_builder{
+			Entitlement: ent(targetResource),
+			Principal:   principal,
+		}.Build()
+	} else {
+		event.CreateRevokeEvent = v2.CreateRevokeEvent_builder{
+			Entitlement: ent(targetResource),
+			Principal:   principal,
+		}.Build()
+	}
+	return event.Build(), nil
+}
+
+// auditSpacePermission reads the space permission a record gives or takes
+// away from its changed values, which name it by its permission key: as the
+// new value when it is given, and as the old value when it is taken away.
+func auditSpacePermission(record client.AuditRecord) (spacePermission, bool, bool) {
+	for _, value := range record.ChangedValues {
+		oldKey := strings.ToUpper(strings.TrimSpace(value.OldValue))
+		newKey := strings.ToUpper(strings.TrimSpace(value.NewValue))
+		switch {
+		case oldKey == "" && newKey != "":
+			if permission, ok := auditPermissionKeys[newKey]; ok {
+				return permission, true, true
+			}
+		case oldKey != "" && newKey == "":
+			if permission, ok := auditPermissionKeys[oldKey]; ok {
+				return permission, false, true
+			}
+		}
+	}
+	return spacePermission{}, false, false
+}
+
+// auditMembershipChange tells whether a record adds a user to a group or
+// removes one from it, from its changed values: values that are only set
+// afterwards are additions and values that are only set before are
+// removals. It returns false when there are none, or both.
+func auditMembershipChange(record client.AuditRecord) (bool, bool) {
+	added, removed := false, false
+	for _, value := range record.ChangedValues {
+		oldValue := strings.TrimSpace(value.OldValue)
+		newValue := strings.TrimSpace(value.NewValue)
+		switch {
+		case oldValue == "" && newValue != "":
+			added = true
+		case oldValue != "" && newValue == "":
+			removed = true
+		}
+	}
+	return added, added != removed
+}
+
+func logUnclassifiedAuditRecord(ctx context.Context, record client.AuditRecord) {
+	ctxzap.Extract(ctx).Debug(
+		"confluence-connector: audit record does not tell what was granted or revoked, reporting a resource change",
+		zap.String("category", record.Category),
+		zap.String("summary", record.Summary),
+		zap.Int64("creation_date", record.CreationDate),
+	)
+}
+
+func parseAuditCursor(cursor string) (auditCursor, error) {
+	var parsed auditCursor
+	if cursor == "" {
+		return parsed, nil
+	}
+	if err := json.Unmarshal([]byte(cursor), &parsed); err != nil {
+		return parsed, status.Errorf(codes.InvalidArgument, "confluence-connector: invalid audit log cursor: %s", err)
+	}
+	return parsed, nil
+}
+
+// auditRecordObjects returns the groups and spaces a record affects, once
+// each.
+func auditRecordObjects(record client.AuditRecord) []client.AuditObject {
+	objects := make([]client.AuditObject, 0, len(record.AssociatedObjects)+1)
+	seen := make(map[client.AuditObject]bool)
+	for _, object := range append([]client.AuditObject{record.AffectedObject}, record.AssociatedObjects...) {
+		switch strings.ToLower(object.ObjectType) {
+		case auditObjectTypeGroup, auditObjectTypeSpace:
+		default:
+			continue
+		}
+		if object.Name == "" || seen[object] {
+			continue
+		}
+		seen[object] = true
+		objects = append(objects, object)
+	}
+	return objects
+}
+
+// auditEventId derives a stable ID, as audit records have none.
+func auditEventId(record client.AuditRecord, object client.AuditObject) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		fmt.Sprint(record.CreationDate),
+		record.Author.AccountId,
+		record.Summary,
+		object.ObjectType,
+		object.Name,
+	}, "\x00")))
+	return hex.EncodeToString(hash[:16])
+}
+
+// auditObjectResolver looks up the resource ID of audited objects by name,
+// remembering the answers for the page being converted.
+type auditObjectResolver struct {
+	client      *client.ConfluenceClient
+	spaceFilter *spaceFilter
+	cache       map[client.AuditObject]*v2.ResourceId
+}
+
+func newAuditObjectResolver(c *client.ConfluenceClient, filter *spaceFilter) *auditObjectResolver {
+	return &auditObjectResolver{
+		client:      c,
+		spaceFilter: filter,
+		cache:       make(map[client.AuditObject]*v2.ResourceId),
+	}
+}
+
+// resolve returns the resource ID of an object, or nil if it no longer
+// exists or is a space that is not synced.
+func (r *auditObjectResolver) resolve(ctx context.Context, object client.AuditObject) (*v2.ResourceId, error) {
+	if resourceId, ok := r.cache[object]; ok {
+		return resourceId, nil
+	}
+
+	var resourceId *v2.ResourceId
+	switch strings.ToLower(object.ObjectType) {
+	case auditObjectTypeGroup:
+		group, _, err := r.client.GetGroupByName(ctx, object.Name)
+		if err != nil && !isNotFound(err) {
+			return nil, fmt.Errorf("confluence-connector: failed to look up group %q: %w", object.Name, err)
+		}
+		if group != nil && group.Id != "" {
+			resourceId = &v2.ResourceId{ResourceType: resourceTypeGroup.Id, Resource: group.Id}
+		}
+	case auditObjectTypeUser:
+		accountId, _, err := r.client.FindUserByName(ctx, object.Name)
+		if err != nil {
+			return nil, fmt.Errorf("confluence-connector: failed to look up user %q: %w", object.Name, err)
+		}
+		if accountId != "" {
+			resourceId = &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: accountId}
+		}
+	case auditObjectTypeSpace:
+		spaceId, _, err := r.client.FindSpaceByName(ctx, object.Name)
+		if err != nil {
+			return nil, fmt.Errorf("confluence-connector: failed to look up space %q: %w", object.Name, err)
+		}
+		included, err := r.includesSpace(ctx, spaceId)
+		if err != nil {
+			return nil, err
+		}
+		if included {
+			resourceId = &v2.ResourceId{ResourceType: spaceResourceType.Id, Resource: spaceId}
+		}
+	}
+
+	if resourceId == nil {
+		ctxzap.Extract(ctx).Debug(
+			"confluence-connector: audited object not found, skipping",
+			zap.String("object_type", object.ObjectType),
+			zap.String("name", object.Name),
+		)
+	}
+	r.cache[object] = resourceId
+	return resourceId, nil
+}
+
+// includesSpace reports whether a space exists and passes the space filter.
+func (r *auditObjectResolver) includesSpace(ctx context.Context, spaceId string) (bool, error) {
+	if spaceId == "" {
+		return false, nil
+	}
+	if !r.spaceFilter.active() {
+		return true, nil
+	}
+
+	space, _, err := r.client.GetSpaceById(ctx, spaceId)
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("confluence-connector: failed to get space %s: %w", spaceId, err)
+	}
+	included, _, err := r.spaceFilter.includes(ctx, r.client, space, false)
+	return included, err
+}
+
+// auditLogUnreadable reports whether the audit log cannot be read with the
+// connector's credentials: Cloud only lets Confluence admins read it.
+func auditLogUnreadable(err error) bool {
+	var reqErr *client.RequestError
+	if !errors.As(err, &reqErr) {
+		return false
+	}
+	switch reqErr.Status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return true
+	}
+	return false
+}
+
+func isNotFound(err error) bool {
+	var reqErr *client.RequestError
+	return errors.As(err, &reqErr) && reqErr.Status == http.StatusNotFound
+}
+
+// newAuditEventFeed returns the audit log feed of the spaces the space
+// builder syncs with the same options.
+func newAuditEventFeed(c *client.ConfluenceClient, filter *spaceFilter, opts spaceBuilderOptions) *auditEventFeed {
+	return &auditEventFeed{
+		client:               c,
+		spaceFilter:          filter,
+		syncPermissions:      opts.accessMode != cfg.SpaceAccessModeRbac,
+		discoverEntitlements: opts.discoverEntitlements,
+		nouns:                mapset.NewSet(opts.nouns...),
+		verbs:                mapset.NewSet(opts.verbs...),
+		now:                  time.Now,
+	}
+}
@@ -0,0 +1,291 @@
+package connector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	cfg "github.com/conductorone/baton-confluence/pkg/config"
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+	"github.com/conductorone/baton-confluence/test"
+)
+
+func changedResources(events []*v2.Event) []string {
+	resources := make([]string, 0, len(events))
+	for _, event := range events {
+		resourceId := event.GetResourceChangeEvent().GetResourceId()
+		resources = append(resources, resourceId.GetResourceType()+":"+resourceId.GetResource())
+	}
+	return resources
+}
+
+// grantEvents describes the grant and revoke events as "grant" or "revoke",
+// the entitlement and the principal.
+func grantEvents(events []*v2.Event) []string {
+	descriptions := make([]string, 0, len(events))
+	for _, event := range events {
+		switch {
+		case event.GetCreateGrantEvent() != nil:
+			grant := event.GetCreateGrantEvent()
+			descriptions = append(descriptions, "grant "+grant.GetEntitlement().GetId()+" "+grant.GetPrincipal().GetId().GetResource())
+		case event.GetCreateRevokeEvent() != nil:
+			revoke := event.GetCreateRevokeEvent()
+			descriptions = append(descriptions, "revoke "+revoke.GetEntitlement().GetId()+" "+revoke.GetPrincipal().GetId().GetResource())
+		}
+	}
+	return descriptions
+}
+
+func TestAuditEventFeed(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+
+	site.AddAuditRecord(client.AuditRecord{
+		CreationDate:      1000,
+		Summary:           "User added to group",
+		Category:          "Users and groups",
+		AffectedObject:    client.AuditObject{Name: "alice", ObjectType: "User"},
+		ChangedValues:     []client.AuditChangedValue{{Name: "Group", NewValue: "engineering"}},
+		AssociatedObjects: []client.AuditObject{{Name: "engineering", ObjectType: "Group"}},
+	})
+	site.AddAuditRecord(client.AuditRecord{
+		CreationDate:   2000,
+		Summary:        "Space permission added",
+		Category:       "Permissions",
+		AffectedObject: client.AuditObject{Name: "Engineering", ObjectType: "Space"},
+		AssociatedObjects: []client.AuditObject{
+			{Name: "confluence-users", ObjectType: "Group"},
+			{Name: "Engineering", ObjectType: "Space"},
+		},
+	})
+	site.AddAuditRecord(client.AuditRecord{
+		CreationDate:   3000,
+		Summary:        "Group removed",
+		AffectedObject: client.AuditObject{Name: "deleted-group", ObjectType: "Group"},
+	})
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	feed := newAuditEventFeed(confluenceClient, nil, spaceBuilderOptions{accessMode: cfg.SpaceAccessModePermissions, nouns: cfg.DefaultNouns, verbs: cfg.DefaultVerbs})
+	feed.now = func() time.Time { return time.UnixMilli(3500) }
+
+	t.Run("should page through the audit log and resume after it", func(t *testing.T) {
+		events, state, _, err := feed.ListEvents(ctx, nil, &pagination.StreamToken{Size: 2})
+		require.Nil(t, err)
+		require.True(t, state.HasMore)
+		// Newest first: the deleted group is skipped.
+		require.Equal(t, []string{"space:100", "group:group-users"}, changedResources(events))
+
+		events, state, _, err = feed.ListEvents(ctx, nil, &pagination.StreamToken{Size: 2, Cursor: state.Cursor})
+		require.Nil(t, err)
+		require.False(t, state.HasMore)
+		require.Equal(t, []string{"grant group:group-engineering:member alice"}, grantEvents(events))
+		require.Equal(t, int64(1000), events[0].GetOccurredAt().AsTime().UnixMilli())
+
+		events, state, _, err = feed.ListEvents(ctx, nil, &pagination.StreamToken{Size: 2, Cursor: state.Cursor})
+		require.Nil(t, err)
+		require.False(t, state.HasMore)
+		require.Empty(t, events)
+
+		site.AddAuditRecord(client.AuditRecord{
+			CreationDate:   4000,
+			Summary:        "User removed from group",
+			AffectedObject: client.AuditObject{Name: "confluence-admins", ObjectType: "Group"},
+		})
+		feed.now = func() time.Time { return time.UnixMilli(4500) }
+
+		events, _, _, err = feed.ListEvents(ctx, nil, &pagination.StreamToken{Size: 2, Cursor: state.Cursor})
+		require.Nil(t, err)
+		require.Equal(t, []string{"group:group-admins"}, changedResources(events))
+	})
+
+	t.Run("should start from the earliest event requested", func(t *testing.T) {
+		events, _, _, err := feed.ListEvents(ctx, timestamppb.New(time.UnixMilli(2500)), &pagination.StreamToken{Size: 10})
+		require.Nil(t, err)
+		require.Equal(t, []string{"group:group-admins"}, changedResources(events))
+	})
+
+	t.Run("should give distinct events stable IDs", func(t *testing.T) {
+		first, _, _, err := feed.ListEvents(ctx, nil, &pagination.StreamToken{Size: 10})
+		require.Nil(t, err)
+		second, _, _, err := feed.ListEvents(ctx, nil, &pagination.StreamToken{Size: 10})
+		require.Nil(t, err)
+		require.Len(t, first, 4)
+		ids := make(map[string]bool)
+		for i := range first {
+			require.Equal(t, first[i].GetId(), second[i].GetId())
+			ids[first[i].GetId()] = true
+		}
+		require.Len(t, ids, 4)
+	})
+}
+
+func TestAuditGrantEvents(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+
+	// Records as Confluence Cloud returns them. Only their objects and
+	// changed values are read, as their summary is in the site's language.
+	author := client.AuditRecord{}.Author
+	author.AccountId = "admin"
+	author.DisplayName = "Site Admin"
+	site.AddAuditRecord(client.AuditRecord{
+		Author:            author,
+		RemoteAddress:     "203.0.113.7",
+		CreationDate:      1000,
+		Summary:           "User removed from group",
+		Category:          "Users and groups",
+		AffectedObject:    client.AuditObject{Name: "Bob Doe", ObjectType: "User"},
+		ChangedValues:     []client.AuditChangedValue{{Name: "Group", OldValue: "confluence-users"}},
+		AssociatedObjects: []client.AuditObject{{Name: "confluence-users", ObjectType: "Group"}},
+	})
+	site.AddAuditRecord(client.AuditRecord{
+		Author:         author,
+		RemoteAddress:  "203.0.113.7",
+		CreationDate:   2000,
+		Summary:        "Autorisation d'espace ajoutée",
+		Category:       "Autorisations",
+		AffectedObject: client.AuditObject{Name: "Engineering", ObjectType: "Space"},
+		ChangedValues: []client.AuditChangedValue{
+			{Name: "Type", NewValue: "EDITSPACE"},
+			{Name: "Group", NewValue: "engineering"},
+		},
+		AssociatedObjects: []client.AuditObject{{Name: "engineering", ObjectType: "Group"}},
+	})
+	site.AddAuditRecord(client.AuditRecord{
+		Author:         author,
+		RemoteAddress:  "203.0.113.7",
+		CreationDate:   3000,
+		Summary:        "Space permission removed",
+		Category:       "Permissions",
+		AffectedObject: client.AuditObject{Name: "Engineering", ObjectType: "Space"},
+		ChangedValues: []client.AuditChangedValue{
+			{Name: "Type", OldValue: "VIEWSPACE"},
+			{Name: "User", OldValue: "carol"},
+		},
+		AssociatedObjects: []client.AuditObject{{Name: "carol", ObjectType: "User"}},
+	})
+	site.AddAuditRecord(client.AuditRecord{
+		Author:            author,
+		CreationDate:      4000,
+		Summary:           "User added to group",
+		Category:          "Users and groups",
+		AffectedObject:    client.AuditObject{Name: "Nobody", ObjectType: "User"},
+		ChangedValues:     []client.AuditChangedValue{{Name: "Group", NewValue: "engineering"}},
+		AssociatedObjects: []client.AuditObject{{Name: "engineering", ObjectType: "Group"}},
+	})
+	site.AddAuditRecord(client.AuditRecord{
+		Author:            author,
+		CreationDate:      5000,
+		Summary:           "User added to group",
+		Category:          "Users and groups",
+		AffectedObject:    client.AuditObject{Name: "alice", ObjectType: "User"},
+		ChangedValues:     []client.AuditChangedValue{},
+		AssociatedObjects: []client.AuditObject{{Name: "confluence-admins", ObjectType: "Group"}},
+	})
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+	options := spaceBuilderOptions{accessMode: cfg.SpaceAccessModePermissions, nouns: cfg.DefaultNouns, verbs: cfg.DefaultVerbs}
+
+	t.Run("should grant and revoke memberships and synced space permissions", func(t *testing.T) {
+		feed := newAuditEventFeed(confluenceClient, nil, options)
+		feed.now = func() time.Time { return time.UnixMilli(4500) }
+
+		events, _, _, err := feed.ListEvents(ctx, nil, &pagination.StreamToken{Size: 10})
+		require.Nil(t, err)
+		require.Equal(t, []string{
+			"revoke space:100:read-space carol",
+			"grant space:100:create-page group-engineering",
+			"revoke group:group-users:member bob",
+		}, grantEvents(events))
+		// The user of the newest record cannot be resolved.
+		require.Equal(t, []string{"group:group-engineering"}, changedResources(events[:1]))
+	})
+
+	t.Run("should report the group when a record does not tell whether a member was added or removed", func(t *testing.T) {
+		feed := newAuditEventFeed(confluenceClient, nil, options)
+		feed.now = func() time.Time { return time.UnixMilli(5500) }
+
+		events, _, _, err := feed.ListEvents(ctx, timestamppb.New(time.UnixMilli(5000)), &pagination.StreamToken{Size: 10})
+		require.Nil(t, err)
+		require.Empty(t, grantEvents(events))
+		require.Equal(t, []string{"group:group-admins"}, changedResources(events))
+	})
+
+	t.Run("should report space changes when permissions are not synced", func(t *testing.T) {
+		feed := newAuditEventFeed(confluenceClient, nil, spaceBuilderOptions{accessMode: cfg.SpaceAccessModeRbac})
+		feed.now = func() time.Time { return time.UnixMilli(3500) }
+
+		events, _, _, err := feed.ListEvents(ctx, timestamppb.New(time.UnixMilli(2000)), &pagination.StreamToken{Size: 10})
+		require.Nil(t, err)
+		require.Empty(t, grantEvents(events))
+		require.ElementsMatch(t, []string{"space:100", "space:100", "group:group-engineering"}, changedResources(events))
+	})
+
+	t.Run("should report space changes when space entitlements are discovered", func(t *testing.T) {
+		discovered := options
+		discovered.discoverEntitlements = true
+		feed := newAuditEventFeed(confluenceClient, nil, discovered)
+		feed.now = func() time.Time { return time.UnixMilli(3500) }
+
+		events, _, _, err := feed.ListEvents(ctx, timestamppb.New(time.UnixMilli(2000)), &pagination.StreamToken{Size: 10})
+		require.Nil(t, err)
+		require.Empty(t, grantEvents(events))
+		require.ElementsMatch(t, []string{"space:100", "space:100", "group:group-engineering"}, changedResources(events))
+	})
+
+	t.Run("should not grant space permissions outside the configured nouns and verbs", func(t *testing.T) {
+		feed := newAuditEventFeed(confluenceClient, nil, spaceBuilderOptions{
+			accessMode: cfg.SpaceAccessModePermissions,
+			nouns:      []string{resourceTypeSpaceID},
+			verbs:      []string{"read"},
+		})
+		feed.now = func() time.Time { return time.UnixMilli(3500) }
+
+		events, _, _, err := feed.ListEvents(ctx, timestamppb.New(time.UnixMilli(2000)), &pagination.StreamToken{Size: 10})
+		require.Nil(t, err)
+		require.Equal(t, []string{"revoke space:100:read-space carol"}, grantEvents(events))
+	})
+}
+
+func TestAuditEventFeedRegistration(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should register the feed on Cloud", func(t *testing.T) {
+		_, server := test.FakeServer(t)
+		c, err := New(ctx, Config{Domain: server.URL, UserName: "admin", ApiKey: "API Key"})
+		require.Nil(t, err)
+		require.Len(t, c.EventFeeds(ctx), 1)
+	})
+
+	t.Run("should report no events while the audit log cannot be read", func(t *testing.T) {
+		site, server := test.FakeServer(t)
+		site.SetCurrentUser("alice")
+		c, err := New(ctx, Config{Domain: server.URL, UserName: "alice", ApiKey: "API Key"})
+		require.Nil(t, err)
+		feeds := c.EventFeeds(ctx)
+		require.Len(t, feeds, 1)
+
+		events, state, _, err := feeds[0].ListEvents(ctx, nil, &pagination.StreamToken{Size: 10})
+		require.Nil(t, err)
+		require.Empty(t, events)
+		require.False(t, state.HasMore)
+	})
+
+	t.Run("should fail on other errors", func(t *testing.T) {
+		site, server := test.FakeServer(t)
+		c, err := New(ctx, Config{Domain: server.URL, UserName: "admin", ApiKey: "API Key"})
+		require.Nil(t, err)
+
+		site.RateLimitNext(1, time.Second)
+		_, _, _, err = c.EventFeeds(ctx)[0].ListEvents(ctx, nil, &pagination.StreamToken{Size: 10})
+		require.NotNil(t, err)
+	})
+}
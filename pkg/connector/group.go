@@ -3,23 +3,37 @@ package connector
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	cfg "github.com/conductorone/baton-confluence/pkg/config"
 	"github.com/conductorone/baton-confluence/pkg/connector/client"
+	config "github.com/conductorone/baton-sdk/pb/c1/config/v1"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const (
 	groupMemberEntitlement = "member"
+
+	deleteGroupAction = "delete_group"
+	forceArgument     = "force"
 )
 
 type groupResourceType struct {
 	resourceType *v2.ResourceType
 	client       *client.ConfluenceClient
+	members      *prefetcher[client.ConfluenceUser]
+	// spaceAccessMode is the space access mode the connector resolved, which
+	// tells whether deleting a group checks the space roles it holds.
+	spaceAccessMode string
 }
 
 func (o *groupResourceType) ResourceType(_ context.Context) *v2.ResourceType {
@@ -58,11 +72,15 @@ func (o *groupResourceType) List(
 		return nil, nil, err
 	}
 	if bag.Current() == nil {
+		// A new sync: drop the pages prefetched for the last one.
+		o.members.reset()
 		bag.Push(pagination.PageState{
 			ResourceTypeID: resourceTypeGroup.Id,
 		})
 	}
-	groups, token, ratelimitData, err := o.client.GetGroups(ctx, bag.PageToken(), ResourcesPageSize)
+	groups, token, ratelimitData, err := o.client.Groups().
+		WithPageSize(ResourcesPageSize).
+		Page(ctx, bag.PageToken())
 	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
 	if err != nil {
 		return nil, syncResults("", outputAnnotations), err
@@ -78,6 +96,7 @@ func (o *groupResourceType) List(
 		}
 
 		rv = append(rv, gr)
+		o.members.expect(g.Id)
 	}
 
 	nextPage, err := bag.NextToken(token)
@@ -126,12 +145,7 @@ func (o *groupResourceType) Grants(
 		})
 	}
 
-	users, token, ratelimitData, err := o.client.GetGroupMembers(
-		ctx,
-		bag.PageToken(),
-		ResourcesPageSize,
-		res.Id.Resource,
-	)
+	users, token, ratelimitData, err := o.members.page(ctx, res.Id.Resource, bag.PageToken(), ResourcesPageSize)
 	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
 	if err != nil {
 		return nil, syncResults("", outputAnnotations), err
@@ -191,9 +205,159 @@ func (o *groupResourceType) Revoke(
 	return outputAnnotations, err
 }
 
-func groupBuilder(client *client.ConfluenceClient) *groupResourceType {
+// Create creates a group named after the resource.
+func (o *groupResourceType) Create(ctx context.Context, res *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	name := strings.TrimSpace(res.GetDisplayName())
+	if name == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "confluence-connector: group name is required")
+	}
+
+	group, ratelimitData, err := o.client.CreateGroup(ctx, name)
+	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+	if err != nil {
+		return nil, outputAnnotations, fmt.Errorf("confluence-connector: failed to create group: %w", err)
+	}
+
+	created, err := groupResource(ctx, group)
+	if err != nil {
+		return nil, outputAnnotations, err
+	}
+	return created, outputAnnotations, nil
+}
+
+// Delete deletes a group, unless spaces still grant it permissions. The
+// delete_group action can force the deletion.
+func (o *groupResourceType) Delete(ctx context.Context, resourceId *v2.ResourceId, _ *v2.ResourceId) (annotations.Annotations, error) {
+	return o.deleteGroup(ctx, resourceId.GetResource(), false)
+}
+
+// ResourceActions registers the action that deletes a group, optionally even
+// if spaces still grant it permissions.
+func (o *groupResourceType) ResourceActions(ctx context.Context, registry actions.ActionRegistry) error {
+	return registry.Register(ctx, &v2.BatonActionSchema{
+		Name:        deleteGroupAction,
+		DisplayName: "Delete group",
+		Description: "Delete a group, refusing groups that spaces still grant permissions to unless forced. " +
+			"The check reads the permissions of every space until one grants the group access, " +
+			"a request or more per space, so it is slow on large sites",
+		Arguments: []*config.Field{
+			config.Field_builder{
+				Name:            "resource_id",
+				DisplayName:     "Group",
+				IsRequired:      true,
+				ResourceIdField: &config.ResourceIdField{},
+			}.Build(),
+			config.Field_builder{
+				Name:        forceArgument,
+				DisplayName: "Force",
+				Description: "Delete the group even if spaces still grant it permissions, without checking them. Confluence removes those permissions",
+				BoolField:   &config.BoolField{},
+			}.Build(),
+		},
+	}, o.deleteGroupHandler)
+}
+
+func (o *groupResourceType) deleteGroupHandler(
+	ctx context.Context,
+	args *structpb.Struct,
+) (*structpb.Struct, annotations.Annotations, error) {
+	resourceId, err := actions.RequireResourceIDArg(args, "resource_id")
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	force, _ := actions.GetBoolArg(args, forceArgument)
+
+	outputAnnotations, err := o.deleteGroup(ctx, resourceId.GetResource(), force)
+	if err != nil {
+		return nil, outputAnnotations, err
+	}
+	return actions.NewReturnValues(true), outputAnnotations, nil
+}
+
+func (o *groupResourceType) deleteGroup(ctx context.Context, groupId string, force bool) (annotations.Annotations, error) {
+	if !force {
+		spaceKey, ratelimitData, err := o.spaceGrantingGroup(ctx, groupId)
+		if err != nil {
+			return WithRateLimitAnnotations(ratelimitData), fmt.Errorf("confluence-connector: failed to check the space access of group %s: %w", groupId, err)
+		}
+		if spaceKey != "" {
+			return WithRateLimitAnnotations(ratelimitData), status.Errorf(
+				codes.FailedPrecondition,
+				"confluence-connector: group %s still has access to space %s; remove it first or use the %s action with %s set",
+				groupId,
+				spaceKey,
+				deleteGroupAction,
+				forceArgument,
+			)
+		}
+	}
+
+	ratelimitData, err := o.client.DeleteGroup(ctx, groupId)
+	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+	if err != nil {
+		if isNotFound(err) {
+			// Already deleted.
+			return outputAnnotations, nil
+		}
+		return outputAnnotations, fmt.Errorf("confluence-connector: failed to delete group: %w", err)
+	}
+	return outputAnnotations, nil
+}
+
+// spaceGrantingGroup returns the key of the first space that grants the group
+// a permission or a space role, or "" when there is none. Space roles are
+// only checked when the connector syncs them. Spaces are checked one by one
+// until one grants the group access, which takes a request per space and
+// page of its permissions, so this is slow on large sites.
+func (o *groupResourceType) spaceGrantingGroup(ctx context.Context, groupId string) (string, *v2.RateLimitDescription, error) {
+	checkRoles := o.spaceAccessMode != cfg.SpaceAccessModePermissions
+
+	principalType, err := confluencePrincipalType(resourceTypeGroupID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	spaces := o.client.Spaces()
+	for space, err := range spaces.All(ctx) {
+		if err != nil {
+			return "", spaces.RateLimit(), err
+		}
+
+		permissions := o.client.SpacePermissions(space.Id)
+		for permission, err := range permissions.All(ctx) {
+			if err != nil {
+				return "", permissions.RateLimit(), err
+			}
+			if permission.Principal.Type == resourceTypeGroupID && permission.Principal.Id == groupId {
+				return space.Key, permissions.RateLimit(), nil
+			}
+		}
+
+		if checkRoles {
+			assignments, _, ratelimitData, err := o.client.SpaceRoleAssignments(space.Id, "", groupId, principalType).
+				WithPageSize(1).
+				Page(ctx, "")
+			if err != nil {
+				return "", ratelimitData, err
+			}
+			if len(assignments) > 0 {
+				return space.Key, ratelimitData, nil
+			}
+		}
+	}
+	return "", spaces.RateLimit(), nil
+}
+
+func groupBuilder(c *client.ConfluenceClient, concurrency int, spaceAccessMode string) *groupResourceType {
 	return &groupResourceType{
-		resourceType: resourceTypeGroup,
-		client:       client,
+		resourceType:    resourceTypeGroup,
+		client:          c,
+		spaceAccessMode: spaceAccessMode,
+		members: newPrefetcher(
+			concurrency,
+			func(ctx context.Context, groupId string, pageToken string, pageSize int) ([]client.ConfluenceUser, string, *v2.RateLimitDescription, error) {
+				return c.GroupMembers(groupId).WithPageSize(pageSize).Page(ctx, pageToken)
+			},
+		),
 	}
 }
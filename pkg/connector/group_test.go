@@ -3,13 +3,19 @@ package connector
 import (
 	"context"
 	"testing"
+	"time"
 
+	cfg "github.com/conductorone/baton-confluence/pkg/config"
 	"github.com/conductorone/baton-confluence/pkg/connector/client"
 	"github.com/conductorone/baton-confluence/test"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	"github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 func TestGroups(t *testing.T) {
@@ -28,7 +34,7 @@ func TestGroups(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	c := groupBuilder(confluenceClient)
+	c := groupBuilder(confluenceClient, 1, cfg.SpaceAccessModeRbac)
 
 	t.Run("should list groups", func(t *testing.T) {
 		resources := make([]*v2.Resource, 0)
@@ -75,3 +81,148 @@ func TestGroups(t *testing.T) {
 		require.NotEmpty(t, grants[0].Id)
 	})
 }
+
+func TestGroupProvisioning(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	c := groupBuilder(confluenceClient, 1, cfg.SpaceAccessModeRbac)
+
+	group, err := groupResource(ctx, &client.ConfluenceGroup{Id: "group-engineering", Name: "engineering"})
+	require.Nil(t, err)
+	member := entitlement.NewAssignmentEntitlement(group, groupMemberEntitlement)
+	bob, err := userResource(ctx, &client.ConfluenceUser{AccountId: "bob", DisplayName: "Bob Doe"}, nil)
+	require.Nil(t, err)
+
+	listMembers := func(t *testing.T) []string {
+		members := make([]string, 0)
+		pToken := pagination.Token{}
+		for {
+			grants, results, err := c.Grants(ctx, group, resource.SyncOpAttrs{PageToken: pToken})
+			require.Nil(t, err)
+			for _, grant := range grants {
+				members = append(members, grant.Principal.Id.Resource)
+			}
+			if results.NextPageToken == "" {
+				return members
+			}
+			pToken.Token = results.NextPageToken
+		}
+	}
+
+	t.Run("should add a user to a group", func(t *testing.T) {
+		grants, _, err := c.Grant(ctx, bob, member)
+		require.Nil(t, err)
+		require.Len(t, grants, 1)
+		require.Equal(t, []string{"alice", "bob"}, site.GroupMembers("group-engineering"))
+		require.Equal(t, []string{"alice", "bob"}, listMembers(t))
+	})
+
+	t.Run("should remove a user from a group", func(t *testing.T) {
+		grants, _, err := c.Grant(ctx, bob, member)
+		require.Nil(t, err)
+
+		_, err = c.Revoke(ctx, grants[0])
+		require.Nil(t, err)
+		require.Equal(t, []string{"alice"}, site.GroupMembers("group-engineering"))
+		require.Equal(t, []string{"alice"}, listMembers(t))
+	})
+
+	t.Run("should surface rate limiting as a retryable error", func(t *testing.T) {
+		site.RateLimitNext(1, 2*time.Second)
+
+		_, _, err := c.List(ctx, nil, resource.SyncOpAttrs{})
+		require.Equal(t, codes.Unavailable, status.Code(err))
+
+		resources, _, err := c.List(ctx, nil, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Len(t, resources, 3)
+	})
+}
+
+func TestGroupLifecycle(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	c := groupBuilder(confluenceClient, 1, cfg.SpaceAccessModeRbac)
+
+	registry := &testActionRegistry{}
+	require.Nil(t, c.ResourceActions(ctx, registry))
+	deleteGroup := registry.handlers[deleteGroupAction]
+	require.NotNil(t, deleteGroup)
+
+	t.Run("should create a group", func(t *testing.T) {
+		created, _, err := c.Create(ctx, &v2.Resource{DisplayName: "project-apollo"})
+		require.Nil(t, err)
+		require.Equal(t, "project-apollo", created.DisplayName)
+		require.NotEmpty(t, created.Id.Resource)
+		require.Contains(t, site.Groups(), "project-apollo")
+
+		_, _, err = c.Create(ctx, &v2.Resource{DisplayName: " "})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("should delete a group without space permissions", func(t *testing.T) {
+		_, err := c.Delete(ctx, &v2.ResourceId{ResourceType: resourceTypeGroupID, Resource: "group-users"}, nil)
+		require.Nil(t, err)
+		require.NotContains(t, site.Groups(), "confluence-users")
+
+		// Deleting a group that is already gone succeeds.
+		_, err = c.Delete(ctx, &v2.ResourceId{ResourceType: resourceTypeGroupID, Resource: "group-users"}, nil)
+		require.Nil(t, err)
+	})
+
+	t.Run("should refuse to delete a group with space permissions", func(t *testing.T) {
+		_, err := c.Delete(ctx, &v2.ResourceId{ResourceType: resourceTypeGroupID, Resource: "group-engineering"}, nil)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		require.ErrorContains(t, err, "ENG")
+		require.Contains(t, site.Groups(), "engineering")
+	})
+
+	t.Run("should refuse to delete a group with a space role", func(t *testing.T) {
+		site.AddGroup("group-reviewers", "reviewers")
+		site.AssignSpaceRole("200", "GROUP", "group-reviewers", "role-viewer")
+
+		_, err := c.Delete(ctx, &v2.ResourceId{ResourceType: resourceTypeGroupID, Resource: "group-reviewers"}, nil)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		require.ErrorContains(t, err, "~alice")
+		require.Contains(t, site.Groups(), "reviewers")
+	})
+
+	t.Run("should check space roles by the configured access mode", func(t *testing.T) {
+		site.AddGroup("group-readers", "readers")
+		site.AssignSpaceRole("200", "GROUP", "group-readers", "role-viewer")
+
+		// Space roles are not synced with granular permissions, so the group
+		// held only through a role is deleted.
+		permissions := groupBuilder(confluenceClient, 1, cfg.SpaceAccessModePermissions)
+		_, err := permissions.Delete(ctx, &v2.ResourceId{ResourceType: resourceTypeGroupID, Resource: "group-readers"}, nil)
+		require.Nil(t, err)
+		require.NotContains(t, site.Groups(), "readers")
+		require.NotContains(t, site.Requests(), "GET "+client.SpaceRoleModeUrlPath)
+	})
+
+	t.Run("should delete a group with space permissions when forced", func(t *testing.T) {
+		args, err := structpb.NewStruct(map[string]interface{}{
+			"resource_id": map[string]interface{}{
+				"resource_type_id": resourceTypeGroupID,
+				"resource_id":      "group-engineering",
+			},
+			forceArgument: true,
+		})
+		require.Nil(t, err)
+
+		_, _, err = deleteGroup(ctx, args)
+		require.Nil(t, err)
+		require.NotContains(t, site.Groups(), "engineering")
+		for _, permission := range site.SpacePermissions("100") {
+			require.NotEqual(t, "group-engineering", permission.Principal.Id)
+		}
+	})
+}
@@ -22,6 +22,12 @@ func annotationsForUserResourceType() annotations.Annotations {
 	return annos
 }
 
+func accessClassAnnotations() annotations.Annotations {
+	annos := annotations.Annotations{}
+	annos.Update(&v2.SkipEntitlementsAndGrants{})
+	return annos
+}
+
 func spaceRoleAnnotations() annotations.Annotations {
 	annos := annotations.Annotations{}
 	annos.Update(&v2.SkipEntitlementsAndGrants{})
@@ -67,6 +73,8 @@ func confluencePrincipalType(resourceTypeId string) (string, error) {
 		return "USER", nil
 	case resourceTypeGroupID:
 		return "GROUP", nil
+	case resourceTypeAccessClassID:
+		return "ACCESS_CLASS", nil
 	}
 	return "", fmt.Errorf("unsupported principal resource type: %s", resourceTypeId)
 }
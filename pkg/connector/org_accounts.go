@@ -0,0 +1,148 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+)
+
+// orgAccounts holds the managed accounts of the Atlassian organization, keyed
+// by account ID. The organization admin API cannot be filtered by product, so
+// listing them all is cheaper than one request per user. The user builder
+// reads them a page per call at the start of each sync, before the users
+// that need them.
+type orgAccounts struct {
+	client *client.OrgAdminClient
+	// site is the synced site, whose Confluence activity is reported.
+	site string
+
+	mu       sync.Mutex
+	accounts map[string]*orgAccount
+	// contiguous is false when pages were read without the first ones, e.g.
+	// when a sync resumed in the middle of the accounts.
+	contiguous bool
+	loaded     bool
+}
+
+// orgAccount is a managed account and when it last used the synced site.
+type orgAccount struct {
+	*client.OrgUser
+	lastActive string
+}
+
+func newOrgAccounts(c *client.OrgAdminClient, site string) *orgAccounts {
+	if c == nil {
+		return nil
+	}
+	return &orgAccounts{client: c, site: site}
+}
+
+// reset drops the accounts read so far, so that they are read again.
+func (o *orgAccounts) reset() {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.accounts = nil
+	o.contiguous = false
+	o.loaded = false
+}
+
+// loadPage reads the page of accounts at cursor, "" for the first one, and
+// returns the cursor of the next page, or "" after the last one.
+func (o *orgAccounts) loadPage(ctx context.Context, cursor string) (string, *v2.RateLimitDescription, error) {
+	users, nextCursor, ratelimitData, err := o.client.GetUsers(ctx, cursor)
+	if err != nil {
+		return "", ratelimitData, fmt.Errorf("confluence-connector: failed to list the accounts of the organization: %w", err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if cursor == "" {
+		o.accounts = make(map[string]*orgAccount)
+		o.contiguous = true
+	} else if o.accounts == nil {
+		o.accounts = make(map[string]*orgAccount)
+	}
+	o.add(users)
+	if nextCursor == "" {
+		o.loaded = o.contiguous
+	}
+	return nextCursor, ratelimitData, nil
+}
+
+// add records accounts. It must be called with the lock held.
+func (o *orgAccounts) add(users []client.OrgUser) {
+	for _, user := range users {
+		o.accounts[user.AccountId] = &orgAccount{
+			OrgUser:    &user,
+			lastActive: user.ConfluenceLastActive(o.site),
+		}
+	}
+}
+
+// get returns the managed account with the given ID, or nil when no
+// organization is configured or the account is not managed by it. If the
+// accounts were not all read at the start of the sync, they are read here.
+func (o *orgAccounts) get(ctx context.Context, accountId string) (*orgAccount, *v2.RateLimitDescription, error) {
+	if o == nil {
+		return nil, nil, nil
+	}
+	o.mu.Lock()
+	loaded := o.loaded
+	account := o.accounts[accountId]
+	o.mu.Unlock()
+	if loaded {
+		return account, nil, nil
+	}
+
+	// Read without holding the lock: listing the organization takes a while.
+	accounts := make([]client.OrgUser, 0)
+	users := o.client.Users()
+	for user, err := range users.All(ctx) {
+		if err != nil {
+			return nil, users.RateLimit(), fmt.Errorf("confluence-connector: failed to list the accounts of the organization: %w", err)
+		}
+		accounts = append(accounts, user)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.accounts = make(map[string]*orgAccount)
+	o.add(accounts)
+	o.contiguous = true
+	o.loaded = true
+	return o.accounts[accountId], users.RateLimit(), nil
+}
+
+// orgAccountStatus maps the status of a managed account to the status of the
+// user and of its resource.
+func orgAccountStatus(accountStatus string) (v2.UserTrait_Status_Status, v2.Status_ResourceStatus) {
+	switch accountStatus {
+	case client.OrgAccountStatusActive:
+		return v2.UserTrait_Status_STATUS_ENABLED, v2.Status_RESOURCE_STATUS_ENABLED
+	case client.OrgAccountStatusInactive, client.OrgAccountStatusSuspended, client.OrgAccountStatusDeactivated:
+		return v2.UserTrait_Status_STATUS_DISABLED, v2.Status_RESOURCE_STATUS_DISABLED
+	case client.OrgAccountStatusClosed:
+		return v2.UserTrait_Status_STATUS_DELETED, v2.Status_RESOURCE_STATUS_DELETED
+	}
+	return v2.UserTrait_Status_STATUS_UNSPECIFIED, v2.Status_RESOURCE_STATUS_UNSPECIFIED
+}
+
+// parseLastActive reads the last active dates of the organization admin API,
+// which are either dates or timestamps.
+func parseLastActive(lastActive string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		parsed, err := time.Parse(layout, lastActive)
+		if err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
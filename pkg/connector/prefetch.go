@@ -0,0 +1,235 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// prefetchIdleTimeout is how long prefetched pages are kept when the SDK stops
+// asking for pages, e.g. because the sync ended or failed.
+const prefetchIdleTimeout = time.Minute
+
+// prefetchPageFunc fetches one page of a listing scoped to a group or space.
+type prefetchPageFunc[T any] func(
+	ctx context.Context,
+	id string,
+	pageToken string,
+	pageSize int,
+) ([]T, string, *v2.RateLimitDescription, error)
+
+// prefetchKey identifies a page. The page size is left out: the SDK may ask
+// for a listing with another size than the one it was prefetched with, and a
+// page is served with the token of the page that follows it, so the listing
+// stays consistent either way.
+type prefetchKey struct {
+	id        string
+	pageToken string
+}
+
+type prefetchedPage[T any] struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	// started is the number of listings started when the prefetch started.
+	started       int
+	items         []T
+	nextToken     string
+	ratelimitData *v2.RateLimitDescription
+	err           error
+}
+
+// prefetcher reads the pages of per-group and per-space listings ahead of the
+// SDK. The SDK asks for them one page per call, so without it a sync walks
+// thousands of groups strictly one request at a time.
+//
+// Builders tell the prefetcher which IDs they expect to be asked for next,
+// and every page they serve tops up the pool with the next page of the same
+// listing and the first pages of the expected IDs. Prefetching only changes
+// when a page is fetched, so results are the same whatever the concurrency.
+// Pages that are asked for out of the expected order, or whose prefetch
+// failed, are fetched inline.
+//
+// The SDK does not have to ask for every prefetched page, so pages that are
+// not claimed before the SDK moves on to a few other listings are dropped to
+// make room for new ones.
+// Prefetches run until the builder resets the prefetcher at the start of the
+// next sync, or until the SDK stops asking for pages for prefetchIdleTimeout.
+//
+// All requests go through the same client, and so through its rate limiter.
+type prefetcher[T any] struct {
+	fetch prefetchPageFunc[T]
+	// concurrency bounds the requests in flight: the one the SDK is waiting
+	// for plus the prefetches.
+	concurrency int
+	idleTimeout time.Duration
+
+	mu       sync.Mutex
+	expected []string
+	pending  map[prefetchKey]*prefetchedPage[T]
+	// listings counts the listings the SDK started, to age out unclaimed
+	// prefetches.
+	listings int
+	// workers is the context prefetches run in. It is created with the first
+	// prefetch and cancelled by reset.
+	workers context.Context
+	cancel  context.CancelFunc
+	idle    *time.Timer
+}
+
+func newPrefetcher[T any](concurrency int, fetch prefetchPageFunc[T]) *prefetcher[T] {
+	return &prefetcher[T]{
+		fetch:       fetch,
+		concurrency: max(concurrency, 1),
+		idleTimeout: prefetchIdleTimeout,
+		pending:     make(map[prefetchKey]*prefetchedPage[T]),
+	}
+}
+
+// expect queues IDs whose first page is likely to be asked for next, in the
+// order they are likely to be asked for.
+func (p *prefetcher[T]) expect(ids ...string) {
+	if p.concurrency <= 1 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expected = append(p.expected, ids...)
+}
+
+// reset cancels the prefetches in flight and forgets the expected IDs and the
+// prefetched pages.
+func (p *prefetcher[T]) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		p.cancel()
+	}
+	if p.idle != nil {
+		p.idle.Stop()
+	}
+	p.workers, p.cancel, p.idle = nil, nil, nil
+	p.expected = nil
+	p.pending = make(map[prefetchKey]*prefetchedPage[T])
+}
+
+// page returns a page of the listing of id, from a prefetch when there is one.
+func (p *prefetcher[T]) page(
+	ctx context.Context,
+	id string,
+	pageToken string,
+	pageSize int,
+) ([]T, string, *v2.RateLimitDescription, error) {
+	if p.concurrency <= 1 {
+		return p.fetch(ctx, id, pageToken, pageSize)
+	}
+
+	key := prefetchKey{id: id, pageToken: pageToken}
+	p.mu.Lock()
+	prefetched, ok := p.pending[key]
+	delete(p.pending, key)
+	if pageToken == "" {
+		p.listings++
+		if index := slices.Index(p.expected, id); index >= 0 {
+			p.expected = slices.Delete(p.expected, index, index+1)
+		}
+		p.evictStale(ctx)
+	}
+	p.mu.Unlock()
+
+	var items []T
+	var nextToken string
+	var ratelimitData *v2.RateLimitDescription
+	var err error
+	if ok {
+		select {
+		case <-ctx.Done():
+			return nil, "", nil, ctx.Err()
+		case <-prefetched.done:
+		}
+		items, nextToken, ratelimitData, err = prefetched.items, prefetched.nextToken, prefetched.ratelimitData, prefetched.err
+	}
+	if !ok || err != nil {
+		items, nextToken, ratelimitData, err = p.fetch(ctx, id, pageToken, pageSize)
+	}
+	if err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	p.schedule(ctx, id, nextToken, pageSize)
+	return items, nextToken, ratelimitData, nil
+}
+
+// evictStale drops the prefetched pages that were not claimed while the SDK
+// started more listings than the pool holds, cancelling them if they are still
+// in flight. It must be called with the lock held.
+func (p *prefetcher[T]) evictStale(ctx context.Context) {
+	evicted := 0
+	for key, prefetched := range p.pending {
+		if p.listings-prefetched.started > p.concurrency {
+			prefetched.cancel()
+			delete(p.pending, key)
+			evicted++
+		}
+	}
+	if evicted > 0 {
+		ctxzap.Extract(ctx).Debug(
+			"confluence-connector: dropped prefetched pages that were not asked for",
+			zap.Int("count", evicted),
+		)
+	}
+}
+
+// schedule starts prefetching the next page of id, then the first pages of
+// the expected IDs, while there is room in the pool.
+func (p *prefetcher[T]) schedule(ctx context.Context, id string, nextToken string, pageSize int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Prefetches outlive the SDK call that started them, but not the sync.
+	if p.workers == nil {
+		p.workers, p.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	}
+	if p.idle == nil {
+		p.idle = time.AfterFunc(p.idleTimeout, p.reset)
+	} else {
+		p.idle.Reset(p.idleTimeout)
+	}
+
+	if nextToken != "" {
+		p.start(prefetchKey{id: id, pageToken: nextToken}, pageSize)
+	}
+	for len(p.expected) > 0 && p.hasRoom() {
+		next := p.expected[0]
+		p.expected = p.expected[1:]
+		p.start(prefetchKey{id: next}, pageSize)
+	}
+}
+
+func (p *prefetcher[T]) hasRoom() bool {
+	return len(p.pending) < p.concurrency-1
+}
+
+// start prefetches a page. It must be called with the lock held.
+func (p *prefetcher[T]) start(key prefetchKey, pageSize int) {
+	if _, ok := p.pending[key]; ok || !p.hasRoom() {
+		return
+	}
+	ctx, cancel := context.WithCancel(p.workers)
+	prefetched := &prefetchedPage[T]{done: make(chan struct{}), cancel: cancel, started: p.listings}
+	p.pending[key] = prefetched
+	go func() {
+		defer close(prefetched.done)
+		prefetched.items, prefetched.nextToken, prefetched.ratelimitData, prefetched.err = p.fetch(
+			ctx,
+			key.id,
+			key.pageToken,
+			pageSize,
+		)
+	}()
+}
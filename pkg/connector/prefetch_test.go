@@ -0,0 +1,265 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	cfg "github.com/conductorone/baton-confluence/pkg/config"
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+	"github.com/conductorone/baton-confluence/test"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefetcher(t *testing.T) {
+	ctx := context.Background()
+
+	// Every listing has two pages: "" and "next".
+	newCountingPrefetcher := func(concurrency int) (*prefetcher[string], func() (int, int)) {
+		var mu sync.Mutex
+		inFlight, maxInFlight, fetches := 0, 0, 0
+		p := newPrefetcher(concurrency, func(_ context.Context, id string, pageToken string, _ int) ([]string, string, *v2.RateLimitDescription, error) {
+			mu.Lock()
+			inFlight++
+			fetches++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			if pageToken == "" {
+				return []string{id + "/0"}, "next", nil, nil
+			}
+			return []string{id + "/1"}, "", nil, nil
+		})
+		return p, func() (int, int) {
+			mu.Lock()
+			defer mu.Unlock()
+			return maxInFlight, fetches
+		}
+	}
+
+	walk := func(t *testing.T, p *prefetcher[string], ids []string) []string {
+		items := make([]string, 0)
+		for _, id := range ids {
+			pageToken := ""
+			for {
+				page, nextToken, _, err := p.page(ctx, id, pageToken, 10)
+				require.Nil(t, err)
+				items = append(items, page...)
+				if nextToken == "" {
+					break
+				}
+				pageToken = nextToken
+			}
+		}
+		return items
+	}
+
+	ids := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	expected := []string{"a/0", "a/1", "b/0", "b/1", "c/0", "c/1", "d/0", "d/1", "e/0", "e/1", "f/0", "f/1", "g/0", "g/1", "h/0", "h/1"}
+
+	t.Run("should fetch every page once and in bounded parallel", func(t *testing.T) {
+		p, stats := newCountingPrefetcher(4)
+		p.expect(ids...)
+
+		require.Equal(t, expected, walk(t, p, ids))
+		maxInFlight, fetches := stats()
+		require.Equal(t, 16, fetches)
+		require.LessOrEqual(t, maxInFlight, 4)
+		require.Greater(t, maxInFlight, 1)
+	})
+
+	t.Run("should return the same pages when asked out of order", func(t *testing.T) {
+		p, _ := newCountingPrefetcher(4)
+		p.expect(ids...)
+
+		reversed := []string{"h", "g", "f", "e", "d", "c", "b", "a"}
+		items := walk(t, p, reversed)
+		require.Equal(t, []string{"h/0", "h/1", "g/0", "g/1", "f/0", "f/1", "e/0", "e/1", "d/0", "d/1", "c/0", "c/1", "b/0", "b/1", "a/0", "a/1"}, items)
+	})
+
+	t.Run("should serve prefetched pages asked for with another size", func(t *testing.T) {
+		p, stats := newCountingPrefetcher(4)
+		p.expect(ids...)
+
+		items := make([]string, 0)
+		for i, id := range ids {
+			pageToken := ""
+			for {
+				page, nextToken, _, err := p.page(ctx, id, pageToken, 10+i)
+				require.Nil(t, err)
+				items = append(items, page...)
+				if nextToken == "" {
+					break
+				}
+				pageToken = nextToken
+			}
+		}
+		require.Equal(t, expected, items)
+		_, fetches := stats()
+		require.Equal(t, 16, fetches)
+	})
+
+	t.Run("should drop pages that are never asked for", func(t *testing.T) {
+		var mu sync.Mutex
+		prefetched := make(map[string]bool)
+		p := newPrefetcher(4, func(ctx context.Context, id string, pageToken string, _ int) ([]string, string, *v2.RateLimitDescription, error) {
+			// Only prefetches run in a cancellable context.
+			if ctx.Done() != nil {
+				mu.Lock()
+				prefetched[id] = true
+				mu.Unlock()
+			}
+			if pageToken == "" {
+				return []string{id + "/0"}, "next", nil, nil
+			}
+			return []string{id + "/1"}, "", nil, nil
+		})
+		p.expect("x", "y", "z")
+		p.expect(ids...)
+
+		require.Equal(t, expected, walk(t, p, ids))
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.True(t, prefetched["x"])
+		require.True(t, prefetched["h"])
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for key := range p.pending {
+			require.NotContains(t, []string{"x", "y", "z"}, key.id)
+		}
+	})
+
+	t.Run("should cancel prefetches when the sync restarts or goes idle", func(t *testing.T) {
+		cancelled := make(chan string, 8)
+		p := newPrefetcher(4, func(ctx context.Context, id string, pageToken string, _ int) ([]string, string, *v2.RateLimitDescription, error) {
+			if ctx.Done() == nil {
+				return []string{id + "/0"}, "next", nil, nil
+			}
+			<-ctx.Done()
+			cancelled <- id + "/" + pageToken
+			return nil, "", nil, ctx.Err()
+		})
+
+		p.expect("b")
+		_, _, _, err := p.page(ctx, "a", "", 10)
+		require.Nil(t, err)
+		p.reset()
+		require.ElementsMatch(t, []string{"a/next", "b/"}, []string{<-cancelled, <-cancelled})
+
+		p.idleTimeout = 10 * time.Millisecond
+		p.expect("b")
+		_, _, _, err = p.page(ctx, "a", "", 10)
+		require.Nil(t, err)
+		require.ElementsMatch(t, []string{"a/next", "b/"}, []string{<-cancelled, <-cancelled})
+	})
+
+	t.Run("should fetch serially without concurrency", func(t *testing.T) {
+		p, stats := newCountingPrefetcher(1)
+		p.expect(ids...)
+
+		require.Equal(t, expected, walk(t, p, ids))
+		maxInFlight, fetches := stats()
+		require.Equal(t, 16, fetches)
+		require.Equal(t, 1, maxInFlight)
+	})
+}
+
+func TestConcurrentSync(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+
+	for i := range 12 {
+		accountId := fmt.Sprintf("user-%02d", i)
+		site.AddUser(client.ConfluenceUser{AccountId: accountId, AccountType: accountTypeAtlassian, DisplayName: accountId})
+	}
+	for i := range 9 {
+		members := []string{fmt.Sprintf("user-%02d", i), fmt.Sprintf("user-%02d", i+1), fmt.Sprintf("user-%02d", i+2)}
+		site.AddGroup(fmt.Sprintf("group-%d", i), fmt.Sprintf("Group %d", i), members...)
+	}
+	for i := range 5 {
+		spaceId := fmt.Sprintf("%d", 300+i)
+		site.AddSpace(client.ConfluenceSpace{Id: spaceId, Key: fmt.Sprintf("S%d", i), Name: spaceId})
+		for j := range 3 {
+			site.AddSpacePermission(spaceId, resourceTypeUserID, fmt.Sprintf("user-%02d", i+j), "read", resourceTypeSpaceID)
+		}
+	}
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+	confluenceClient.SetConcurrency(4)
+
+	// syncUsers lists the users and returns their IDs and the page tokens, in
+	// the order they were returned.
+	syncUsers := func(t *testing.T, concurrency int) ([]string, []string) {
+		c := userBuilder(confluenceClient, nil, nil, concurrency)
+		ids := make([]string, 0)
+		tokens := make([]string, 0)
+		pToken := pagination.Token{Size: 2}
+		for {
+			resources, results, err := c.List(ctx, nil, resource.SyncOpAttrs{PageToken: pToken})
+			require.Nil(t, err)
+			for _, r := range resources {
+				ids = append(ids, r.Id.Resource)
+			}
+			tokens = append(tokens, results.NextPageToken)
+			if results.NextPageToken == "" {
+				return ids, tokens
+			}
+			pToken.Token = results.NextPageToken
+		}
+	}
+
+	// syncSpaceGrants lists the spaces, then their grants space by space.
+	syncSpaceGrants := func(t *testing.T, concurrency int) []string {
+		c := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{
+			accessMode:  cfg.SpaceAccessModePermissions,
+			nouns:       []string{resourceTypeSpaceID},
+			verbs:       []string{"read"},
+			concurrency: concurrency,
+		})
+		spaces, _, err := c.List(ctx, nil, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+
+		grants := make([]string, 0)
+		for _, space := range spaces {
+			pToken := pagination.Token{Size: 2}
+			for {
+				page, results, err := c.Grants(ctx, space, resource.SyncOpAttrs{PageToken: pToken})
+				require.Nil(t, err)
+				for _, grant := range page {
+					grants = append(grants, grant.Id)
+				}
+				if results.NextPageToken == "" {
+					break
+				}
+				pToken.Token = results.NextPageToken
+			}
+		}
+		return grants
+	}
+
+	t.Run("should list the same users and page tokens whatever the concurrency", func(t *testing.T) {
+		serialIds, serialTokens := syncUsers(t, 1)
+		concurrentIds, concurrentTokens := syncUsers(t, 4)
+
+		require.Equal(t, serialIds, concurrentIds)
+		require.Equal(t, serialTokens, concurrentTokens)
+	})
+
+	t.Run("should list the same space grants whatever the concurrency", func(t *testing.T) {
+		serial := syncSpaceGrants(t, 1)
+		require.NotEmpty(t, serial)
+		require.Equal(t, serial, syncSpaceGrants(t, 4))
+	})
+}
@@ -8,6 +8,12 @@ const (
 	resourceTypeGroupID = "group"
 	resourceTypeUserID  = "user"
 	resourceTypeSpaceID = "space"
+	resourceTypeSiteID  = "site"
+
+	resourceTypePageID     = "page"
+	resourceTypeBlogpostID = "blogpost"
+
+	resourceTypeAccessClassID = "access_class"
 
 	SpaceRoleResourceTypeID           = "space_role"
 	SpaceRoleAssignmentResourceTypeID = "space_role_assignment"
@@ -32,6 +38,27 @@ var (
 		DisplayName: "Space",
 		Traits:      []v2.ResourceType_Trait{},
 	}
+	siteResourceType = &v2.ResourceType{
+		Id:          resourceTypeSiteID,
+		DisplayName: "Site",
+		Traits:      []v2.ResourceType_Trait{},
+	}
+	pageResourceType = &v2.ResourceType{
+		Id:          resourceTypePageID,
+		DisplayName: "Page",
+		Traits:      []v2.ResourceType_Trait{},
+	}
+	blogpostResourceType = &v2.ResourceType{
+		Id:          resourceTypeBlogpostID,
+		DisplayName: "Blog Post",
+		Traits:      []v2.ResourceType_Trait{},
+	}
+	accessClassResourceType = &v2.ResourceType{
+		Id:          resourceTypeAccessClassID,
+		DisplayName: "Access Class",
+		Traits:      []v2.ResourceType_Trait{},
+		Annotations: accessClassAnnotations(),
+	}
 	spaceRoleResourceType = &v2.ResourceType{
 		Id:          SpaceRoleResourceTypeID,
 		DisplayName: "Space Role",
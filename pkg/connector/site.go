@@ -0,0 +1,424 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grantSdk "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+)
+
+// siteResourceID is the ID of the only site resource. A connector syncs a
+// single site, so the ID does not need to tell sites apart.
+const siteResourceID = "site"
+
+// Page states of the site grants in their pagination bag.
+const (
+	// globalPermissionHoldersPageState pages the holders recorded by the user
+	// sync, by offset.
+	globalPermissionHoldersPageState = "global_permission_holder"
+	// userSearchPageState pages a user search, by its cursor.
+	userSearchPageState = "user_search"
+)
+
+// globalPermission is a site-wide permission. Confluence reports some in the
+// operations of each user, and gives others through membership of a group.
+type globalPermission struct {
+	slug string
+	// operation and target match the permission in the operations of users.
+	operation string
+	target    string
+	// group is the name of the group that holds the permission.
+	group       string
+	displayName string
+	description string
+}
+
+// globalPermissions are the global permissions synced as entitlements of the
+// site.
+var globalPermissions = []globalPermission{
+	{
+		slug:        "use-application",
+		operation:   "use",
+		target:      "application",
+		displayName: "Can use Confluence",
+		description: "Can sign in to and use Confluence",
+	},
+	{
+		slug:        "create-space",
+		operation:   "create",
+		target:      "space",
+		displayName: "Create space",
+		description: "Can create spaces",
+	},
+	{
+		slug:        "administer-application",
+		operation:   "administer",
+		target:      "application",
+		displayName: "Confluence administrator",
+		description: "Can administer Confluence",
+	},
+	{
+		slug:        "site-admin",
+		group:       "site-admins",
+		displayName: "Site admin",
+		description: "Administers the Atlassian site, its users and its products, as a member of the site-admins group",
+	},
+	{
+		slug:        "system-administrator",
+		group:       "system-administrators",
+		displayName: "System administrator",
+		description: "Has the System administrator global permission, as a member of the system-administrators group",
+	},
+}
+
+// globalPermissionSlug returns the slug of the global permission an operation
+// of a user stands for.
+func globalPermissionSlug(operation client.ConfluenceOperation) (string, bool) {
+	for _, permission := range globalPermissions {
+		if permission.group == "" && permission.operation == operation.Operation && permission.target == operation.TargetType {
+			return permission.slug, true
+		}
+	}
+	return "", false
+}
+
+// globalPermissionHolder is a user and the global permissions in its
+// operations.
+type globalPermissionHolder struct {
+	accountId string
+	slugs     []string
+}
+
+// globalPermissionHolders records the global permissions of the users found
+// by user search while the user builder lists them, so that the site grants
+// do not search users a second time. The resources of every type are listed
+// before any grant is synced, so the search is complete by then; if it is not,
+// e.g. when a sync resumed after the search, the site searches users itself.
+type globalPermissionHolders struct {
+	mu       sync.Mutex
+	complete bool
+	holders  []globalPermissionHolder
+}
+
+func newGlobalPermissionHolders() *globalPermissionHolders {
+	return &globalPermissionHolders{}
+}
+
+// reset forgets the holders recorded for the last sync.
+func (h *globalPermissionHolders) reset() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.complete = false
+	h.holders = nil
+}
+
+func newGlobalPermissionHolder(user *client.ConfluenceUser) globalPermissionHolder {
+	holder := globalPermissionHolder{accountId: user.AccountId}
+	for _, operation := range user.Operations {
+		if slug, ok := globalPermissionSlug(operation); ok {
+			holder.slugs = append(holder.slugs, slug)
+		}
+	}
+	return holder
+}
+
+// add records the global permissions of a user found by user search.
+func (h *globalPermissionHolders) add(user *client.ConfluenceUser) {
+	if h == nil {
+		return
+	}
+	holder := newGlobalPermissionHolder(user)
+	if len(holder.slugs) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.holders = append(h.holders, holder)
+}
+
+// finish marks the user search as complete.
+func (h *globalPermissionHolders) finish() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.complete = true
+}
+
+// page returns the holders from offset on, at most size of them, and the
+// offset of the next page, or "" after the last one. It returns false until
+// the user search is complete.
+func (h *globalPermissionHolders) page(offset int, size int) ([]globalPermissionHolder, string, bool) {
+	if h == nil {
+		return nil, "", false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.complete {
+		return nil, "", false
+	}
+	start := min(offset, len(h.holders))
+	end := min(start+size, len(h.holders))
+	if end >= len(h.holders) {
+		return h.holders[start:end], "", true
+	}
+	return h.holders[start:end], strconv.Itoa(end), true
+}
+
+type siteBuilder struct {
+	client  *client.ConfluenceClient
+	domain  string
+	holders *globalPermissionHolders
+}
+
+func (b *siteBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return siteResourceType
+}
+
+func (b *siteBuilder) List(
+	_ context.Context,
+	parentResourceID *v2.ResourceId,
+	_ rs.SyncOpAttrs,
+) ([]*v2.Resource, *rs.SyncOpResults, error) {
+	if parentResourceID != nil {
+		return nil, nil, nil
+	}
+
+	site, err := rs.NewResource(b.domain, siteResourceType, siteResourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []*v2.Resource{site}, syncResults("", nil), nil
+}
+
+func (b *siteBuilder) Entitlements(
+	_ context.Context,
+	res *v2.Resource,
+	_ rs.SyncOpAttrs,
+) ([]*v2.Entitlement, *rs.SyncOpResults, error) {
+	entitlements := make([]*v2.Entitlement, 0, len(globalPermissions))
+	for _, permission := range globalPermissions {
+		opts := []entitlement.EntitlementOption{
+			entitlement.WithGrantableTo(resourceTypeUser),
+			entitlement.WithDisplayName(permission.displayName),
+			entitlement.WithDescription(permission.description),
+		}
+		if permission.group != "" {
+			opts = append(opts, entitlement.WithGrantableTo(resourceTypeGroup))
+		}
+		entitlements = append(entitlements, entitlement.NewPermissionEntitlement(res, permission.slug, opts...))
+	}
+	return entitlements, syncResults("", nil), nil
+}
+
+// Grants returns the groups that hold global permissions, expandable to their
+// members, then the users whose operations include global permissions.
+// Confluence reports the permissions each user ends up with, not the groups
+// they were given to, so those grants are to users. The users are paged from
+// the holders recorded by the user sync, or else from a user search.
+func (b *siteBuilder) Grants(
+	ctx context.Context,
+	res *v2.Resource,
+	opts rs.SyncOpAttrs,
+) ([]*v2.Grant, *rs.SyncOpResults, error) {
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(opts.PageToken.Token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var grants []*v2.Grant
+	if bag.Current() == nil {
+		groupGrants, ratelimitData, err := b.groupGrants(ctx, res)
+		if err != nil {
+			return nil, syncResults("", WithRateLimitAnnotations(ratelimitData)), err
+		}
+		grants = groupGrants
+		bag.Push(pagination.PageState{ResourceTypeID: globalPermissionHoldersPageState})
+	}
+
+	size := opts.PageToken.Size
+	if size <= 0 {
+		size = ResourcesPageSize
+	}
+
+	if bag.ResourceTypeID() == globalPermissionHoldersPageState {
+		offset, _ := strconv.Atoi(bag.PageToken())
+		holders, nextOffset, ok := b.holders.page(offset, size)
+		if ok {
+			for _, holder := range holders {
+				grants = append(grants, holderGrants(res, holder)...)
+			}
+			nextPage, err := bag.NextToken(nextOffset)
+			if err != nil {
+				return nil, nil, err
+			}
+			return grants, syncResults(nextPage, nil), nil
+		}
+		// The user sync did not record the holders, e.g. because the sync
+		// resumed after it: search the users from the start.
+		bag.Pop()
+		bag.Push(pagination.PageState{ResourceTypeID: userSearchPageState})
+	}
+
+	users, nextToken, ratelimitData, err := b.client.UsersFromSearch().
+		WithPageSize(size).
+		Page(ctx, bag.PageToken())
+	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+	if err != nil {
+		return nil, syncResults("", outputAnnotations), fmt.Errorf("confluence-connector: failed to list users: %w", err)
+	}
+	for _, user := range users {
+		if !shouldIncludeUser(ctx, user) {
+			continue
+		}
+		grants = append(grants, holderGrants(res, newGlobalPermissionHolder(&user))...)
+	}
+
+	nextPage, err := bag.NextToken(nextToken)
+	if err != nil {
+		return nil, syncResults("", outputAnnotations), err
+	}
+	return grants, syncResults(nextPage, outputAnnotations), nil
+}
+
+// Grant adds a user to the group that holds a global permission. The other
+// global permissions cannot be changed through the API.
+func (b *siteBuilder) Grant(
+	ctx context.Context,
+	principal *v2.Resource,
+	ent *v2.Entitlement,
+) ([]*v2.Grant, annotations.Annotations, error) {
+	group, ratelimitData, err := b.provisionedGroup(ctx, ent.Slug, principal.Id)
+	if err != nil {
+		return nil, WithRateLimitAnnotations(ratelimitData), err
+	}
+	ratelimitData, err = b.client.AddUserToGroup(ctx, principal.Id.Resource, group.Id)
+	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+	if err != nil {
+		return nil, outputAnnotations, err
+	}
+	return []*v2.Grant{grantSdk.NewGrant(ent.Resource, ent.Slug, principal.Id)}, outputAnnotations, nil
+}
+
+// Revoke removes a user from the group that holds a global permission.
+func (b *siteBuilder) Revoke(
+	ctx context.Context,
+	grant *v2.Grant,
+) (annotations.Annotations, error) {
+	group, ratelimitData, err := b.provisionedGroup(ctx, grant.Entitlement.Slug, grant.Principal.Id)
+	if err != nil {
+		return WithRateLimitAnnotations(ratelimitData), err
+	}
+	ratelimitData, err = b.client.RemoveUserFromGroup(ctx, grant.Principal.Id.Resource, group.Id)
+	return WithRateLimitAnnotations(ratelimitData), err
+}
+
+// provisionedGroup returns the group whose membership gives the global
+// permission slug to a user. Only users are provisioned, as Confluence groups
+// cannot be nested.
+func (b *siteBuilder) provisionedGroup(
+	ctx context.Context,
+	slug string,
+	principal *v2.ResourceId,
+) (*client.ConfluenceGroup, *v2.RateLimitDescription, error) {
+	idx := slices.IndexFunc(globalPermissions, func(permission globalPermission) bool {
+		return permission.slug == slug
+	})
+	if idx < 0 {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "confluence-connector: unknown global permission %s", slug)
+	}
+	permission := globalPermissions[idx]
+	if permission.group == "" {
+		return nil, nil, status.Errorf(
+			codes.InvalidArgument,
+			"confluence-connector: the %s global permission cannot be changed through the Confluence API",
+			slug,
+		)
+	}
+	if principal.ResourceType != resourceTypeUser.Id {
+		return nil, nil, status.Errorf(
+			codes.InvalidArgument,
+			"confluence-connector: the %s global permission can only be provisioned to users, through the %s group",
+			slug,
+			permission.group,
+		)
+	}
+
+	group, ratelimitData, err := b.client.GetGroupByName(ctx, permission.group)
+	if isNotFound(err) {
+		return nil, ratelimitData, status.Errorf(codes.FailedPrecondition, "confluence-connector: the site has no %s group", permission.group)
+	}
+	if err != nil {
+		return nil, ratelimitData, fmt.Errorf("confluence-connector: failed to get group %s: %w", permission.group, err)
+	}
+	return group, ratelimitData, nil
+}
+
+// groupGrants grants the global permissions held through a group to the
+// group, when the site has it.
+func (b *siteBuilder) groupGrants(ctx context.Context, res *v2.Resource) ([]*v2.Grant, *v2.RateLimitDescription, error) {
+	var grants []*v2.Grant
+	var ratelimitData *v2.RateLimitDescription
+	for _, permission := range globalPermissions {
+		if permission.group == "" {
+			continue
+		}
+		var group *client.ConfluenceGroup
+		var err error
+		group, ratelimitData, err = b.client.GetGroupByName(ctx, permission.group)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, ratelimitData, fmt.Errorf("confluence-connector: failed to get group %s: %w", permission.group, err)
+		}
+		grants = append(grants, grantSdk.NewGrant(
+			res,
+			permission.slug,
+			&v2.ResourceId{ResourceType: resourceTypeGroup.Id, Resource: group.Id},
+			grantSdk.WithAnnotation(&v2.GrantExpandable{
+				EntitlementIds: []string{
+					fmt.Sprintf("group:%s:member", group.Id),
+				},
+			}),
+		))
+	}
+	return grants, ratelimitData, nil
+}
+
+func holderGrants(res *v2.Resource, holder globalPermissionHolder) []*v2.Grant {
+	grants := make([]*v2.Grant, 0, len(holder.slugs))
+	for _, slug := range holder.slugs {
+		grants = append(grants, grantSdk.NewGrant(
+			res,
+			slug,
+			&v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: holder.accountId},
+		))
+	}
+	return grants
+}
+
+func newSiteBuilder(c *client.ConfluenceClient, domain string, holders *globalPermissionHolders) *siteBuilder {
+	return &siteBuilder{
+		client:  c,
+		domain:  domain,
+		holders: holders,
+	}
+}
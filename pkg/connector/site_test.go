@@ -0,0 +1,175 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+	"github.com/conductorone/baton-confluence/test"
+)
+
+func TestSite(t *testing.T) {
+	ctx := context.Background()
+	fakeSite, server := test.FakeServer(t)
+	fakeSite.AddGroup("group-site-admins", "site-admins", "admin")
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	b := newSiteBuilder(confluenceClient, server.URL, nil)
+
+	sites, _, err := b.List(ctx, nil, resource.SyncOpAttrs{})
+	require.Nil(t, err)
+	require.Len(t, sites, 1)
+	site := sites[0]
+
+	t.Run("should list the global permissions as entitlements", func(t *testing.T) {
+		entitlements, _, err := b.Entitlements(ctx, site, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Len(t, entitlements, len(globalPermissions))
+	})
+
+	t.Run("should grant global permissions from the operations of users", func(t *testing.T) {
+		grants, results, err := b.Grants(ctx, site, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Empty(t, results.NextPageToken)
+
+		byEntitlement := make(map[string][]string)
+		for _, grant := range grants {
+			byEntitlement[grant.Entitlement.Id] = append(byEntitlement[grant.Entitlement.Id], grant.Principal.Id.Resource)
+		}
+		require.Equal(t, []string{"admin"}, byEntitlement["site:site:administer-application"])
+		require.Equal(t, []string{"admin"}, byEntitlement["site:site:create-space"])
+		// Deactivated users have no operations and app accounts are skipped.
+		require.Equal(t, []string{"admin", "alice", "bob"}, byEntitlement["site:site:use-application"])
+	})
+
+	t.Run("should grant global permissions held through groups to the groups", func(t *testing.T) {
+		grants, _, err := b.Grants(ctx, site, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+
+		byEntitlement := make(map[string][]string)
+		for _, grant := range grants {
+			if grant.Principal.Id.ResourceType == resourceTypeGroupID {
+				byEntitlement[grant.Entitlement.Id] = append(byEntitlement[grant.Entitlement.Id], grant.Principal.Id.Resource)
+				require.NotNil(t, grant.Annotations)
+			}
+		}
+		require.Equal(t, []string{"group-site-admins"}, byEntitlement["site:site:site-admin"])
+		// The site has no system-administrators group.
+		require.Empty(t, byEntitlement["site:site:system-administrator"])
+	})
+
+	t.Run("should page the user search with its own tokens", func(t *testing.T) {
+		userGrants := make([]string, 0)
+		pToken := pagination.Token{Size: 1}
+		for {
+			grants, results, err := b.Grants(ctx, site, resource.SyncOpAttrs{PageToken: pToken})
+			require.Nil(t, err)
+			for _, grant := range grants {
+				if grant.Principal.Id.ResourceType == resourceTypeUserID {
+					userGrants = append(userGrants, grant.Id)
+				}
+			}
+			if results.NextPageToken == "" {
+				break
+			}
+			pToken.Token = results.NextPageToken
+		}
+		require.ElementsMatch(t, []string{
+			"site:site:use-application:user:admin",
+			"site:site:create-space:user:admin",
+			"site:site:administer-application:user:admin",
+			"site:site:use-application:user:alice",
+			"site:site:use-application:user:bob",
+		}, userGrants)
+	})
+
+	t.Run("should provision the global permissions held through groups", func(t *testing.T) {
+		entitlements, _, err := b.Entitlements(ctx, site, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		bySlug := make(map[string]*v2.Entitlement)
+		for _, ent := range entitlements {
+			bySlug[ent.Slug] = ent
+		}
+		alice, err := userResource(ctx, &client.ConfluenceUser{AccountId: "alice", DisplayName: "Alice Doe"}, nil)
+		require.Nil(t, err)
+
+		grants, _, err := b.Grant(ctx, alice, bySlug["site-admin"])
+		require.Nil(t, err)
+		require.Len(t, grants, 1)
+		require.Contains(t, fakeSite.GroupMembers("group-site-admins"), "alice")
+
+		_, err = b.Revoke(ctx, &v2.Grant{Entitlement: bySlug["site-admin"], Principal: alice})
+		require.Nil(t, err)
+		require.NotContains(t, fakeSite.GroupMembers("group-site-admins"), "alice")
+
+		// The site has no system-administrators group.
+		_, _, err = b.Grant(ctx, alice, bySlug["system-administrator"])
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, _, err = b.Grant(ctx, alice, bySlug["create-space"])
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		group, err := groupResource(ctx, &client.ConfluenceGroup{Id: "group-site-admins", Name: "site-admins"})
+		require.Nil(t, err)
+		_, _, err = b.Grant(ctx, group, bySlug["site-admin"])
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("should grant global permissions from the user sync without searching users again", func(t *testing.T) {
+		holders := newGlobalPermissionHolders()
+		users := userBuilder(confluenceClient, nil, holders, 1)
+		b := newSiteBuilder(confluenceClient, server.URL, holders)
+
+		pToken := pagination.Token{}
+		for {
+			_, results, err := users.List(ctx, nil, resource.SyncOpAttrs{PageToken: pToken})
+			require.Nil(t, err)
+			if results.NextPageToken == "" {
+				break
+			}
+			pToken.Token = results.NextPageToken
+		}
+
+		searches := func() int {
+			return len(slices.DeleteFunc(fakeSite.Requests(), func(request string) bool {
+				return !strings.Contains(request, client.SearchUrlPath)
+			}))
+		}
+		before := searches()
+
+		userGrants := make([]string, 0)
+		pToken = pagination.Token{Size: 1}
+		for {
+			grants, results, err := b.Grants(ctx, site, resource.SyncOpAttrs{PageToken: pToken})
+			require.Nil(t, err)
+			for _, grant := range grants {
+				if grant.Principal.Id.ResourceType == resourceTypeUserID {
+					userGrants = append(userGrants, grant.Id)
+				}
+			}
+			if results.NextPageToken == "" {
+				break
+			}
+			pToken.Token = results.NextPageToken
+		}
+		require.Equal(t, before, searches())
+		require.ElementsMatch(t, []string{
+			"site:site:use-application:user:admin",
+			"site:site:create-space:user:admin",
+			"site:site:administer-application:user:admin",
+			"site:site:use-application:user:alice",
+			"site:site:use-application:user:bob",
+		}, userGrants)
+	})
+}
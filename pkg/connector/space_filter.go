@@ -0,0 +1,182 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+)
+
+const spaceTypePersonal = "personal"
+
+// spaceTypes and spaceStatuses are the values accepted by the space type and
+// status filters.
+var (
+	spaceTypes    = []string{"global", spaceTypePersonal, "collaboration", "knowledge_base"}
+	spaceStatuses = []string{client.SpaceStatusCurrent, client.SpaceStatusArchived}
+)
+
+// spaceFilter selects the spaces to sync. Spaces it rejects are not listed,
+// so neither their permissions nor their child resources are synced. Empty
+// lists do not filter.
+type spaceFilter struct {
+	skipPersonal bool
+	// personalSpaceOwners keeps the personal spaces of these account IDs or
+	// usernames.
+	personalSpaceOwners []string
+	// includeKeys and excludeKeys are glob patterns matched against space
+	// keys, as accepted by path.Match.
+	includeKeys []string
+	excludeKeys []string
+	types       []string
+	statuses    []string
+	// A space is included when it has any of includeLabels and excluded when
+	// it has any of excludeLabels.
+	includeLabels []string
+	excludeLabels []string
+}
+
+func newSpaceFilter(config Config) (*spaceFilter, error) {
+	if config.SkipPersonalSpaces && len(config.PersonalSpaceOwners) > 0 {
+		return nil, errors.New("confluence-connector: skip-personal-spaces and personal-space-owners cannot be used together")
+	}
+	for _, pattern := range slices.Concat(config.IncludeSpaceKeys, config.ExcludeSpaceKeys) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("confluence-connector: invalid space key pattern %q: %w", pattern, err)
+		}
+	}
+	for _, spaceType := range config.SpaceTypes {
+		if !slices.Contains(spaceTypes, spaceType) {
+			return nil, fmt.Errorf("confluence-connector: unsupported space type: %s", spaceType)
+		}
+	}
+	for _, spaceStatus := range config.SpaceStatuses {
+		if !slices.Contains(spaceStatuses, spaceStatus) {
+			return nil, fmt.Errorf("confluence-connector: unsupported space status: %s", spaceStatus)
+		}
+	}
+
+	return &spaceFilter{
+		skipPersonal:        config.SkipPersonalSpaces,
+		personalSpaceOwners: config.PersonalSpaceOwners,
+		includeKeys:         config.IncludeSpaceKeys,
+		excludeKeys:         config.ExcludeSpaceKeys,
+		types:               config.SpaceTypes,
+		statuses:            config.SpaceStatuses,
+		includeLabels:       config.IncludeSpaceLabels,
+		excludeLabels:       config.ExcludeSpaceLabels,
+	}, nil
+}
+
+// active reports whether the filter rejects any space.
+func (f *spaceFilter) active() bool {
+	return f != nil && (f.skipPersonal ||
+		len(f.personalSpaceOwners) > 0 ||
+		len(f.includeKeys) > 0 ||
+		len(f.excludeKeys) > 0 ||
+		len(f.types) > 0 ||
+		len(f.statuses) > 0 ||
+		len(f.includeLabels) > 0 ||
+		len(f.excludeLabels) > 0)
+}
+
+// query returns the part of the filter Confluence applies when listing
+// spaces: literal included keys, a single type or status, and the included
+// labels. Exclusions and key patterns are only checked by includes.
+func (f *spaceFilter) query() client.SpaceFilter {
+	var query client.SpaceFilter
+	if f == nil {
+		return query
+	}
+	if !slices.ContainsFunc(f.includeKeys, isKeyPattern) {
+		query.Keys = f.includeKeys
+	}
+	if len(f.types) == 1 {
+		query.Type = f.types[0]
+	}
+	if len(f.statuses) == 1 {
+		query.Status = f.statuses[0]
+	}
+	query.Labels = f.includeLabels
+	return query
+}
+
+// includes reports whether a space passes the filter. listed is set for
+// spaces listed with query, whose included labels were already checked by
+// Confluence; the labels of a space are only read when they are needed.
+func (f *spaceFilter) includes(
+	ctx context.Context,
+	c *client.ConfluenceClient,
+	space *client.ConfluenceSpace,
+	listed bool,
+) (bool, *v2.RateLimitDescription, error) {
+	if !f.active() {
+		return true, nil, nil
+	}
+	if f.skipPersonal && space.Type == spaceTypePersonal {
+		return false, nil, nil
+	}
+	if len(f.includeKeys) > 0 && !matchesAnyKey(f.includeKeys, space.Key) {
+		return false, nil, nil
+	}
+	if matchesAnyKey(f.excludeKeys, space.Key) {
+		return false, nil, nil
+	}
+	if len(f.types) > 0 && !slices.Contains(f.types, space.Type) {
+		return false, nil, nil
+	}
+	if len(f.statuses) > 0 && !slices.Contains(f.statuses, space.Status) {
+		return false, nil, nil
+	}
+
+	var ratelimitData *v2.RateLimitDescription
+	if space.Type == spaceTypePersonal && len(f.personalSpaceOwners) > 0 {
+		var ownerId string
+		var err error
+		ownerId, ratelimitData, err = c.GetPersonalSpaceOwner(ctx, space)
+		if err != nil {
+			return false, ratelimitData, fmt.Errorf("confluence-connector: failed to get the owner of space %s: %w", space.Key, err)
+		}
+		username := strings.TrimPrefix(space.Key, "~")
+		if !slices.Contains(f.personalSpaceOwners, ownerId) && !slices.Contains(f.personalSpaceOwners, username) {
+			return false, ratelimitData, nil
+		}
+	}
+
+	checkIncludedLabels := len(f.includeLabels) > 0 && !listed
+	if !checkIncludedLabels && len(f.excludeLabels) == 0 {
+		return true, ratelimitData, nil
+	}
+	labels, ratelimitData, err := c.GetSpaceLabels(ctx, space.Id)
+	if err != nil {
+		return false, ratelimitData, fmt.Errorf("confluence-connector: failed to get labels of space %s: %w", space.Key, err)
+	}
+	if checkIncludedLabels && !hasAnyLabel(labels, f.includeLabels) {
+		return false, ratelimitData, nil
+	}
+	return !hasAnyLabel(labels, f.excludeLabels), ratelimitData, nil
+}
+
+func isKeyPattern(key string) bool {
+	return strings.ContainsAny(key, `*?[\`)
+}
+
+func matchesAnyKey(patterns []string, key string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		// Patterns were validated by newSpaceFilter.
+		matched, _ := path.Match(pattern, key)
+		return matched
+	})
+}
+
+func hasAnyLabel(labels []string, wanted []string) bool {
+	return slices.ContainsFunc(labels, func(label string) bool {
+		return slices.Contains(wanted, label)
+	})
+}
@@ -0,0 +1,146 @@
+package connector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+
+	cfg "github.com/conductorone/baton-confluence/pkg/config"
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+	"github.com/conductorone/baton-confluence/test"
+)
+
+func TestNewSpaceFilter(t *testing.T) {
+	_, err := newSpaceFilter(Config{SpaceTypes: []string{"team"}})
+	require.ErrorContains(t, err, "unsupported space type")
+
+	_, err = newSpaceFilter(Config{SpaceStatuses: []string{"deleted"}})
+	require.ErrorContains(t, err, "unsupported space status")
+
+	_, err = newSpaceFilter(Config{SkipPersonalSpaces: true, PersonalSpaceOwners: []string{"alice"}})
+	require.ErrorContains(t, err, "cannot be used together")
+
+	_, err = newSpaceFilter(Config{IncludeSpaceKeys: []string{"ENG["}})
+	require.ErrorContains(t, err, "invalid space key pattern")
+
+	filter, err := newSpaceFilter(Config{})
+	require.Nil(t, err)
+	require.False(t, filter.active())
+
+	filter, err = newSpaceFilter(Config{
+		IncludeSpaceKeys:   []string{"ENG", "DOCS"},
+		SpaceTypes:         []string{"global"},
+		SpaceStatuses:      []string{"current", "archived"},
+		IncludeSpaceLabels: []string{"team"},
+	})
+	require.Nil(t, err)
+	require.Equal(t, client.SpaceFilter{
+		Keys:   []string{"ENG", "DOCS"},
+		Type:   "global",
+		Labels: []string{"team"},
+	}, filter.query())
+
+	filter, err = newSpaceFilter(Config{IncludeSpaceKeys: []string{"ENG", "TEAM-*"}})
+	require.Nil(t, err)
+	require.Empty(t, filter.query().Keys)
+}
+
+func TestSpaceFiltering(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+	site.AddSpace(client.ConfluenceSpace{Id: "300", Key: "DOCS", Name: "Documentation", Status: client.SpaceStatusArchived})
+	site.AddSpace(client.ConfluenceSpace{Id: "400", Key: "TEAM-A", Name: "Team A", Type: "collaboration"})
+	site.SetSpaceLabels("100", "team")
+	site.SetSpaceLabels("400", "team", "internal")
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	listSpaces := func(t *testing.T, config Config) []string {
+		filter, err := newSpaceFilter(config)
+		require.Nil(t, err)
+		c := newSpaceBuilder(confluenceClient, filter, spaceBuilderOptions{
+			accessMode:  cfg.SpaceAccessModeRbac,
+			concurrency: 1,
+		})
+
+		keys := make([]string, 0)
+		pToken := &pagination.Token{}
+		for {
+			resources, results, err := c.List(ctx, nil, resource.SyncOpAttrs{PageToken: *pToken})
+			require.Nil(t, err)
+			for _, r := range resources {
+				space, _, err := confluenceClient.GetSpaceById(ctx, r.Id.Resource)
+				require.Nil(t, err)
+				keys = append(keys, space.Key)
+			}
+			if results.NextPageToken == "" {
+				return keys
+			}
+			pToken = &pagination.Token{Token: results.NextPageToken}
+		}
+	}
+
+	t.Run("should list every space without a filter", func(t *testing.T) {
+		require.Equal(t, []string{"ENG", "~alice", "DOCS", "TEAM-A"}, listSpaces(t, Config{}))
+	})
+
+	t.Run("should include and exclude space keys by pattern", func(t *testing.T) {
+		require.Equal(t, []string{"ENG", "TEAM-A"}, listSpaces(t, Config{IncludeSpaceKeys: []string{"ENG", "TEAM-*"}}))
+		require.Equal(t, []string{"ENG", "DOCS"}, listSpaces(t, Config{ExcludeSpaceKeys: []string{"~*", "TEAM-*"}}))
+	})
+
+	t.Run("should filter by type and status", func(t *testing.T) {
+		require.Equal(t, []string{"ENG", "DOCS"}, listSpaces(t, Config{SpaceTypes: []string{"global"}}))
+		require.Equal(t, []string{"ENG", "~alice", "TEAM-A"}, listSpaces(t, Config{SpaceStatuses: []string{client.SpaceStatusCurrent}}))
+		require.Equal(t, []string{"ENG", "DOCS", "TEAM-A"}, listSpaces(t, Config{SkipPersonalSpaces: true}))
+	})
+
+	t.Run("should only sync the personal spaces of the selected users", func(t *testing.T) {
+		require.Equal(t, []string{"ENG", "DOCS", "TEAM-A"}, listSpaces(t, Config{PersonalSpaceOwners: []string{"bob"}}))
+		require.Equal(t, []string{"ENG", "~alice", "DOCS", "TEAM-A"}, listSpaces(t, Config{PersonalSpaceOwners: []string{"alice"}}))
+	})
+
+	t.Run("should filter by label", func(t *testing.T) {
+		require.Equal(t, []string{"ENG", "TEAM-A"}, listSpaces(t, Config{IncludeSpaceLabels: []string{"team"}}))
+		require.Equal(t, []string{"ENG"}, listSpaces(t, Config{
+			IncludeSpaceLabels: []string{"team"},
+			ExcludeSpaceLabels: []string{"internal"},
+		}))
+	})
+
+	t.Run("should send the filter to Confluence", func(t *testing.T) {
+		filter, err := newSpaceFilter(Config{SpaceTypes: []string{"collaboration"}})
+		require.Nil(t, err)
+		spaces, _, _, err := confluenceClient.GetFilteredSpaces(ctx, filter.query(), 10, "")
+		require.Nil(t, err)
+		require.Len(t, spaces, 1)
+		require.Equal(t, "TEAM-A", spaces[0].Key)
+	})
+
+	t.Run("should drop audit events of filtered out spaces", func(t *testing.T) {
+		site.AddAuditRecord(client.AuditRecord{
+			CreationDate:   1000,
+			Summary:        "Space permission added",
+			AffectedObject: client.AuditObject{Name: "Engineering", ObjectType: "Space"},
+		})
+		site.AddAuditRecord(client.AuditRecord{
+			CreationDate:   2000,
+			Summary:        "Space permission added",
+			AffectedObject: client.AuditObject{Name: "Team A", ObjectType: "Space"},
+		})
+
+		filter, err := newSpaceFilter(Config{ExcludeSpaceLabels: []string{"internal"}})
+		require.Nil(t, err)
+		feed := newAuditEventFeed(confluenceClient, filter, spaceBuilderOptions{accessMode: cfg.SpaceAccessModePermissions, nouns: cfg.DefaultNouns, verbs: cfg.DefaultVerbs})
+		feed.now = func() time.Time { return time.UnixMilli(3000) }
+
+		events, _, _, err := feed.ListEvents(ctx, nil, &pagination.StreamToken{Size: 10})
+		require.Nil(t, err)
+		require.Equal(t, []string{spaceResourceType.Id + ":100"}, changedResources(events))
+	})
+}
@@ -0,0 +1,128 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// spacePermission is a space permission operation and the target it applies
+// to, e.g. create on page.
+type spacePermission struct {
+	operation string
+	target    string
+}
+
+// spacePermissionCatalog lists every space permission the connector can sync
+// and provision. Nouns are targets and verbs are operations: only the pairs
+// listed here are valid, so configuring `create` and `space` does not produce
+// a `create-space` entitlement.
+//
+// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-space-permissions/
+var spacePermissionCatalog = []spacePermission{
+	{"read", resourceTypeSpaceID},
+	{"administer", resourceTypeSpaceID},
+	{"delete", resourceTypeSpaceID},
+	{"export", resourceTypeSpaceID},
+	{"restrict_content", resourceTypeSpaceID},
+	{"create", "page"},
+	{"delete", "page"},
+	{"archive", "page"},
+	{"copy", "page"},
+	{"move", "page"},
+	{"restore", "page"},
+	{"purge", "page"},
+	{"purge_version", "page"},
+	{"create", "blogpost"},
+	{"delete", "blogpost"},
+	{"restore", "blogpost"},
+	{"purge", "blogpost"},
+	{"purge_version", "blogpost"},
+	{"create", "comment"},
+	{"delete", "comment"},
+	{"create", "attachment"},
+	{"delete", "attachment"},
+	{"create", "whiteboard"},
+	{"delete", "whiteboard"},
+	{"create", "database"},
+	{"delete", "database"},
+	{"create", "embed"},
+	{"delete", "embed"},
+	{"read", "userProfile"},
+	{"create_space", "application"},
+}
+
+// deprecatedOperations were valid verbs before the catalog, but no space
+// permission uses them. They still become entitlements with every noun, so
+// that existing deployments keep them, but they cannot be provisioned.
+var deprecatedOperations = []string{"update"}
+
+// isCatalogedSpacePermission reports whether the operation can be granted on
+// the target.
+func isCatalogedSpacePermission(operation string, target string) bool {
+	return slices.Contains(spacePermissionCatalog, spacePermission{operation: operation, target: target})
+}
+
+// isSyncedSpacePermission reports whether the operation on the target becomes
+// an entitlement when the nouns and verbs include them: the cataloged pairs,
+// and the deprecated operations on any target.
+func isSyncedSpacePermission(operation string, target string) bool {
+	return isCatalogedSpacePermission(operation, target) || slices.Contains(deprecatedOperations, operation)
+}
+
+// catalogOperations returns the distinct operations of the catalog, the valid
+// verbs.
+func catalogOperations() []string {
+	operations := make([]string, 0)
+	for _, permission := range spacePermissionCatalog {
+		if !slices.Contains(operations, permission.operation) {
+			operations = append(operations, permission.operation)
+		}
+	}
+	return operations
+}
+
+// validVerbs returns the verbs accepted in the configuration: the catalog
+// operations and the deprecated ones.
+func validVerbs() []string {
+	return append(catalogOperations(), deprecatedOperations...)
+}
+
+// warnDeprecatedVerbs logs the configured verbs that are deprecated.
+func warnDeprecatedVerbs(ctx context.Context, verbs []string) {
+	for _, verb := range verbs {
+		if slices.Contains(deprecatedOperations, verb) {
+			ctxzap.Extract(ctx).Warn(
+				"confluence-connector: the verb is deprecated, as no space permission uses it; its entitlements are still synced but cannot be provisioned",
+				zap.String("verb", verb),
+			)
+		}
+	}
+}
+
+// catalogTargets returns the distinct targets of the catalog, the valid nouns.
+func catalogTargets() []string {
+	targets := make([]string, 0)
+	for _, permission := range spacePermissionCatalog {
+		if !slices.Contains(targets, permission.target) {
+			targets = append(targets, permission.target)
+		}
+	}
+	return targets
+}
+
+// catalogedSpacePermission parses an entitlement slug and checks it against
+// the catalog before it is provisioned.
+func catalogedSpacePermission(slug string) (string, string, error) {
+	operation, target, err := GetEntitlementComponents(slug)
+	if err != nil {
+		return "", "", err
+	}
+	if !isCatalogedSpacePermission(operation, target) {
+		return "", "", fmt.Errorf("confluence-connector: %s is not a supported space permission", slug)
+	}
+	return operation, target, nil
+}
@@ -0,0 +1,124 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cfg "github.com/conductorone/baton-confluence/pkg/config"
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+	"github.com/conductorone/baton-confluence/test"
+)
+
+func TestSpacePermissionCatalog(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should only default to valid nouns and verbs", func(t *testing.T) {
+		require.Subset(t, catalogTargets(), cfg.DefaultNouns)
+		require.Subset(t, validVerbs(), cfg.DefaultVerbs)
+		require.Contains(t, cfg.DefaultVerbs, "update")
+	})
+
+	t.Run("should accept every cataloged noun and verb, in catalog order", func(t *testing.T) {
+		nouns, err := filterArgs([]string{"embed", "whiteboard", "database", "space"}, catalogTargets(), cfg.DefaultNouns)
+		require.Nil(t, err)
+		require.Equal(t, []string{"space", "whiteboard", "database", "embed"}, nouns)
+
+		verbs, err := filterArgs([]string{"purge_version", "copy", "move"}, catalogOperations(), cfg.DefaultVerbs)
+		require.Nil(t, err)
+		require.Equal(t, []string{"copy", "move", "purge_version"}, verbs)
+
+		_, err = filterArgs([]string{"write"}, validVerbs(), cfg.DefaultVerbs)
+		require.NotNil(t, err)
+	})
+
+	t.Run("should keep the entitlements of deprecated verbs", func(t *testing.T) {
+		verbs, err := filterArgs([]string{"update", "read"}, validVerbs(), cfg.DefaultVerbs)
+		require.Nil(t, err)
+		require.Equal(t, []string{"read", "update"}, verbs)
+
+		c := newSpaceBuilder(nil, nil, spaceBuilderOptions{
+			accessMode:  cfg.SpaceAccessModePermissions,
+			nouns:       []string{"space"},
+			verbs:       verbs,
+			concurrency: 1,
+		})
+		space, err := spaceResource(ctx, &client.ConfluenceSpace{Id: "100", Name: "Engineering"}, nil, "")
+		require.Nil(t, err)
+		entitlements, _, err := c.Entitlements(ctx, space, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Len(t, entitlements, 3)
+		require.Equal(t, "read-space", entitlements[1].Slug)
+		require.Equal(t, "update-space", entitlements[2].Slug)
+	})
+
+	t.Run("should parse entitlement slugs", func(t *testing.T) {
+		operation, target, err := GetEntitlementComponents("purge_version-page")
+		require.Nil(t, err)
+		require.Equal(t, "purge_version", operation)
+		require.Equal(t, "page", target)
+
+		_, _, err = GetEntitlementComponents("read")
+		require.NotNil(t, err)
+	})
+
+	t.Run("should only create entitlements for cataloged pairs", func(t *testing.T) {
+		c := newSpaceBuilder(nil, nil, spaceBuilderOptions{
+			accessMode:  cfg.SpaceAccessModePermissions,
+			nouns:       catalogTargets(),
+			verbs:       catalogOperations(),
+			concurrency: 1,
+		})
+		space, err := spaceResource(ctx, &client.ConfluenceSpace{Id: "100", Name: "Engineering"}, nil, "")
+		require.Nil(t, err)
+
+		entitlements, _, err := c.Entitlements(ctx, space, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		// Every cataloged pair, after the owner entitlement.
+		require.Len(t, entitlements, len(spacePermissionCatalog)+1)
+		require.Equal(t, spaceOwnerEntitlement, entitlements[0].Slug)
+
+		slugs := make([]string, 0, len(entitlements))
+		for _, ent := range entitlements {
+			slugs = append(slugs, ent.Slug)
+		}
+		require.Contains(t, slugs, "create-whiteboard")
+		require.Contains(t, slugs, "read-userProfile")
+		require.Contains(t, slugs, "create_space-application")
+		require.NotContains(t, slugs, "archive-comment")
+	})
+
+	t.Run("should refuse to provision uncataloged pairs", func(t *testing.T) {
+		_, server := test.FakeServer(t)
+		confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+		require.Nil(t, err)
+		c := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{
+			accessMode:  cfg.SpaceAccessModePermissions,
+			nouns:       cfg.DefaultNouns,
+			verbs:       cfg.DefaultVerbs,
+			concurrency: 1,
+		})
+
+		space, err := spaceResource(ctx, &client.ConfluenceSpace{Id: "100", Key: "ENG", Name: "Engineering"}, nil, "")
+		require.Nil(t, err)
+		alice, err := userResource(ctx, &client.ConfluenceUser{AccountId: "alice", DisplayName: "Alice Doe"}, nil)
+		require.Nil(t, err)
+
+		archiveComment := entitlement.NewPermissionEntitlement(space, "archive-comment")
+		_, _, err = c.Grant(ctx, alice, archiveComment)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = c.Revoke(ctx, &v2.Grant{Entitlement: archiveComment, Principal: alice})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		updatePage := entitlement.NewPermissionEntitlement(space, "update-page")
+		_, _, err = c.Grant(ctx, alice, updatePage)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
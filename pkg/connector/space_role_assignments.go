@@ -10,6 +10,8 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	grantSdk "github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -26,25 +28,7 @@ type spaceRoleListPageToken struct {
 }
 
 type spaceRoleAssignmentBuilder struct {
-	client    *client.ConfluenceClient
-	roleNames map[string]string
-}
-
-func (b *spaceRoleAssignmentBuilder) loadRoleNames(ctx context.Context) error {
-	// If we have 4 or more role names, we can assume we have all the role names we need.
-	if len(b.roleNames) >= 4 {
-		return nil
-	}
-	b.roleNames = make(map[string]string)
-	// There can be only up to 14 roles, 4 defaults + 10 custom roles, so 1 call is enough to fetch them all.
-	roles, _, _, err := b.client.GetSpaceRoles(ctx, "", "", ResourcesPageSize)
-	if err != nil {
-		return fmt.Errorf("confluence-connector: failed to fetch space role names: %w", err)
-	}
-	for _, r := range roles {
-		b.roleNames[r.Id] = r.Name
-	}
-	return nil
+	client *client.ConfluenceClient
 }
 
 func (b *spaceRoleAssignmentBuilder) ResourceType(_ context.Context) *v2.ResourceType {
@@ -70,16 +54,14 @@ func (b *spaceRoleAssignmentBuilder) List(
 		}
 	}
 
-	assignments, nextCursor, rateLimitData, err := b.client.GetSpaceRoleAssignments(
-		ctx, spaceID, "", "", "", pageToken.Cursor, ResourcesPageSize,
-	)
+	// Every role available in the space gets a binding, whether or not
+	// anyone holds it, so that any role on any space can be requested.
+	roles, nextCursor, rateLimitData, err := b.client.SpaceRoles(spaceID).
+		WithPageSize(ResourcesPageSize).
+		Page(ctx, pageToken.Cursor)
 	outputAnnotations := WithRateLimitAnnotations(rateLimitData)
 	if err != nil {
-		return nil, syncResults("", outputAnnotations), fmt.Errorf("confluence-connector: failed to list space role assignments: %w", err)
-	}
-
-	if err := b.loadRoleNames(ctx); err != nil {
-		return nil, syncResults("", outputAnnotations), err
+		return nil, syncResults("", outputAnnotations), fmt.Errorf("confluence-connector: failed to list space roles: %w", err)
 	}
 
 	// Use the space name cached in the token when available (pages 2+).
@@ -93,20 +75,13 @@ func (b *spaceRoleAssignmentBuilder) List(
 		}
 	}
 
-	// Deduplicate by roleId within this page — multiple principals share a roleId.
-	// Across pages, C1 deduplicates by resource ID.
-	seen := make(map[string]bool)
-	var resources []*v2.Resource
-	for _, assignment := range assignments {
-		if seen[assignment.RoleId] {
-			continue
-		}
-		seen[assignment.RoleId] = true
-		roleName := b.roleNames[assignment.RoleId]
+	resources := make([]*v2.Resource, 0, len(roles))
+	for _, role := range roles {
+		roleName := role.Name
 		if roleName == "" {
-			roleName = assignment.RoleId
+			roleName = role.Id
 		}
-		r, err := spaceRoleAssignmentResource(assignment.RoleId, parentResourceID, roleName, spaceName)
+		r, err := spaceRoleAssignmentResource(role.Id, parentResourceID, roleName, spaceName)
 		if err != nil {
 			return nil, syncResults("", outputAnnotations), err
 		}
@@ -134,7 +109,7 @@ func (b *spaceRoleAssignmentBuilder) StaticEntitlements(
 		entitlement.NewAssignmentEntitlement(
 			nil,
 			spaceRoleAssignmentEntitlement,
-			entitlement.WithGrantableTo(resourceTypeUser, resourceTypeGroup),
+			entitlement.WithGrantableTo(resourceTypeUser, resourceTypeGroup, accessClassResourceType),
 		),
 	}, syncResults("", nil), nil
 }
@@ -163,9 +138,9 @@ func (b *spaceRoleAssignmentBuilder) Grants(
 	spaceID := scopeTrait.GetScopeResourceId().GetResource()
 	roleID := scopeTrait.GetRoleId().GetResource()
 
-	assignments, nextCursor, rateLimitData, err := b.client.GetSpaceRoleAssignments(
-		ctx, spaceID, roleID, "", "", opts.PageToken.Token, opts.PageToken.Size,
-	)
+	assignments, nextCursor, rateLimitData, err := b.client.SpaceRoleAssignments(spaceID, roleID, "", "").
+		WithPageSize(opts.PageToken.Size).
+		Page(ctx, opts.PageToken.Token)
 	outputAnnotations := WithRateLimitAnnotations(rateLimitData)
 	if err != nil {
 		return nil, syncResults("", outputAnnotations), fmt.Errorf("confluence-connector: failed to list space role assignments: %w", err)
@@ -173,32 +148,9 @@ func (b *spaceRoleAssignmentBuilder) Grants(
 
 	var grants []*v2.Grant
 	for _, assignment := range assignments {
-		var resourceType string
-		var grantOpts []grantSdk.GrantOption
-
-		switch assignment.Principal.PrincipalType {
-		case "USER":
-			resourceType = resourceTypeUser.Id
-		case "GROUP":
-			resourceType = resourceTypeGroup.Id
-			grantOpts = append(grantOpts, grantSdk.WithAnnotation(&v2.GrantExpandable{
-				EntitlementIds: []string{
-					fmt.Sprintf("group:%s:member", assignment.Principal.PrincipalId),
-				},
-			}))
-		default:
-			continue
+		if g := roleAssignmentGrant(ctx, res, assignment); g != nil {
+			grants = append(grants, g)
 		}
-
-		grants = append(grants, grantSdk.NewGrant(
-			res,
-			spaceRoleAssignmentEntitlement,
-			&v2.ResourceId{
-				ResourceType: resourceType,
-				Resource:     assignment.Principal.PrincipalId,
-			},
-			grantOpts...,
-		))
 	}
 	return grants, syncResults(nextCursor, outputAnnotations), nil
 }
@@ -228,7 +180,9 @@ func (b *spaceRoleAssignmentBuilder) Grant(
 	}
 	principalID := principal.Id.Resource
 
-	existing, _, _, err := b.client.GetSpaceRoleAssignments(ctx, spaceID, roleID, principalID, principalType, "", 1)
+	existing, _, _, err := b.client.SpaceRoleAssignments(spaceID, roleID, principalID, principalType).
+		WithPageSize(1).
+		Page(ctx, "")
 	if err != nil {
 		return nil, nil, fmt.Errorf("confluence-connector: failed to check existing role assignments: %w", err)
 	}
@@ -281,7 +235,9 @@ func (b *spaceRoleAssignmentBuilder) Revoke(
 	}
 	principalID := grant.Principal.Id.Resource
 
-	existing, _, _, err := b.client.GetSpaceRoleAssignments(ctx, spaceID, roleID, principalID, principalType, "", 1)
+	existing, _, _, err := b.client.SpaceRoleAssignments(spaceID, roleID, principalID, principalType).
+		WithPageSize(1).
+		Page(ctx, "")
 	if err != nil {
 		return nil, fmt.Errorf("confluence-connector: failed to check existing role assignments: %w", err)
 	}
@@ -309,6 +265,46 @@ func (b *spaceRoleAssignmentBuilder) Revoke(
 	return outputAnnotations, nil
 }
 
+// roleAssignmentGrant turns a role assignment into a grant of the binding of
+// the role on the space, or nil if the principal is not synced.
+func roleAssignmentGrant(ctx context.Context, res *v2.Resource, assignment client.SpaceRoleAssignment) *v2.Grant {
+	var resourceType string
+	var grantOpts []grantSdk.GrantOption
+
+	switch assignment.Principal.PrincipalType {
+	case "USER":
+		resourceType = resourceTypeUser.Id
+	case "GROUP":
+		resourceType = resourceTypeGroup.Id
+		grantOpts = append(grantOpts, grantSdk.WithAnnotation(&v2.GrantExpandable{
+			EntitlementIds: []string{
+				fmt.Sprintf("group:%s:member", assignment.Principal.PrincipalId),
+			},
+		}))
+	case "ACCESS_CLASS":
+		if !isAccessClass(assignment.Principal.PrincipalId) {
+			ctxzap.Extract(ctx).Warn(
+				"confluence-connector: skipping role assignment to an unknown access class",
+				zap.String("access_class", assignment.Principal.PrincipalId),
+			)
+			return nil
+		}
+		resourceType = accessClassResourceType.Id
+	default:
+		return nil
+	}
+
+	return grantSdk.NewGrant(
+		res,
+		spaceRoleAssignmentEntitlement,
+		&v2.ResourceId{
+			ResourceType: resourceType,
+			Resource:     assignment.Principal.PrincipalId,
+		},
+		grantOpts...,
+	)
+}
+
 func spaceRoleAssignmentResource(roleId string, spaceResourceID *v2.ResourceId, roleName, spaceName string) (*v2.Resource, error) {
 	return rs.NewScopeBindingResource(
 		fmt.Sprintf("%s on %s", roleName, spaceName),
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/stretchr/testify/require"
 
@@ -100,3 +101,143 @@ func TestSpaceRoleAssignments(t *testing.T) {
 		require.Equal(t, resourceTypeGroup.Id, grants[1].Principal.Id.ResourceType)
 	})
 }
+
+func TestSpaceRoleAssignmentProvisioning(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	b := newSpaceRoleAssignmentBuilder(confluenceClient)
+
+	spaceResourceID := &v2.ResourceId{
+		ResourceType: spaceResourceType.Id,
+		Resource:     "100",
+	}
+	binding, err := spaceRoleAssignmentResource("role-viewer", spaceResourceID, "Viewer", "Engineering")
+	require.Nil(t, err)
+	assigned := entitlement.NewAssignmentEntitlement(binding, spaceRoleAssignmentEntitlement)
+	alice, err := userResource(ctx, &client.ConfluenceUser{AccountId: "alice", DisplayName: "Alice Doe"}, nil)
+	require.Nil(t, err)
+
+	aliceAssignment := client.SpaceRoleAssignment{
+		Principal: client.SpaceRoleAssignmentPrincipal{PrincipalType: "USER", PrincipalId: "alice"},
+		RoleId:    "role-viewer",
+	}
+
+	t.Run("should assign a space role", func(t *testing.T) {
+		grants, annos, err := b.Grant(ctx, alice, assigned)
+		require.Nil(t, err)
+		require.Len(t, grants, 1)
+		require.False(t, annos.Contains(&v2.GrantAlreadyExists{}))
+		require.Contains(t, site.SpaceRoleAssignments("100"), aliceAssignment)
+
+		grants, _, err = b.Grants(ctx, binding, rs.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Len(t, grants, 2)
+	})
+
+	t.Run("should report an existing assignment", func(t *testing.T) {
+		grants, annos, err := b.Grant(ctx, alice, assigned)
+		require.Nil(t, err)
+		require.Empty(t, grants)
+		require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	})
+
+	t.Run("should remove a space role assignment", func(t *testing.T) {
+		grant := &v2.Grant{Entitlement: assigned, Principal: alice}
+		annos, err := b.Revoke(ctx, grant)
+		require.Nil(t, err)
+		require.False(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+		require.NotContains(t, site.SpaceRoleAssignments("100"), aliceAssignment)
+
+		annos, err = b.Revoke(ctx, grant)
+		require.Nil(t, err)
+		require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+	})
+}
+
+func TestSpaceRoleAssignmentsListEveryRole(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	b := newSpaceRoleAssignmentBuilder(confluenceClient)
+
+	// Nobody holds a role on this space.
+	site.AddSpace(client.ConfluenceSpace{Id: "300", Key: "EMPTY", Name: "Empty"})
+	spaceResourceID := &v2.ResourceId{
+		ResourceType: spaceResourceType.Id,
+		Resource:     "300",
+	}
+
+	resources, results, err := b.List(ctx, spaceResourceID, rs.SyncOpAttrs{})
+	require.Nil(t, err)
+	require.Equal(t, "", results.NextPageToken)
+	require.Len(t, resources, len(site.SpaceRoles()))
+
+	ids := make([]string, 0, len(resources))
+	for _, res := range resources {
+		ids = append(ids, res.Id.Resource)
+	}
+	require.Contains(t, ids, "300:role-admin")
+	require.Contains(t, ids, "300:role-no-access")
+	require.Equal(t, "Admin on Empty", resources[2].DisplayName)
+}
+
+func TestSpaceRoleAssignmentsToAccessClasses(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	b := newSpaceRoleAssignmentBuilder(confluenceClient)
+
+	spaceResourceID := &v2.ResourceId{
+		ResourceType: spaceResourceType.Id,
+		Resource:     "100",
+	}
+	binding, err := spaceRoleAssignmentResource("role-collaborator", spaceResourceID, "Collaborator", "Engineering")
+	require.Nil(t, err)
+	assigned := entitlement.NewAssignmentEntitlement(binding, spaceRoleAssignmentEntitlement)
+
+	classes, _, err := newAccessClassBuilder().List(ctx, nil, rs.SyncOpAttrs{})
+	require.Nil(t, err)
+	var allLicensedUsers *v2.Resource
+	for _, class := range classes {
+		if class.Id.Resource == "all-licensed-users" {
+			allLicensedUsers = class
+		}
+	}
+	require.NotNil(t, allLicensedUsers)
+
+	t.Run("should sync grants to access classes", func(t *testing.T) {
+		grants, _, err := b.Grants(ctx, binding, rs.SyncOpAttrs{})
+		require.Nil(t, err)
+
+		var principals []*v2.ResourceId
+		for _, g := range grants {
+			principals = append(principals, g.Principal.Id)
+		}
+		require.Contains(t, principals, allLicensedUsers.Id)
+	})
+
+	t.Run("should remove the access of an access class", func(t *testing.T) {
+		grant := &v2.Grant{Entitlement: assigned, Principal: allLicensedUsers}
+		annos, err := b.Revoke(ctx, grant)
+		require.Nil(t, err)
+		require.False(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+		for _, assignment := range site.SpaceRoleAssignments("100") {
+			require.NotEqual(t, "ACCESS_CLASS", assignment.Principal.PrincipalType)
+		}
+
+		grants, annos, err := b.Grant(ctx, allLicensedUsers, assigned)
+		require.Nil(t, err)
+		require.Len(t, grants, 1)
+		require.False(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	})
+}
@@ -7,10 +7,21 @@ import (
 	"net/http"
 
 	"github.com/conductorone/baton-confluence/pkg/connector/client"
+	config "github.com/conductorone/baton-sdk/pb/c1/config/v1"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	updateSpaceRolePermissionsAction = "update_space_role_permissions"
+	spacePermissionsProfileKey       = "space_permissions"
 )
 
 type spaceRoleBuilder struct {
@@ -30,12 +41,9 @@ func (b *spaceRoleBuilder) List(
 		return nil, nil, nil
 	}
 
-	roles, nextCursor, rateLimitData, err := b.client.GetSpaceRoles(
-		ctx,
-		"",
-		opts.PageToken.Token,
-		ResourcesPageSize,
-	)
+	roles, nextCursor, rateLimitData, err := b.client.SpaceRoles("").
+		WithPageSize(ResourcesPageSize).
+		Page(ctx, opts.PageToken.Token)
 	outputAnnotations := WithRateLimitAnnotations(rateLimitData)
 	if err != nil {
 		var reqErr *client.RequestError
@@ -65,6 +73,164 @@ func (b *spaceRoleBuilder) Grants(_ context.Context, _ *v2.Resource, _ rs.SyncOp
 	return nil, nil, nil
 }
 
+// Create defines a custom space role named after the resource. The space
+// permissions it bundles are read from the space_permissions profile field.
+func (b *spaceRoleBuilder) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	if resource.GetDisplayName() == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "confluence-connector: space role name is required")
+	}
+
+	var permissions []string
+	if profile := rs.GetProfile(resource); profile != nil {
+		for _, value := range profile.GetFields()[spacePermissionsProfileKey].GetListValue().GetValues() {
+			permissions = append(permissions, value.GetStringValue())
+		}
+	}
+	err := validateRolePermissions(permissions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	role, ratelimitData, err := b.client.CreateSpaceRole(ctx, client.SpaceRoleRequest{
+		Name:             resource.GetDisplayName(),
+		Description:      resource.GetDescription(),
+		SpacePermissions: permissions,
+	})
+	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+	if err != nil {
+		return nil, outputAnnotations, fmt.Errorf("confluence-connector: failed to create space role: %w", err)
+	}
+
+	created, err := spaceRoleResource(*role)
+	if err != nil {
+		return nil, outputAnnotations, err
+	}
+	return created, outputAnnotations, nil
+}
+
+// Delete deletes a custom space role. Default roles cannot be deleted.
+func (b *spaceRoleBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId, _ *v2.ResourceId) (annotations.Annotations, error) {
+	role, ratelimitData, err := b.customSpaceRole(ctx, resourceId.GetResource())
+	if err != nil {
+		return WithRateLimitAnnotations(ratelimitData), err
+	}
+
+	ratelimitData, err = b.client.DeleteSpaceRole(ctx, role.Id)
+	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+	if err != nil {
+		return outputAnnotations, fmt.Errorf("confluence-connector: failed to delete space role: %w", err)
+	}
+	return outputAnnotations, nil
+}
+
+// ResourceActions registers the action that changes which space permissions a
+// custom role bundles.
+func (b *spaceRoleBuilder) ResourceActions(ctx context.Context, registry actions.ActionRegistry) error {
+	return registry.Register(ctx, &v2.BatonActionSchema{
+		Name:        updateSpaceRolePermissionsAction,
+		DisplayName: "Update space role permissions",
+		Description: "Replace the space permissions bundled by a custom space role",
+		Arguments: []*config.Field{
+			config.Field_builder{
+				Name:            "resource_id",
+				DisplayName:     "Space role",
+				IsRequired:      true,
+				ResourceIdField: &config.ResourceIdField{},
+			}.Build(),
+			config.Field_builder{
+				Name:             spacePermissionsProfileKey,
+				DisplayName:      "Space permissions",
+				Description:      "The space permissions of the role, as <operation>-<target> slugs such as read-space",
+				IsRequired:       true,
+				StringSliceField: &config.StringSliceField{},
+			}.Build(),
+		},
+		ReturnTypes: []*config.Field{
+			config.Field_builder{
+				Name:          "resource",
+				DisplayName:   "Space role",
+				ResourceField: &config.ResourceField{},
+			}.Build(),
+		},
+	}, b.updateSpaceRolePermissions)
+}
+
+func (b *spaceRoleBuilder) updateSpaceRolePermissions(
+	ctx context.Context,
+	args *structpb.Struct,
+) (*structpb.Struct, annotations.Annotations, error) {
+	resourceId, err := actions.RequireResourceIDArg(args, "resource_id")
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	permissions, ok := actions.GetStringSliceArg(args, spacePermissionsProfileKey)
+	if !ok {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "confluence-connector: missing %s argument", spacePermissionsProfileKey)
+	}
+	err = validateRolePermissions(permissions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	role, ratelimitData, err := b.customSpaceRole(ctx, resourceId.GetResource())
+	if err != nil {
+		return nil, WithRateLimitAnnotations(ratelimitData), err
+	}
+
+	role, ratelimitData, err = b.client.UpdateSpaceRole(ctx, role.Id, client.SpaceRoleRequest{
+		Name:             role.Name,
+		Description:      role.Description,
+		SpacePermissions: permissions,
+	})
+	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+	if err != nil {
+		return nil, outputAnnotations, fmt.Errorf("confluence-connector: failed to update space role: %w", err)
+	}
+
+	updated, err := spaceRoleResource(*role)
+	if err != nil {
+		return nil, outputAnnotations, err
+	}
+	returnField, err := actions.NewResourceReturnField("resource", updated)
+	if err != nil {
+		return nil, outputAnnotations, err
+	}
+	return actions.NewReturnValues(true, returnField), outputAnnotations, nil
+}
+
+// customSpaceRole fetches a role and refuses Confluence's default roles,
+// which cannot be changed.
+func (b *spaceRoleBuilder) customSpaceRole(ctx context.Context, roleId string) (*client.SpaceRole, *v2.RateLimitDescription, error) {
+	role, ratelimitData, err := b.client.GetSpaceRole(ctx, roleId)
+	if err != nil {
+		var reqErr *client.RequestError
+		if errors.As(err, &reqErr) && reqErr.Status == http.StatusNotFound {
+			return nil, ratelimitData, status.Errorf(codes.NotFound, "confluence-connector: space role %s not found", roleId)
+		}
+		return nil, ratelimitData, fmt.Errorf("confluence-connector: failed to fetch space role: %w", err)
+	}
+	if role.Type != client.SpaceRoleTypeCustom {
+		return nil, ratelimitData, status.Errorf(
+			codes.FailedPrecondition,
+			"confluence-connector: %s is a default space role and cannot be modified",
+			role.Name,
+		)
+	}
+	return role, ratelimitData, nil
+}
+
+// validateRolePermissions checks the space permissions of a role against the
+// catalog.
+func validateRolePermissions(permissions []string) error {
+	for _, permission := range permissions {
+		_, _, err := catalogedSpacePermission(permission)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	return nil
+}
+
 func spaceRoleResource(role client.SpaceRole) (*v2.Resource, error) {
 	perms := make([]interface{}, len(role.SpacePermissions))
 	for i, p := range role.SpacePermissions {
@@ -77,8 +243,9 @@ func spaceRoleResource(role client.SpaceRole) (*v2.Resource, error) {
 		nil,
 		rs.WithDescription(role.Description),
 		rs.WithResourceProfile(map[string]interface{}{
-			"description":       role.Description,
-			"space_permissions": perms,
+			"description":              role.Description,
+			"role_type":                role.Type,
+			spacePermissionsProfileKey: perms,
 		}),
 	)
 }
@@ -0,0 +1,134 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+	"github.com/conductorone/baton-confluence/test"
+)
+
+// testActionRegistry records the handlers registered by a resource builder.
+type testActionRegistry struct {
+	handlers map[string]actions.ActionHandler
+}
+
+func (r *testActionRegistry) Register(_ context.Context, schema *v2.BatonActionSchema, handler actions.ActionHandler) error {
+	if r.handlers == nil {
+		r.handlers = make(map[string]actions.ActionHandler)
+	}
+	r.handlers[schema.GetName()] = handler
+	return nil
+}
+
+func (r *testActionRegistry) RegisterAction(ctx context.Context, _ string, schema *v2.BatonActionSchema, handler actions.ActionHandler) error {
+	return r.Register(ctx, schema, handler)
+}
+
+func updatePermissionsArgs(t *testing.T, roleId string, permissions ...interface{}) *structpb.Struct {
+	args, err := structpb.NewStruct(map[string]interface{}{
+		"resource_id": map[string]interface{}{
+			"resource_type_id": spaceRoleResourceType.Id,
+			"resource_id":      roleId,
+		},
+		spacePermissionsProfileKey: permissions,
+	})
+	require.Nil(t, err)
+	return args
+}
+
+func TestSpaceRoleManagement(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	b := newSpaceRoleBuilder(confluenceClient)
+
+	registry := &testActionRegistry{}
+	require.Nil(t, b.ResourceActions(ctx, registry))
+	updatePermissions := registry.handlers[updateSpaceRolePermissionsAction]
+	require.NotNil(t, updatePermissions)
+
+	var roleId string
+
+	t.Run("should create a custom space role", func(t *testing.T) {
+		role, err := rs.NewRoleResource(
+			"Editor",
+			spaceRoleResourceType,
+			"",
+			nil,
+			rs.WithDescription("Edit pages"),
+			rs.WithResourceProfile(map[string]interface{}{
+				spacePermissionsProfileKey: []interface{}{"read-space", "create-page"},
+			}),
+		)
+		require.Nil(t, err)
+
+		created, _, err := b.Create(ctx, role)
+		require.Nil(t, err)
+		require.NotEmpty(t, created.Id.Resource)
+		require.Equal(t, "Editor", created.DisplayName)
+		roleId = created.Id.Resource
+
+		stored, _, err := confluenceClient.GetSpaceRole(ctx, roleId)
+		require.Nil(t, err)
+		require.Equal(t, client.SpaceRoleTypeCustom, stored.Type)
+		require.Equal(t, []string{"read-space", "create-page"}, stored.SpacePermissions)
+	})
+
+	t.Run("should refuse permissions outside the catalog", func(t *testing.T) {
+		role, err := rs.NewRoleResource(
+			"Broken",
+			spaceRoleResourceType,
+			"",
+			nil,
+			rs.WithResourceProfile(map[string]interface{}{
+				spacePermissionsProfileKey: []interface{}{"create-space"},
+			}),
+		)
+		require.Nil(t, err)
+
+		_, _, err = b.Create(ctx, role)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("should update the permissions of a custom space role", func(t *testing.T) {
+		rv, _, err := updatePermissions(ctx, updatePermissionsArgs(t, roleId, "read-space", "export-space"))
+		require.Nil(t, err)
+		require.True(t, rv.Fields["success"].GetBoolValue())
+
+		stored, _, err := confluenceClient.GetSpaceRole(ctx, roleId)
+		require.Nil(t, err)
+		require.Equal(t, "Editor", stored.Name)
+		require.Equal(t, []string{"read-space", "export-space"}, stored.SpacePermissions)
+	})
+
+	t.Run("should protect default space roles", func(t *testing.T) {
+		_, _, err := updatePermissions(ctx, updatePermissionsArgs(t, "role-viewer", "read-space", "export-space"))
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, err = b.Delete(ctx, &v2.ResourceId{ResourceType: spaceRoleResourceType.Id, Resource: "role-viewer"}, nil)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("should delete a custom space role", func(t *testing.T) {
+		_, err := b.Delete(ctx, &v2.ResourceId{ResourceType: spaceRoleResourceType.Id, Resource: roleId}, nil)
+		require.Nil(t, err)
+		for _, role := range site.SpaceRoles() {
+			require.NotEqual(t, roleId, role.Id)
+		}
+
+		_, err = b.Delete(ctx, &v2.ResourceId{ResourceType: spaceRoleResourceType.Id, Resource: roleId}, nil)
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
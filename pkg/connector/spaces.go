@@ -3,66 +3,151 @@ package connector
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
+	config "github.com/conductorone/baton-sdk/pb/c1/config/v1"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	grantSdk "github.com/conductorone/baton-sdk/pkg/types/grant"
 	"github.com/conductorone/baton-sdk/pkg/types/resource"
 	mapset "github.com/deckarep/golang-set/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 
+	cfg "github.com/conductorone/baton-confluence/pkg/config"
 	"github.com/conductorone/baton-confluence/pkg/connector/client"
 )
 
-const separator = "-"
+const (
+	separator = "-"
+
+	archiveSpaceAction = "archive_space"
+	restoreSpaceAction = "restore_space"
+
+	// Profile fields read when a space is created.
+	spaceKeyProfileKey       = "key"
+	spaceRequesterProfileKey = "requester_account_id"
+
+	// spaceOwnerEntitlement is held by the creator of a space, or by the user
+	// a personal space belongs to.
+	spaceOwnerEntitlement = "owner"
+
+	spaceAuthorProfileKey = "author_id"
+	spaceOwnerProfileKey  = "owner_account_id"
+)
+
+// spaceKeyPattern matches valid space keys, which are letters and digits
+// only.
+var spaceKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]{1,255}$`)
 
 func createEntitlementName(verb, noun string) string {
 	return fmt.Sprintf("%s%s%s", verb, separator, noun)
 }
 
 // GetEntitlementComponents returns the operation and target in that order.
-func GetEntitlementComponents(operation string) (string, string) {
+func GetEntitlementComponents(operation string) (string, string, error) {
 	parts := strings.Split(operation, separator)
-	return parts[0], parts[1]
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("confluence-connector: invalid space permission entitlement: %s", operation)
+	}
+	return parts[0], parts[1], nil
 }
 
 type spaceBuilder struct {
-	client             *client.ConfluenceClient
-	skipPersonalSpaces bool
-	useRbac            bool
-	nouns              []string
-	verbs              []string
+	client *client.ConfluenceClient
+	filter *spaceFilter
+	// syncPermissions syncs granular space permissions as entitlements of the
+	// space and syncRoles its role assignments as child resources. Both are
+	// set for sites in transition to roles.
+	syncPermissions bool
+	syncRoles       bool
+	nouns           []string
+	verbs           []string
+	// discoverEntitlements builds the entitlements of each space from the
+	// operations Confluence reports for it instead of nouns × verbs.
+	discoverEntitlements bool
+	// discovered caches the permissions discovered for each space from the
+	// entitlement pass to the grant pass, so that grants are only emitted for
+	// entitlements that were.
+	discoveredMu sync.Mutex
+	discovered   map[string][]spacePermission
+	// syncContentRestrictions syncs the restricted pages and blog posts of
+	// each space as child resources.
+	syncContentRestrictions bool
+	permissions             *prefetcher[client.ConfluenceSpacePermission]
+	// listed holds the permissions of each space read while listing it, which
+	// tell whether anonymous users can view the space, until its grants are
+	// synced. Past maxListedSpacePermissions, permissions are not kept and
+	// are read again for the grants.
+	listedMu    sync.Mutex
+	listed      map[string][]client.ConfluenceSpacePermission
+	listedCount int
+}
+
+// spaceBuilderOptions are what the space builder syncs.
+type spaceBuilderOptions struct {
+	// accessMode is one of the cfg.SpaceAccessMode values, resolved.
+	accessMode              string
+	nouns                   []string
+	verbs                   []string
+	discoverEntitlements    bool
+	syncContentRestrictions bool
+	concurrency             int
 }
 
+// maxListedSpacePermissions bounds the space permissions kept from the space
+// listing to the grant pass.
+const maxListedSpacePermissions = 100000
+
 func (o *spaceBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return spaceResourceType
 }
 
-// List returns all the spaces as resource objects.
+// List returns the spaces that pass the space filter as resource objects.
 func (o *spaceBuilder) List(
 	ctx context.Context,
 	parentResourceID *v2.ResourceId,
 	opts resource.SyncOpAttrs,
 ) ([]*v2.Resource, *resource.SyncOpResults, error) {
-	spaces, nextToken, ratelimitData, err := o.client.GetSpaces(
-		ctx,
-		ResourcesPageSize,
-		opts.PageToken.Token,
-	)
+	if opts.PageToken.Token == "" {
+		// A new sync: drop the pages prefetched for the last one.
+		o.permissions.reset()
+		o.resetListedPermissions()
+	}
+	spaces, nextToken, ratelimitData, err := o.client.FilteredSpaces(o.filter.query()).
+		WithPageSize(ResourcesPageSize).
+		Page(ctx, opts.PageToken.Token)
 	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
 	if err != nil {
 		return nil, syncResults("", outputAnnotations), err
 	}
-	rv := make([]*v2.Resource, 0)
+	includedSpaces := make([]client.ConfluenceSpace, 0, len(spaces))
 	for _, space := range spaces {
-		spaceCopy := space
-		if o.skipPersonalSpaces && spaceCopy.Type == "personal" {
+		included, ratelimitData, err := o.filter.includes(ctx, o.client, &space, true)
+		if err != nil {
+			return nil, syncResults("", WithRateLimitAnnotations(ratelimitData)), err
+		}
+		if !included {
 			continue
 		}
-		ur, err := spaceResource(ctx, &spaceCopy, o.useRbac)
+		includedSpaces = append(includedSpaces, space)
+		if o.syncPermissions {
+			o.permissions.expect(space.Id)
+		}
+	}
+
+	rv := make([]*v2.Resource, 0, len(includedSpaces))
+	for i := range includedSpaces {
+		ur, ratelimitData, err := o.spaceResource(ctx, &includedSpaces[i])
 		if err != nil {
-			return nil, nil, err
+			return nil, syncResults("", WithRateLimitAnnotations(ratelimitData)), err
 		}
 		rv = append(rv, ur)
 	}
@@ -70,77 +155,335 @@ func (o *spaceBuilder) List(
 	return rv, syncResults(nextToken, outputAnnotations), nil
 }
 
+// spaceResource builds the resource of a space, checking whether anonymous
+// users can view it when its permissions are synced, and who owns it when it
+// is a personal space.
+func (o *spaceBuilder) spaceResource(ctx context.Context, space *client.ConfluenceSpace) (*v2.Resource, *v2.RateLimitDescription, error) {
+	var anonymousRead *bool
+	var ratelimitData *v2.RateLimitDescription
+	var err error
+	if o.syncPermissions {
+		var permissions []client.ConfluenceSpacePermission
+		permissions, ratelimitData, err = o.listPermissions(ctx, space.Id)
+		if err != nil {
+			return nil, ratelimitData, err
+		}
+		allowed := allowsAnonymousRead(permissions)
+		anonymousRead = &allowed
+	}
+
+	var ownerId string
+	if space.Type == spaceTypePersonal {
+		ownerId, ratelimitData, err = o.client.GetPersonalSpaceOwner(ctx, space)
+		if err != nil {
+			return nil, ratelimitData, fmt.Errorf("confluence-connector: failed to get the owner of space %s: %w", space.Key, err)
+		}
+	}
+
+	r, err := spaceResource(ctx, space, anonymousRead, ownerId, o.childResourceTypes()...)
+	return r, ratelimitData, err
+}
+
+// listPermissions reads every permission of a space while it is listed, and
+// keeps them for its grants while there is room.
+func (o *spaceBuilder) listPermissions(
+	ctx context.Context,
+	spaceId string,
+) ([]client.ConfluenceSpacePermission, *v2.RateLimitDescription, error) {
+	var permissions []client.ConfluenceSpacePermission
+	var ratelimitData *v2.RateLimitDescription
+	pageToken := ""
+	for {
+		var page []client.ConfluenceSpacePermission
+		var err error
+		page, pageToken, ratelimitData, err = o.permissions.page(ctx, spaceId, pageToken, ResourcesPageSize)
+		if err != nil {
+			return nil, ratelimitData, err
+		}
+		permissions = append(permissions, page...)
+		if pageToken == "" {
+			break
+		}
+	}
+
+	o.listedMu.Lock()
+	defer o.listedMu.Unlock()
+	if o.listedCount+len(permissions) <= maxListedSpacePermissions {
+		o.listed[spaceId] = permissions
+		o.listedCount += len(permissions)
+	}
+	return permissions, ratelimitData, nil
+}
+
+// takeListedPermissions returns the permissions of a space kept from its
+// listing, and forgets them.
+func (o *spaceBuilder) takeListedPermissions(spaceId string) ([]client.ConfluenceSpacePermission, bool) {
+	o.listedMu.Lock()
+	defer o.listedMu.Unlock()
+	permissions, ok := o.listed[spaceId]
+	if ok {
+		delete(o.listed, spaceId)
+		o.listedCount -= len(permissions)
+	}
+	return permissions, ok
+}
+
+func (o *spaceBuilder) resetListedPermissions() {
+	o.listedMu.Lock()
+	defer o.listedMu.Unlock()
+	o.listed = make(map[string][]client.ConfluenceSpacePermission)
+	o.listedCount = 0
+}
+
+// allowsAnonymousRead reports whether the permissions of a space let
+// anonymous users view it.
+func allowsAnonymousRead(permissions []client.ConfluenceSpacePermission) bool {
+	for _, permission := range permissions {
+		if permission.Principal.Type == client.SpacePermissionPrincipalAnonymous &&
+			permission.Operation.Key == "read" &&
+			permission.Operation.TargetType == "space" {
+			return true
+		}
+	}
+	return false
+}
+
+// childResourceTypes returns the resource types synced under each space.
+func (o *spaceBuilder) childResourceTypes() []*v2.ResourceType {
+	var rv []*v2.ResourceType
+	if o.syncRoles {
+		rv = append(rv, spaceRoleAssignmentResourceType)
+	}
+	if o.syncContentRestrictions {
+		rv = append(rv, pageResourceType, blogpostResourceType)
+	}
+	return rv
+}
+
+// Entitlements returns the owner entitlement of the space, followed by its
+// space permissions when they are synced.
 func (o *spaceBuilder) Entitlements(
 	ctx context.Context,
 	res *v2.Resource,
-	_ resource.SyncOpAttrs,
+	opts resource.SyncOpAttrs,
 ) ([]*v2.Entitlement, *resource.SyncOpResults, error) {
-	if o.useRbac {
-		return nil, nil, nil
+	entitlements := make([]*v2.Entitlement, 0)
+	if opts.PageToken.Token == "" {
+		entitlements = append(entitlements, spaceOwnerEntitlementFor(res))
 	}
 
-	entitlements := make([]*v2.Entitlement, 0)
+	if !o.syncPermissions {
+		return entitlements, syncResults("", nil), nil
+	}
+
+	if o.discoverEntitlements {
+		discovered, ratelimitData, err := o.discoveredPermissions(ctx, res.Id.Resource)
+		outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+		if err != nil {
+			return nil, syncResults("", outputAnnotations), err
+		}
+		for _, permission := range discovered {
+			entitlements = append(entitlements, spacePermissionEntitlement(res, permission.operation, permission.target))
+		}
+		return entitlements, syncResults("", outputAnnotations), nil
+	}
 
 	for _, noun := range o.nouns {
 		for _, verb := range o.verbs {
-			operationName := createEntitlementName(verb, noun)
-			entitlements = append(
-				entitlements,
-				entitlement.NewPermissionEntitlement(
-					res,
-					operationName,
-					entitlement.WithGrantableTo(resourceTypeUser),
-					entitlement.WithGrantableTo(resourceTypeGroup),
-					entitlement.WithDisplayName(
-						fmt.Sprintf("Can %s %s", operationName, res.DisplayName),
-					),
-					entitlement.WithDescription(
-						fmt.Sprintf(
-							"Has permission to %s %s the %s space in Confluence",
-							verb,
-							noun,
-							res.DisplayName,
-						),
-					),
-				))
+			if !isSyncedSpacePermission(verb, noun) {
+				continue
+			}
+			entitlements = append(entitlements, spacePermissionEntitlement(res, verb, noun))
 		}
 	}
 
 	return entitlements, syncResults("", nil), nil
 }
 
+// discoveredPermissions returns the space permissions Confluence reports for
+// the space. Their slugs are the same as those of the noun and verb cross
+// product, so switching modes keeps existing grants.
+//
+// Confluence reports the operations the connector's account can perform in
+// the space, which are all of them for an admin, so they are filtered through
+// the catalog to keep the ones that can be provisioned.
+func (o *spaceBuilder) discoveredPermissions(
+	ctx context.Context,
+	spaceId string,
+) ([]spacePermission, *v2.RateLimitDescription, error) {
+	o.discoveredMu.Lock()
+	discovered, ok := o.discovered[spaceId]
+	o.discoveredMu.Unlock()
+	if ok {
+		return discovered, nil, nil
+	}
+
+	operations := o.client.SpaceOperations(spaceId)
+	discovered = make([]spacePermission, 0)
+	for operation, err := range operations.All(ctx) {
+		if err != nil {
+			return nil, operations.RateLimit(), err
+		}
+		permission := spacePermission{operation: operation.Operation, target: operation.TargetType}
+		if !isCatalogedSpacePermission(permission.operation, permission.target) || slices.Contains(discovered, permission) {
+			continue
+		}
+		discovered = append(discovered, permission)
+	}
+
+	o.discoveredMu.Lock()
+	o.discovered[spaceId] = discovered
+	o.discoveredMu.Unlock()
+	return discovered, operations.RateLimit(), nil
+}
+
+// forgetDiscoveredPermissions drops the cached permissions of a space once
+// its grants are synced.
+func (o *spaceBuilder) forgetDiscoveredPermissions(spaceId string) {
+	o.discoveredMu.Lock()
+	defer o.discoveredMu.Unlock()
+	delete(o.discovered, spaceId)
+}
+
+// spaceOwnerEntitlementFor is the entitlement of the user who created the
+// space or whose personal space it is. It reflects history rather than
+// access, so it cannot be granted.
+func spaceOwnerEntitlementFor(res *v2.Resource) *v2.Entitlement {
+	return entitlement.NewAssignmentEntitlement(
+		res,
+		spaceOwnerEntitlement,
+		entitlement.WithGrantableTo(resourceTypeUser),
+		entitlement.WithDisplayName(fmt.Sprintf("%s Space Owner", res.DisplayName)),
+		entitlement.WithDescription(fmt.Sprintf("Created the %s space in Confluence", res.DisplayName)),
+	)
+}
+
+// spaceOwnerGrants grants the owner entitlement to the owner of a personal
+// space, or else to the author of the space, as recorded in its profile.
+func spaceOwnerGrants(res *v2.Resource) []*v2.Grant {
+	profile := resource.GetProfile(res)
+	ownerId, _ := resource.GetProfileStringValue(profile, spaceOwnerProfileKey)
+	if ownerId == "" {
+		ownerId, _ = resource.GetProfileStringValue(profile, spaceAuthorProfileKey)
+	}
+	if ownerId == "" {
+		return nil
+	}
+	return []*v2.Grant{
+		grantSdk.NewGrant(res, spaceOwnerEntitlement, &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: ownerId}),
+	}
+}
+
+func spacePermissionEntitlement(res *v2.Resource, verb string, noun string) *v2.Entitlement {
+	operationName := createEntitlementName(verb, noun)
+	return entitlement.NewPermissionEntitlement(
+		res,
+		operationName,
+		entitlement.WithGrantableTo(resourceTypeUser),
+		entitlement.WithGrantableTo(resourceTypeGroup),
+		entitlement.WithDisplayName(
+			fmt.Sprintf("Can %s %s", operationName, res.DisplayName),
+		),
+		entitlement.WithDescription(
+			fmt.Sprintf(
+				"Has permission to %s %s the %s space in Confluence",
+				verb,
+				noun,
+				res.DisplayName,
+			),
+		),
+	)
+}
+
 // checkSpacePermission checks if the operation is in the list of operations we care about.
 func checkSpacePermission(nouns mapset.Set[string], verbs mapset.Set[string], operation, targetType string) bool {
-	return verbs.Contains(operation) && nouns.Contains(targetType)
+	return verbs.Contains(operation) && nouns.Contains(targetType) && isSyncedSpacePermission(operation, targetType)
 }
 
+// Grants returns the owner of the space, followed by the granted space
+// permissions when they are synced. The permissions read while listing the
+// space are returned in one page; otherwise they are read page by page.
 func (o *spaceBuilder) Grants(
 	ctx context.Context,
 	res *v2.Resource,
 	opts resource.SyncOpAttrs,
 ) ([]*v2.Grant, *resource.SyncOpResults, error) {
-	if o.useRbac {
-		return nil, nil, nil
+	var grants []*v2.Grant
+	if opts.PageToken.Token == "" {
+		grants = spaceOwnerGrants(res)
+	}
+
+	if !o.syncPermissions {
+		return grants, syncResults("", nil), nil
+	}
+
+	emitted, ratelimitData, err := o.emittedPermissions(ctx, res.Id.Resource)
+	if err != nil {
+		return nil, syncResults("", WithRateLimitAnnotations(ratelimitData)), err
+	}
+
+	if opts.PageToken.Token == "" {
+		if listed, ok := o.takeListedPermissions(res.Id.Resource); ok {
+			if o.discoverEntitlements {
+				o.forgetDiscoveredPermissions(res.Id.Resource)
+			}
+			return append(grants, permissionGrants(res, listed, emitted)...), syncResults("", WithRateLimitAnnotations(ratelimitData)), nil
+		}
 	}
 
-	permissionsList, nextToken, ratelimitData, err := o.client.GetSpacePermissions(
+	permissionsList, nextToken, ratelimitData, err := o.permissions.page(
 		ctx,
+		res.Id.Resource,
 		opts.PageToken.Token,
 		opts.PageToken.Size,
-		res.Id.Resource,
 	)
 	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
 	if err != nil {
 		return nil, syncResults("", outputAnnotations), err
 	}
+	if nextToken == "" && o.discoverEntitlements {
+		o.forgetDiscoveredPermissions(res.Id.Resource)
+	}
+
+	return append(grants, permissionGrants(res, permissionsList, emitted)...), syncResults(nextToken, outputAnnotations), nil
+}
 
-	nounsSet := mapset.NewSet(o.nouns...)
-	verbsSet := mapset.NewSet(o.verbs...)
+// emittedPermissions returns whether a space permission has an entitlement on
+// the space: when it is in the configured nouns and verbs or, with discovery,
+// when it was discovered for the space.
+func (o *spaceBuilder) emittedPermissions(
+	ctx context.Context,
+	spaceId string,
+) (func(operation string, target string) bool, *v2.RateLimitDescription, error) {
+	if !o.discoverEntitlements {
+		nounsSet := mapset.NewSet(o.nouns...)
+		verbsSet := mapset.NewSet(o.verbs...)
+		return func(operation string, target string) bool {
+			return checkSpacePermission(nounsSet, verbsSet, operation, target)
+		}, nil, nil
+	}
 
+	discovered, ratelimitData, err := o.discoveredPermissions(ctx, spaceId)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	return func(operation string, target string) bool {
+		return slices.Contains(discovered, spacePermission{operation: operation, target: target})
+	}, ratelimitData, nil
+}
+
+// permissionGrants turns the permissions of a space into grants, keeping the
+// ones that have an entitlement.
+func permissionGrants(
+	res *v2.Resource,
+	permissions []client.ConfluenceSpacePermission,
+	emitted func(operation string, target string) bool,
+) []*v2.Grant {
 	var grants []*v2.Grant
-	for _, permission := range permissionsList {
+	for _, permission := range permissions {
 		var grantOpts []grantSdk.GrantOption
 		var resourceType string
+		principalId := permission.Principal.Id
 		switch permission.Principal.Type {
 		case resourceTypeUserID:
 			resourceType = resourceTypeUser.Id
@@ -151,21 +494,24 @@ func (o *spaceBuilder) Grants(
 					fmt.Sprintf("group:%s:member", permission.Principal.Id),
 				},
 			}))
+		case client.SpacePermissionPrincipalAnonymous:
+			resourceType = accessClassResourceType.Id
+			principalId = anonymousAccessClassID
 		default:
 			continue
 		}
-		if !checkSpacePermission(nounsSet, verbsSet, permission.Operation.Key, permission.Operation.TargetType) {
+		if !emitted(permission.Operation.Key, permission.Operation.TargetType) {
 			continue
 		}
 		grants = append(grants, grantSdk.NewGrant(
 			res,
 			createEntitlementName(permission.Operation.Key, permission.Operation.TargetType),
-			&v2.ResourceId{ResourceType: resourceType, Resource: permission.Principal.Id},
+			&v2.ResourceId{ResourceType: resourceType, Resource: principalId},
 			grantOpts...,
 		))
 	}
 
-	return grants, syncResults(nextToken, outputAnnotations), nil
+	return grants
 }
 
 func (o *spaceBuilder) Grant(
@@ -173,11 +519,24 @@ func (o *spaceBuilder) Grant(
 	principal *v2.Resource,
 	ent *v2.Entitlement,
 ) ([]*v2.Grant, annotations.Annotations, error) {
-	spaceName := ent.Resource.Id.Resource
-	key, target := GetEntitlementComponents(ent.Slug)
+	if ent.Slug == spaceOwnerEntitlement {
+		return nil, nil, status.Error(codes.InvalidArgument, "confluence-connector: the owner of a space cannot be changed")
+	}
+	if principal.Id.ResourceType == accessClassResourceType.Id {
+		return nil, nil, status.Errorf(
+			codes.InvalidArgument,
+			"confluence-connector: space permissions cannot be granted to access class %s",
+			principal.Id.Resource,
+		)
+	}
+	spaceId := ent.Resource.Id.Resource
+	key, target, err := catalogedSpacePermission(ent.Slug)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	ratelimitData, err := o.client.AddSpacePermission(
 		ctx,
-		spaceName,
+		spaceId,
 		key,
 		target,
 		principal.Id.Resource,
@@ -195,35 +554,290 @@ func (o *spaceBuilder) Revoke(
 	ctx context.Context,
 	grant *v2.Grant,
 ) (annotations.Annotations, error) {
+	if grant.Entitlement.Slug == spaceOwnerEntitlement {
+		return nil, status.Error(codes.InvalidArgument, "confluence-connector: the owner of a space cannot be changed")
+	}
 	spaceId := grant.Entitlement.Resource.Id.Resource
-	key, target := GetEntitlementComponents(grant.Entitlement.Slug)
+	key, target, err := catalogedSpacePermission(grant.Entitlement.Slug)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	principalId := grant.Principal.Id.Resource
+	principalType := grant.Principal.Id.ResourceType
+	if principalType == accessClassResourceType.Id {
+		// Anonymous users are the only access class granular permissions can
+		// be given to.
+		if principalId != anonymousAccessClassID {
+			return nil, status.Errorf(codes.InvalidArgument, "confluence-connector: access class %s has no space permissions", principalId)
+		}
+		principalId = ""
+		principalType = client.SpacePermissionPrincipalAnonymous
+	}
 	ratelimitData, err := o.client.RemoveSpacePermission(
 		ctx,
 		spaceId,
 		key,
 		target,
-		grant.Principal.Id.Resource,
-		grant.Principal.Id.ResourceType,
+		principalId,
+		principalType,
 	)
 	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
 	return outputAnnotations, err
 }
 
-func newSpaceBuilder(client *client.ConfluenceClient, skipPersonalSpaces bool, useRbac bool, nouns, verbs []string) *spaceBuilder {
+// Create creates a space named after the resource, with the key given in its
+// key profile field. The user in its requester_account_id profile field, if
+// any, is made an admin of the space. The grants of the new space are not
+// returned, as the SDK ignores them on a create, and are synced with the next
+// sync instead.
+func (o *spaceBuilder) Create(ctx context.Context, res *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	name := strings.TrimSpace(res.GetDisplayName())
+	if name == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "confluence-connector: space name is required")
+	}
+	profile := resource.GetProfile(res)
+	key, _ := resource.GetProfileStringValue(profile, spaceKeyProfileKey)
+	if !spaceKeyPattern.MatchString(key) {
+		return nil, nil, status.Errorf(
+			codes.InvalidArgument,
+			"confluence-connector: invalid space key %q, space keys are 1 to 255 letters and digits",
+			key,
+		)
+	}
+	requester, _ := resource.GetProfileStringValue(profile, spaceRequesterProfileKey)
+
+	space, ratelimitData, err := o.client.CreateSpace(ctx, client.CreateSpaceRequest{
+		Key:         key,
+		Name:        name,
+		Description: res.GetDescription(),
+	})
+	if err != nil {
+		return nil, WithRateLimitAnnotations(ratelimitData), fmt.Errorf("confluence-connector: failed to create space: %w", err)
+	}
+
+	if requester != "" {
+		ratelimitData, err = o.makeSpaceAdmin(ctx, space.Id, requester)
+		if err != nil {
+			// Roll back, so that the create can be retried with the same key.
+			_, deleteErr := o.client.DeleteSpace(ctx, space.Id)
+			if deleteErr != nil {
+				return nil, WithRateLimitAnnotations(ratelimitData), fmt.Errorf(
+					"confluence-connector: created space %s but failed to make %s its admin: %w, and failed to delete it: %w",
+					space.Key,
+					requester,
+					err,
+					deleteErr,
+				)
+			}
+			return nil, WithRateLimitAnnotations(ratelimitData), fmt.Errorf(
+				"confluence-connector: failed to make %s the admin of space %s, deleted it: %w",
+				requester,
+				space.Key,
+				err,
+			)
+		}
+	}
+
+	// The grants of the new space are synced with the next sync.
+	created, err := spaceResource(ctx, space, nil, "", o.childResourceTypes()...)
+	if err != nil {
+		return nil, WithRateLimitAnnotations(ratelimitData), err
+	}
+	return created, WithRateLimitAnnotations(ratelimitData), nil
+}
+
+// makeSpaceAdmin gives a user admin access to a space: the read and
+// administer permissions, or, when only roles are synced, the default role
+// that administers the space.
+func (o *spaceBuilder) makeSpaceAdmin(ctx context.Context, spaceId string, accountId string) (*v2.RateLimitDescription, error) {
+	if o.syncPermissions {
+		for _, operation := range []string{"read", "administer"} {
+			ratelimitData, err := o.client.AddSpacePermission(ctx, spaceId, operation, resourceTypeSpaceID, accountId, resourceTypeUserID)
+			if err != nil {
+				return ratelimitData, err
+			}
+		}
+		return nil, nil
+	}
+
+	roles := o.client.SpaceRoles(spaceId)
+	var adminRole *client.SpaceRole
+	for role, err := range roles.All(ctx) {
+		if err != nil {
+			return roles.RateLimit(), err
+		}
+		if role.Type != client.SpaceRoleTypeCustom && slices.Contains(role.SpacePermissions, createEntitlementName("administer", resourceTypeSpaceID)) {
+			adminRole = &role
+			break
+		}
+	}
+	if adminRole == nil {
+		return roles.RateLimit(), status.Error(codes.FailedPrecondition, "confluence-connector: no default space role administers spaces")
+	}
+
+	return o.client.SetSpaceRoleAssignment(ctx, spaceId, []client.SetSpaceRoleAssignmentRequest{
+		{
+			Principal: client.SpaceRoleAssignmentPrincipal{PrincipalType: "USER", PrincipalId: accountId},
+			RoleId:    adminRole.Id,
+		},
+	})
+}
+
+// Delete deletes a space with all its content. Confluence deletes it in the
+// background.
+func (o *spaceBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId, _ *v2.ResourceId) (annotations.Annotations, error) {
+	ratelimitData, err := o.client.DeleteSpace(ctx, resourceId.GetResource())
+	outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+	if err != nil {
+		if isNotFound(err) {
+			return outputAnnotations, status.Errorf(codes.NotFound, "confluence-connector: space %s not found", resourceId.GetResource())
+		}
+		return outputAnnotations, fmt.Errorf("confluence-connector: failed to delete space: %w", err)
+	}
+	return outputAnnotations, nil
+}
+
+// ResourceActions registers the actions that archive a space and restore an
+// archived one.
+func (o *spaceBuilder) ResourceActions(ctx context.Context, registry actions.ActionRegistry) error {
+	err := registry.Register(ctx, spaceStatusActionSchema(
+		archiveSpaceAction,
+		"Archive space",
+		"Archive a space, making it read-only and hiding it from search",
+	), o.spaceStatusHandler(client.SpaceStatusArchived))
+	if err != nil {
+		return err
+	}
+	return registry.Register(ctx, spaceStatusActionSchema(
+		restoreSpaceAction,
+		"Restore space",
+		"Restore an archived space",
+	), o.spaceStatusHandler(client.SpaceStatusCurrent))
+}
+
+func spaceStatusActionSchema(name string, displayName string, description string) *v2.BatonActionSchema {
+	return &v2.BatonActionSchema{
+		Name:        name,
+		DisplayName: displayName,
+		Description: description,
+		Arguments: []*config.Field{
+			config.Field_builder{
+				Name:            "resource_id",
+				DisplayName:     "Space",
+				IsRequired:      true,
+				ResourceIdField: &config.ResourceIdField{},
+			}.Build(),
+		},
+		ReturnTypes: []*config.Field{
+			config.Field_builder{
+				Name:          "resource",
+				DisplayName:   "Space",
+				ResourceField: &config.ResourceField{},
+			}.Build(),
+		},
+	}
+}
+
+// spaceStatusHandler returns the handler of the action that sets the status
+// of a space.
+func (o *spaceBuilder) spaceStatusHandler(spaceStatus string) actions.ActionHandler {
+	return func(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+		resourceId, err := actions.RequireResourceIDArg(args, "resource_id")
+		if err != nil {
+			return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		spaceId := resourceId.GetResource()
+
+		ratelimitData, err := o.client.SetSpaceStatus(ctx, spaceId, spaceStatus)
+		if err != nil {
+			if isNotFound(err) {
+				return nil, WithRateLimitAnnotations(ratelimitData), status.Errorf(codes.NotFound, "confluence-connector: space %s not found", spaceId)
+			}
+			return nil, WithRateLimitAnnotations(ratelimitData), fmt.Errorf("confluence-connector: failed to set the status of space %s: %w", spaceId, err)
+		}
+
+		space, ratelimitData, err := o.client.GetSpaceById(ctx, spaceId)
+		outputAnnotations := WithRateLimitAnnotations(ratelimitData)
+		if err != nil {
+			return nil, outputAnnotations, fmt.Errorf("confluence-connector: failed to fetch space %s: %w", spaceId, err)
+		}
+		updated, ratelimitData, err := o.spaceResource(ctx, space)
+		if err != nil {
+			return nil, WithRateLimitAnnotations(ratelimitData), err
+		}
+		returnField, err := actions.NewResourceReturnField("resource", updated)
+		if err != nil {
+			return nil, outputAnnotations, err
+		}
+		return actions.NewReturnValues(true, returnField), outputAnnotations, nil
+	}
+}
+
+func newSpaceBuilder(c *client.ConfluenceClient, filter *spaceFilter, opts spaceBuilderOptions) *spaceBuilder {
 	return &spaceBuilder{
-		client:             client,
-		skipPersonalSpaces: skipPersonalSpaces,
-		useRbac:            useRbac,
-		nouns:              nouns,
-		verbs:              verbs,
+		client:                  c,
+		filter:                  filter,
+		syncPermissions:         opts.accessMode != cfg.SpaceAccessModeRbac,
+		syncRoles:               opts.accessMode != cfg.SpaceAccessModePermissions,
+		nouns:                   opts.nouns,
+		verbs:                   opts.verbs,
+		discoverEntitlements:    opts.discoverEntitlements,
+		discovered:              make(map[string][]spacePermission),
+		syncContentRestrictions: opts.syncContentRestrictions,
+		listed:                  make(map[string][]client.ConfluenceSpacePermission),
+		permissions: newPrefetcher(
+			opts.concurrency,
+			func(ctx context.Context, spaceId string, pageToken string, pageSize int) ([]client.ConfluenceSpacePermission, string, *v2.RateLimitDescription, error) {
+				return c.SpacePermissions(spaceId).WithPageSize(pageSize).Page(ctx, pageToken)
+			},
+		),
 	}
 }
 
-func spaceResource(ctx context.Context, space *client.ConfluenceSpace, useRbac bool) (*v2.Resource, error) {
-	var opts []resource.ResourceOption
-	if useRbac {
+// spaceResource builds the resource of a space. anonymousRead, when it was
+// checked, marks the spaces anyone on the internet can view and ownerId is the
+// account a personal space belongs to. Archived spaces are disabled.
+func spaceResource(
+	ctx context.Context,
+	space *client.ConfluenceSpace,
+	anonymousRead *bool,
+	ownerId string,
+	childResourceTypes ...*v2.ResourceType,
+) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		spaceKeyProfileKey:    space.Key,
+		"type":                space.Type,
+		"status":              space.Status,
+		"description":         space.Description.Plain.Value,
+		"homepage_id":         space.HomepageId,
+		spaceAuthorProfileKey: space.AuthorId,
+		"created_at":          space.CreatedAt,
+	}
+	if anonymousRead != nil {
+		profile["anonymous_read"] = *anonymousRead
+	}
+	if ownerId != "" {
+		profile[spaceOwnerProfileKey] = ownerId
+	}
+
+	resourceStatus := v2.Status_RESOURCE_STATUS_ENABLED
+	if space.Status == client.SpaceStatusArchived {
+		resourceStatus = v2.Status_RESOURCE_STATUS_DISABLED
+	}
+
+	opts := []resource.ResourceOption{
+		resource.WithResourceProfile(profile),
+		resource.WithResourceStatus(resourceStatus, space.Status),
+	}
+	if space.Description.Plain.Value != "" {
+		opts = append(opts, resource.WithDescription(space.Description.Plain.Value))
+	}
+	if createdAt, err := time.Parse(time.RFC3339, space.CreatedAt); err == nil {
+		opts = append(opts, resource.WithResourceCreatedAt(createdAt))
+	}
+	for _, childResourceType := range childResourceTypes {
 		opts = append(opts, resource.WithAnnotation(&v2.ChildResourceType{
-			ResourceTypeId: spaceRoleAssignmentResourceType.Id,
+			ResourceTypeId: childResourceType.Id,
 		}))
 	}
 	return resource.NewResource(space.Name, spaceResourceType, space.Id, opts...)
@@ -2,13 +2,21 @@ package connector
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	"github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 
+	cfg "github.com/conductorone/baton-confluence/pkg/config"
 	"github.com/conductorone/baton-confluence/pkg/connector/client"
 	"github.com/conductorone/baton-confluence/test"
 )
@@ -29,18 +37,16 @@ func TestSpaces(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	c := newSpaceBuilder(
-		confluenceClient,
-		false,
-		false,
-		[]string{
+	c := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{
+		accessMode: cfg.SpaceAccessModePermissions,
+		nouns: []string{
 			"attachment",
 			"blogpost",
 			"comment",
 			"page",
 			resourceTypeSpaceID,
 		},
-		[]string{
+		verbs: []string{
 			"administer",
 			"archive",
 			"create",
@@ -50,7 +56,8 @@ func TestSpaces(t *testing.T) {
 			"restrict_content",
 			"update",
 		},
-	)
+		concurrency: 1,
+	})
 
 	t.Run("should list spaces", func(t *testing.T) {
 		resources := make([]*v2.Resource, 0)
@@ -76,7 +83,7 @@ func TestSpaces(t *testing.T) {
 		confluenceSpace := client.ConfluenceSpace{
 			Id: "678",
 		}
-		space, _ := spaceResource(ctx, &confluenceSpace, false)
+		space, _ := spaceResource(ctx, &confluenceSpace, nil, "")
 
 		grants, results, err := c.Grants(ctx, space, resource.SyncOpAttrs{})
 		require.Nil(t, err)
@@ -86,3 +93,542 @@ func TestSpaces(t *testing.T) {
 		require.Len(t, grants, 25)
 	})
 }
+
+func TestSpaceProvisioning(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	c := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{
+		accessMode:  cfg.SpaceAccessModePermissions,
+		nouns:       []string{"page", resourceTypeSpaceID},
+		verbs:       []string{"administer", "create", "read"},
+		concurrency: 1,
+	})
+
+	space, err := spaceResource(ctx, &client.ConfluenceSpace{Id: "100", Key: "ENG", Name: "Engineering"}, nil, "")
+	require.Nil(t, err)
+	readSpace := entitlement.NewPermissionEntitlement(space, "read-space")
+	alice, err := userResource(ctx, &client.ConfluenceUser{AccountId: "alice", DisplayName: "Alice Doe"}, nil)
+	require.Nil(t, err)
+
+	hasGrant := func(t *testing.T, principalId string, ent *v2.Entitlement) bool {
+		grants, _, err := c.Grants(ctx, space, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		for _, grant := range grants {
+			if grant.Principal.Id.Resource == principalId && grant.Entitlement.Id == ent.Id {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("should page through spaces by cursor", func(t *testing.T) {
+		spaceIds := make([]string, 0)
+		cursor := ""
+		for {
+			spaces, nextCursor, _, err := confluenceClient.GetSpaces(ctx, 1, cursor)
+			require.Nil(t, err)
+			for _, space := range spaces {
+				spaceIds = append(spaceIds, space.Id)
+			}
+			if nextCursor == "" {
+				break
+			}
+			cursor = nextCursor
+		}
+		require.Equal(t, []string{"100", "200"}, spaceIds)
+	})
+
+	t.Run("should grant a space permission by space key", func(t *testing.T) {
+		grants, _, err := c.Grant(ctx, alice, readSpace)
+		require.Nil(t, err)
+		require.Len(t, grants, 1)
+		require.True(t, hasGrant(t, "alice", readSpace))
+		require.Contains(t, site.Requests(), "POST /wiki/rest/api/space/ENG/permissions")
+	})
+
+	t.Run("should revoke a space permission", func(t *testing.T) {
+		grant := &v2.Grant{Entitlement: readSpace, Principal: alice}
+		_, err := c.Revoke(ctx, grant)
+		require.Nil(t, err)
+		require.False(t, hasGrant(t, "alice", readSpace))
+		require.Len(t, site.SpacePermissions("100"), 6)
+	})
+}
+
+func TestDiscoveredSpaceEntitlements(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+	site.AddSpacePermission("100", "user", "bob", "purge_version", "page")
+	site.AddSpacePermission("100", "user", "bob", "create", "folder")
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	nouns := []string{"page", resourceTypeSpaceID}
+	verbs := []string{"create", "read"}
+	space, err := spaceResource(ctx, &client.ConfluenceSpace{Id: "100", Key: "ENG", Name: "Engineering"}, nil, "")
+	require.Nil(t, err)
+
+	slugs := func(entitlements []*v2.Entitlement) []string {
+		rv := make([]string, 0, len(entitlements))
+		for _, ent := range entitlements {
+			rv = append(rv, ent.Slug)
+		}
+		return rv
+	}
+
+	t.Run("should build entitlements from the space's operations", func(t *testing.T) {
+		c := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{
+			accessMode:           cfg.SpaceAccessModePermissions,
+			nouns:                nouns,
+			verbs:                verbs,
+			discoverEntitlements: true,
+			concurrency:          1,
+		})
+
+		entitlements, results, err := c.Entitlements(ctx, space, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Empty(t, results.NextPageToken)
+
+		discovered := slugs(entitlements)
+		// Slugs of the operations in the configured nouns and verbs are the
+		// same as without discovery.
+		require.Contains(t, discovered, "read-space")
+		require.Contains(t, discovered, "create-page")
+		// Operations outside the nouns and verbs are added.
+		require.Contains(t, discovered, "purge_version-page")
+		// Combinations that the space does not report are left out.
+		require.NotContains(t, discovered, "read-page")
+		// Operations that cannot be provisioned are left out.
+		require.NotContains(t, discovered, "create-folder")
+
+		static, _, err := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{
+			accessMode:  cfg.SpaceAccessModePermissions,
+			nouns:       nouns,
+			verbs:       verbs,
+			concurrency: 1,
+		}).
+			Entitlements(ctx, space, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Contains(t, slugs(static), "read-space")
+		require.NotContains(t, slugs(static), "purge_version-page")
+	})
+
+	t.Run("should grant operations outside the configured nouns and verbs", func(t *testing.T) {
+		c := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{
+			accessMode:           cfg.SpaceAccessModePermissions,
+			nouns:                nouns,
+			verbs:                verbs,
+			discoverEntitlements: true,
+			concurrency:          1,
+		})
+
+		grants, _, err := c.Grants(ctx, space, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+
+		entitlementIds := make([]string, 0, len(grants))
+		for _, grant := range grants {
+			entitlementIds = append(entitlementIds, grant.Entitlement.Id)
+		}
+		require.Contains(t, entitlementIds, "space:100:purge_version-page")
+		require.NotContains(t, entitlementIds, "space:100:create-folder")
+	})
+}
+
+func TestHybridSpaceAccess(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+	site.SetSpaceRoleMode("ROLES_TRANSITION")
+
+	t.Run("should treat use-rbac as the rbac mode", func(t *testing.T) {
+		mode, err := resolveSpaceAccessMode(Config{UseRbac: true})
+		require.Nil(t, err)
+		require.Equal(t, cfg.SpaceAccessModeRbac, mode)
+
+		mode, err = resolveSpaceAccessMode(Config{UseRbac: true, SpaceAccessMode: cfg.SpaceAccessModeHybrid})
+		require.Nil(t, err)
+		require.Equal(t, cfg.SpaceAccessModeHybrid, mode)
+
+		_, err = resolveSpaceAccessMode(Config{SpaceAccessMode: "roles"})
+		require.NotNil(t, err)
+	})
+
+	connector, err := New(ctx, Config{
+		Domain:          server.URL,
+		UserName:        "admin",
+		ApiKey:          "API Key",
+		SpaceAccessMode: cfg.SpaceAccessModeHybrid,
+	})
+	require.Nil(t, err)
+
+	t.Run("should validate both APIs", func(t *testing.T) {
+		_, err := connector.Validate(ctx)
+		require.Nil(t, err)
+		require.Contains(t, site.Requests(), "GET "+client.CurrentUserUrlPath)
+		require.Contains(t, site.Requests(), "GET "+client.SpaceRoleModeUrlPath)
+	})
+
+	c := newSpaceBuilder(connector.client, nil, spaceBuilderOptions{
+		accessMode:  cfg.SpaceAccessModeHybrid,
+		nouns:       []string{resourceTypeSpaceID},
+		verbs:       []string{"read", "administer"},
+		concurrency: 1,
+	})
+	assignments := newSpaceRoleAssignmentBuilder(connector.client)
+	alice, err := userResource(ctx, &client.ConfluenceUser{AccountId: "alice", DisplayName: "Alice Doe"}, nil)
+	require.Nil(t, err)
+
+	var space *v2.Resource
+	t.Run("should sync permission grants and role bindings for the same space", func(t *testing.T) {
+		spaces, _, err := c.List(ctx, nil, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		for _, s := range spaces {
+			if s.Id.Resource == "100" {
+				space = s
+			}
+		}
+		require.NotNil(t, space)
+		annos := annotations.Annotations(space.Annotations)
+		require.True(t, annos.Contains(&v2.ChildResourceType{}))
+
+		entitlements, _, err := c.Entitlements(ctx, space, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		// The owner entitlement and the two configured permissions.
+		require.Len(t, entitlements, 3)
+
+		grants, _, err := c.Grants(ctx, space, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.NotEmpty(t, grants)
+
+		bindings, _, err := assignments.List(ctx, space.Id, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Len(t, bindings, len(site.SpaceRoles()))
+	})
+
+	t.Run("should provision each entitlement type through its own API", func(t *testing.T) {
+		_, _, err := c.Grant(ctx, alice, entitlement.NewPermissionEntitlement(space, "administer-space"))
+		require.Nil(t, err)
+		require.Contains(t, site.Requests(), "POST /wiki/rest/api/space/ENG/permissions")
+
+		binding, err := spaceRoleAssignmentResource("role-viewer", space.Id, "Viewer", space.DisplayName)
+		require.Nil(t, err)
+		_, _, err = assignments.Grant(ctx, alice, entitlement.NewAssignmentEntitlement(binding, spaceRoleAssignmentEntitlement))
+		require.Nil(t, err)
+		require.Contains(t, site.Requests(), "POST /wiki/api/v2/spaces/100/role-assignments")
+	})
+}
+
+func TestAnonymousSpaceAccess(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+	site.AddSpacePermission("100", client.SpacePermissionPrincipalAnonymous, "", "read", "space")
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	c := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{
+		accessMode:  cfg.SpaceAccessModePermissions,
+		nouns:       []string{resourceTypeSpaceID},
+		verbs:       []string{"read"},
+		concurrency: 1,
+	})
+
+	spaces, _, err := c.List(ctx, nil, resource.SyncOpAttrs{})
+	require.Nil(t, err)
+	spacesById := make(map[string]*v2.Resource)
+	for _, space := range spaces {
+		spacesById[space.Id.Resource] = space
+	}
+	space := spacesById["100"]
+	require.NotNil(t, space)
+
+	t.Run("should mark spaces anonymous users can read", func(t *testing.T) {
+		for id, expected := range map[string]bool{"100": true, "200": false} {
+			profile := resource.GetProfile(spacesById[id])
+			require.NotNil(t, profile)
+			require.Equal(t, expected, profile.Fields["anonymous_read"].GetBoolValue())
+		}
+	})
+
+	t.Run("should read the permissions of each space once, while listing it", func(t *testing.T) {
+		once := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{
+			accessMode:  cfg.SpaceAccessModePermissions,
+			nouns:       []string{resourceTypeSpaceID},
+			verbs:       []string{"read"},
+			concurrency: 1,
+		})
+		permissionRequests := func(from int) int {
+			count := 0
+			for _, request := range site.Requests()[from:] {
+				if strings.Contains(request, "/spaces/100/permissions") {
+					count++
+				}
+			}
+			return count
+		}
+
+		requests := len(site.Requests())
+		spaces, _, err := once.List(ctx, nil, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Equal(t, 1, permissionRequests(requests))
+
+		requests = len(site.Requests())
+		grants, results, err := once.Grants(ctx, spaces[0], resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Empty(t, results.NextPageToken)
+		require.Zero(t, permissionRequests(requests))
+		require.NotEmpty(t, grants)
+
+		// The permissions are only kept for one grant pass.
+		_, _, err = once.Grants(ctx, spaces[0], resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Equal(t, 1, permissionRequests(requests))
+	})
+
+	t.Run("should not mark spaces when permissions are not synced", func(t *testing.T) {
+		rbac := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{accessMode: cfg.SpaceAccessModeRbac})
+		spaces, _, err := rbac.List(ctx, nil, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		_, ok := resource.GetProfile(spaces[0]).Fields["anonymous_read"]
+		require.False(t, ok)
+	})
+
+	anonymousGrant := func(t *testing.T) *v2.Grant {
+		grants, _, err := c.Grants(ctx, space, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		for _, grant := range grants {
+			if grant.Principal.Id.ResourceType == accessClassResourceType.Id {
+				return grant
+			}
+		}
+		return nil
+	}
+
+	t.Run("should grant anonymous permissions to the anonymous users access class", func(t *testing.T) {
+		grant := anonymousGrant(t)
+		require.NotNil(t, grant)
+		require.Equal(t, anonymousAccessClassID, grant.Principal.Id.Resource)
+		require.Equal(t, "space:100:read-space", grant.Entitlement.Id)
+	})
+
+	t.Run("should revoke anonymous access", func(t *testing.T) {
+		grant := anonymousGrant(t)
+		require.NotNil(t, grant)
+		_, err := c.Revoke(ctx, &v2.Grant{
+			Entitlement: entitlement.NewPermissionEntitlement(space, "read-space"),
+			Principal:   &v2.Resource{Id: grant.Principal.Id},
+		})
+		require.Nil(t, err)
+		require.Nil(t, anonymousGrant(t))
+	})
+
+	t.Run("should refuse to grant space permissions to access classes", func(t *testing.T) {
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: accessClassResourceType.Id, Resource: anonymousAccessClassID}}
+		_, _, err := c.Grant(ctx, principal, entitlement.NewPermissionEntitlement(space, "read-space"))
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("should refuse to revoke space permissions from other access classes", func(t *testing.T) {
+		_, err := c.Revoke(ctx, &v2.Grant{
+			Entitlement: entitlement.NewPermissionEntitlement(space, "read-space"),
+			Principal: &v2.Resource{
+				Id: &v2.ResourceId{ResourceType: accessClassResourceType.Id, Resource: "all-licensed-users"},
+			},
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func spaceResourceArgs(t *testing.T, spaceId string) *structpb.Struct {
+	args, err := structpb.NewStruct(map[string]interface{}{
+		"resource_id": map[string]interface{}{
+			"resource_type_id": spaceResourceType.Id,
+			"resource_id":      spaceId,
+		},
+	})
+	require.Nil(t, err)
+	return args
+}
+
+func newSpaceRequest(t *testing.T, name string, key string, requester string) *v2.Resource {
+	r, err := resource.NewResource(name, spaceResourceType, key, resource.WithResourceProfile(map[string]interface{}{
+		spaceKeyProfileKey:       key,
+		spaceRequesterProfileKey: requester,
+	}))
+	require.Nil(t, err)
+	return r
+}
+
+func TestSpaceLifecycle(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	nouns := []string{"space"}
+	verbs := []string{"read", "administer"}
+	c := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{
+		accessMode:  cfg.SpaceAccessModePermissions,
+		nouns:       nouns,
+		verbs:       verbs,
+		concurrency: 1,
+	})
+
+	t.Run("should validate the space key", func(t *testing.T) {
+		for _, key := range []string{"", "AP-1", "~alice", "APOLLO ONE"} {
+			_, _, err := c.Create(ctx, newSpaceRequest(t, "Apollo", key, ""))
+			require.Equal(t, codes.InvalidArgument, status.Code(err), key)
+		}
+	})
+
+	var created *v2.Resource
+	t.Run("should create a space with the requester as admin", func(t *testing.T) {
+		created, _, err = c.Create(ctx, newSpaceRequest(t, "Apollo", "APOLLO", "alice"))
+		require.Nil(t, err)
+		require.Equal(t, "Apollo", created.DisplayName)
+		require.Equal(t, "APOLLO", site.Space("APOLLO").Key)
+
+		var granted []string
+		for _, permission := range site.SpacePermissions(created.Id.Resource) {
+			granted = append(granted, permission.Operation.Key+"-"+permission.Operation.TargetType+"="+permission.Principal.Id)
+		}
+		require.ElementsMatch(t, []string{
+			"read-space=admin",
+			"administer-space=admin",
+			"read-space=alice",
+			"administer-space=alice",
+		}, granted)
+	})
+
+	t.Run("should delete the space when the requester cannot be made its admin", func(t *testing.T) {
+		_, _, err := c.Create(ctx, newSpaceRequest(t, "Artemis", "ARTEMIS", "nobody"))
+		require.NotNil(t, err)
+		require.Nil(t, site.Space("ARTEMIS"))
+
+		_, _, err = c.Create(ctx, newSpaceRequest(t, "Artemis", "ARTEMIS", "bob"))
+		require.Nil(t, err)
+		require.NotNil(t, site.Space("ARTEMIS"))
+	})
+
+	t.Run("should archive and restore a space", func(t *testing.T) {
+		registry := &testActionRegistry{}
+		require.Nil(t, c.ResourceActions(ctx, registry))
+
+		_, _, err := registry.handlers[archiveSpaceAction](ctx, spaceResourceArgs(t, created.Id.Resource))
+		require.Nil(t, err)
+		require.Equal(t, client.SpaceStatusArchived, site.Space("APOLLO").Status)
+
+		_, _, err = registry.handlers[restoreSpaceAction](ctx, spaceResourceArgs(t, created.Id.Resource))
+		require.Nil(t, err)
+		require.Equal(t, client.SpaceStatusCurrent, site.Space("APOLLO").Status)
+
+		_, _, err = registry.handlers[archiveSpaceAction](ctx, spaceResourceArgs(t, "404"))
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("should delete a space", func(t *testing.T) {
+		_, err := c.Delete(ctx, created.Id, nil)
+		require.Nil(t, err)
+		require.Nil(t, site.Space("APOLLO"))
+	})
+
+	t.Run("should assign the admin role when syncing roles", func(t *testing.T) {
+		rbac := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{
+			accessMode:  cfg.SpaceAccessModeRbac,
+			nouns:       nouns,
+			verbs:       verbs,
+			concurrency: 1,
+		})
+		created, _, err := rbac.Create(ctx, newSpaceRequest(t, "Gemini", "GEMINI", "bob"))
+		require.Nil(t, err)
+
+		require.Contains(t, site.SpaceRoleAssignments(created.Id.Resource), client.SpaceRoleAssignment{
+			Principal: client.SpaceRoleAssignmentPrincipal{PrincipalType: "USER", PrincipalId: "bob"},
+			RoleId:    "role-admin",
+		})
+	})
+}
+
+func TestSpaceProfile(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+	site.AddSpace(client.ConfluenceSpace{Id: "300", Key: "OLD", Name: "Old Projects", Status: client.SpaceStatusArchived})
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+
+	c := newSpaceBuilder(confluenceClient, nil, spaceBuilderOptions{
+		accessMode:  cfg.SpaceAccessModeRbac,
+		concurrency: 1,
+	})
+	spaces, _, err := c.List(ctx, nil, resource.SyncOpAttrs{})
+	require.Nil(t, err)
+	require.Len(t, spaces, 3)
+
+	t.Run("should describe the space in its profile", func(t *testing.T) {
+		space := spaces[0]
+		profile := resource.GetProfile(space)
+		for key, expected := range map[string]string{
+			spaceKeyProfileKey: "ENG",
+			"type":             "global",
+			"status":           client.SpaceStatusCurrent,
+			"description":      "Design docs and runbooks",
+			"homepage_id":      "1001",
+			"author_id":        "admin",
+		} {
+			value, ok := resource.GetProfileStringValue(profile, key)
+			require.True(t, ok, key)
+			require.Equal(t, expected, value, key)
+		}
+		require.Equal(t, "Design docs and runbooks", space.GetDescription())
+		require.Equal(t, "2023-01-16T09:30:00Z", space.GetCreatedAt().AsTime().Format(time.RFC3339))
+		require.Equal(t, v2.Status_RESOURCE_STATUS_ENABLED, space.GetStatus().GetStatus())
+	})
+
+	t.Run("should disable archived spaces", func(t *testing.T) {
+		require.Equal(t, v2.Status_RESOURCE_STATUS_DISABLED, spaces[2].GetStatus().GetStatus())
+	})
+
+	t.Run("should grant ownership of the space to its author", func(t *testing.T) {
+		entitlements, _, err := c.Entitlements(ctx, spaces[0], resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Len(t, entitlements, 1)
+		require.Equal(t, spaceOwnerEntitlement, entitlements[0].Slug)
+
+		grants, _, err := c.Grants(ctx, spaces[0], resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Len(t, grants, 1)
+		require.Equal(t, "space:100:owner", grants[0].Entitlement.Id)
+		require.Equal(t, "admin", grants[0].Principal.Id.Resource)
+
+		// The archived space has no recorded author.
+		grants, _, err = c.Grants(ctx, spaces[2], resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Empty(t, grants)
+	})
+
+	t.Run("should grant ownership of a personal space to its owner", func(t *testing.T) {
+		personal := spaces[1]
+		owner, ok := resource.GetProfileStringValue(resource.GetProfile(personal), spaceOwnerProfileKey)
+		require.True(t, ok)
+		require.Equal(t, "alice", owner)
+
+		grants, _, err := c.Grants(ctx, personal, resource.SyncOpAttrs{})
+		require.Nil(t, err)
+		require.Len(t, grants, 1)
+		require.Equal(t, "space:200:owner", grants[0].Entitlement.Id)
+		require.Equal(t, "alice", grants[0].Principal.Id.Resource)
+	})
+
+	t.Run("should refuse to change the owner", func(t *testing.T) {
+		alice, err := userResource(ctx, &client.ConfluenceUser{AccountId: "alice", DisplayName: "Alice Doe"}, nil)
+		require.Nil(t, err)
+		_, _, err = c.Grant(ctx, alice, spaceOwnerEntitlementFor(spaces[0]))
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
@@ -19,11 +19,21 @@ const (
 	// calls during the 2D user-listing scheme (groups → members).
 	// The membersByGroupId endpoint enforces a maximum of 200.
 	GroupPageSizeMaximum = 200
+
+	// orgAccountsPageState marks the pages of the organization's managed
+	// accounts in the pagination bag of the user listing.
+	orgAccountsPageState = "org_account"
 )
 
 type userResourceType struct {
 	resourceType *v2.ResourceType
 	client       *client.ConfluenceClient
+	groupMembers *prefetcher[client.ConfluenceUser]
+	// orgAccounts is nil unless an Atlassian organization is configured.
+	orgAccounts *orgAccounts
+	// globalPermissions records the global permissions of the users found by
+	// user search for the site grants. It is nil when the site is not synced.
+	globalPermissions *globalPermissionHolders
 }
 
 // limitPageSizeForGroups enforces the membersByGroupId endpoint max of 200.
@@ -38,21 +48,48 @@ func (o *userResourceType) ResourceType(_ context.Context) *v2.ResourceType {
 	return o.resourceType
 }
 
-func userResource(ctx context.Context, user *client.ConfluenceUser) (*v2.Resource, error) {
+// userResource builds a user. The status, login and last login of managed
+// accounts come from the organization; other Cloud users are considered
+// disabled when Confluence reports no operations for them.
+func userResource(ctx context.Context, user *client.ConfluenceUser, account *orgAccount) (*v2.Resource, error) {
+	email := user.Email
+	if email == "" && account != nil && account.EmailVerified {
+		// Confluence hides the email of users with a private profile.
+		email = account.Email
+	}
+
 	profile := map[string]interface{}{
 		"user_name":    user.DisplayName,
 		"account_type": user.AccountType,
-		"email":        user.Email,
+		"email":        email,
 		"id":           user.AccountId,
 	}
 
-	status := v2.Status_RESOURCE_STATUS_ENABLED
-	if len(user.Operations) == 0 {
-		status = v2.Status_RESOURCE_STATUS_DISABLED
+	userTraitOptions := []resource.UserTraitOption{
+		resource.WithEmail(email, true),
 	}
 
-	userTraitOptions := []resource.UserTraitOption{
-		resource.WithEmail(user.Email, true),
+	status := v2.Status_RESOURCE_STATUS_ENABLED
+	statusDetails := ""
+	if account != nil {
+		var userStatus v2.UserTrait_Status_Status
+		userStatus, status = orgAccountStatus(account.AccountStatus)
+		statusDetails = account.AccountStatus
+		userTraitOptions = append(userTraitOptions, resource.WithDetailedStatus(userStatus, account.AccountStatus))
+
+		profile["account_status"] = account.AccountStatus
+		profile["email_verified"] = account.EmailVerified
+		if account.Email != "" {
+			userTraitOptions = append(userTraitOptions, resource.WithUserLogin(account.Email))
+		}
+		if lastActive, ok := parseLastActive(account.lastActive); ok {
+			profile["last_active"] = account.lastActive
+			userTraitOptions = append(userTraitOptions, resource.WithLastLogin(lastActive))
+		}
+	} else if len(user.Operations) == 0 && user.UserKey == "" {
+		// Data Center users (identified by a user key) never carry
+		// operations, so the operations heuristic only applies to Cloud.
+		status = v2.Status_RESOURCE_STATUS_DISABLED
 	}
 
 	newUserResource, err := resource.NewUserResource(
@@ -61,7 +98,7 @@ func userResource(ctx context.Context, user *client.ConfluenceUser) (*v2.Resourc
 		user.AccountId,
 		userTraitOptions,
 		resource.WithResourceProfile(profile),
-		resource.WithResourceStatus(status, ""),
+		resource.WithResourceStatus(status, statusDetails),
 	)
 	if err != nil {
 		return nil, err
@@ -70,6 +107,19 @@ func userResource(ctx context.Context, user *client.ConfluenceUser) (*v2.Resourc
 	return newUserResource, nil
 }
 
+// userResource builds a user with its managed account, if any.
+func (o *userResourceType) userResource(
+	ctx context.Context,
+	user *client.ConfluenceUser,
+) (*v2.Resource, *v2.RateLimitDescription, error) {
+	account, ratelimitData, err := o.orgAccounts.get(ctx, user.AccountId)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+	newUserResource, err := userResource(ctx, user, account)
+	return newUserResource, ratelimitData, err
+}
+
 // parsePageToken returns the pagination bag and the current page number.
 func parsePageToken(
 	pToken pagination.Token,
@@ -128,9 +178,41 @@ func (o *userResourceType) List(
 	outputResources := make([]*v2.Resource, 0)
 	var outputAnnotations annotations.Annotations
 
+	if opts.PageToken.Token == "" {
+		// A new sync: managed accounts may have changed since the last one,
+		// and pages prefetched for the last one are not asked for anymore.
+		o.orgAccounts.reset()
+		o.groupMembers.reset()
+		o.globalPermissions.reset()
+		if o.orgAccounts != nil {
+			// Managed accounts are read first, so that users are built with them.
+			bag.Pop()
+			bag.Push(pagination.PageState{ResourceTypeID: orgAccountsPageState})
+		}
+	}
+
 	switch bag.ResourceTypeID() {
+	case orgAccountsPageState:
+		nextCursor, ratelimitData, err := o.orgAccounts.loadPage(ctx, bag.PageToken())
+		outputAnnotations = WithRateLimitAnnotations(ratelimitData)
+		if err != nil {
+			return nil, syncResults("", outputAnnotations), err
+		}
+
+		err = bag.Next(nextCursor)
+		if err != nil {
+			return nil, syncResults("", outputAnnotations), err
+		}
+
+		if bag.Current() == nil {
+			logger.Debug("Finished reading the accounts of the organization, moving on to User Search")
+			bag.Push(pagination.PageState{})
+		}
+
 	case "":
-		users, nextToken, ratelimitData, err := o.client.GetUsersFromSearch(ctx, page, size)
+		users, nextToken, ratelimitData, err := o.client.UsersFromSearch().
+			WithPageSize(size).
+			Page(ctx, page)
 		outputAnnotations = WithRateLimitAnnotations(ratelimitData)
 		if err != nil {
 			return nil, syncResults("", outputAnnotations), err
@@ -141,12 +223,13 @@ func (o *userResourceType) List(
 			}
 
 			userCopy := user
-			newUserResource, err := userResource(ctx, &userCopy)
+			newUserResource, ratelimitData, err := o.userResource(ctx, &userCopy)
 			if err != nil {
-				return nil, nil, err
+				return nil, syncResults("", WithRateLimitAnnotations(ratelimitData)), err
 			}
 
 			outputResources = append(outputResources, newUserResource)
+			o.globalPermissions.add(&userCopy)
 		}
 
 		err = bag.Next(nextToken)
@@ -156,6 +239,7 @@ func (o *userResourceType) List(
 
 		if bag.Current() == nil {
 			logger.Debug("Finished User Search, moving on to 2D query")
+			o.globalPermissions.finish()
 			bag.Push(
 				pagination.PageState{
 					// Using "user" here as a placeholder so the for loop know to start again.
@@ -173,11 +257,9 @@ func (o *userResourceType) List(
 		size = limitPageSizeForGroups(size)
 
 		// Add a new page of groups
-		groups, nextToken, ratelimitData, err := o.client.GetGroups(
-			ctx,
-			page,
-			size,
-		)
+		groups, nextToken, ratelimitData, err := o.client.Groups().
+			WithPageSize(size).
+			Page(ctx, page)
 		logger.Debug(
 			"Got groups",
 			zap.Int("len", len(groups)),
@@ -208,25 +290,25 @@ func (o *userResourceType) List(
 			)
 		}
 
+		// The bag is a stack: the groups are walked in reverse.
+		for i := len(groups) - 1; i >= 0; i-- {
+			o.groupMembers.expect(groups[i].Id)
+		}
+
 	case resourceTypeGroup.Id:
 		currentState := bag.Current()
-
-		start := currentState.Token
-		if start == "" {
-			start = "0"
-		}
 		logger.Debug(
 			"Got a group from the bag",
-			zap.String("start", start),
+			zap.String("start", currentState.Token),
 			zap.String("group_id", currentState.ResourceID),
 		)
 
 		// Get users for this group.
-		users, nextToken, ratelimitData, err := o.client.GetGroupMembers(
+		users, nextToken, ratelimitData, err := o.groupMembers.page(
 			ctx,
-			start,
-			size,
 			currentState.ResourceID,
+			currentState.Token,
+			size,
 		)
 		outputAnnotations = WithRateLimitAnnotations(ratelimitData)
 		if err != nil {
@@ -246,9 +328,9 @@ func (o *userResourceType) List(
 			}
 
 			userCopy := user
-			newUserResource, err := userResource(ctx, &userCopy)
+			newUserResource, ratelimitData, err := o.userResource(ctx, &userCopy)
 			if err != nil {
-				return nil, nil, err
+				return nil, syncResults("", WithRateLimitAnnotations(ratelimitData)), err
 			}
 
 			outputResources = append(outputResources, newUserResource)
@@ -282,9 +364,25 @@ func (o *userResourceType) Grants(
 	return nil, nil, nil
 }
 
-func userBuilder(client *client.ConfluenceClient) *userResourceType {
+func userBuilder(
+	c *client.ConfluenceClient,
+	orgAdmin *client.OrgAdminClient,
+	globalPermissions *globalPermissionHolders,
+	concurrency int,
+) *userResourceType {
 	return &userResourceType{
-		resourceType: resourceTypeUser,
-		client:       client,
+		resourceType:      resourceTypeUser,
+		client:            c,
+		orgAccounts:       newOrgAccounts(orgAdmin, c.Site()),
+		globalPermissions: globalPermissions,
+		groupMembers: newPrefetcher(
+			concurrency,
+			func(ctx context.Context, groupId string, start string, pageSize int) ([]client.ConfluenceUser, string, *v2.RateLimitDescription, error) {
+				if start == "" {
+					start = "0"
+				}
+				return c.GroupMembers(groupId).WithPageSize(pageSize).Page(ctx, start)
+			},
+		),
 	}
 }
@@ -2,7 +2,11 @@ package connector
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/conductorone/baton-confluence/pkg/connector/client"
 	"github.com/conductorone/baton-confluence/test"
@@ -29,7 +33,7 @@ func TestUsersList(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		c := userBuilder(confluenceClient)
+		c := userBuilder(confluenceClient, nil, nil, 1)
 
 		resources := make([]*v2.Resource, 0)
 		pToken := pagination.Token{Size: 2}
@@ -61,3 +65,108 @@ func TestUsersList(t *testing.T) {
 		require.Equal(t, allIDs.Cardinality(), 3)
 	})
 }
+
+func TestUsersWithOrgAccounts(t *testing.T) {
+	ctx := context.Background()
+	site, server := test.FakeServer(t)
+	site.AddUser(client.ConfluenceUser{AccountId: "dave", DisplayName: "Dave Doe"})
+	site.SetOrg("org-1")
+	site.AddOrgUser(client.OrgUser{
+		AccountId:     "alice",
+		Email:         "alice@example.com",
+		EmailVerified: true,
+		LastActive:    "2024-06-01",
+		ProductAccess: []client.OrgProductAccess{
+			{Key: "confluence.ondemand", Url: server.URL, LastActive: "2024-05-01"},
+		},
+	})
+	site.AddOrgUser(client.OrgUser{AccountId: "carol", AccountStatus: client.OrgAccountStatusInactive, Email: "carol@example.com"})
+	site.AddOrgUser(client.OrgUser{AccountId: "dave", Email: "dave@example.com", EmailVerified: true, LastActive: "2024-06-02T10:00:00Z"})
+	// Enough accounts for a second page.
+	for i := range 30 {
+		site.AddOrgUser(client.OrgUser{AccountId: fmt.Sprintf("managed-%02d", i)})
+	}
+
+	confluenceClient, err := client.NewConfluenceClient(ctx, "admin", "API Key", server.URL)
+	require.Nil(t, err)
+	orgAdmin, err := client.NewOrgAdminClient(ctx, "org-1", "Admin Key", server.URL)
+	require.Nil(t, err)
+	c := userBuilder(confluenceClient, orgAdmin, nil, 1)
+
+	orgRequests := func() int {
+		return len(slices.DeleteFunc(site.Requests(), func(request string) bool {
+			return !strings.Contains(request, "/admin/v1/orgs/org-1/users")
+		}))
+	}
+
+	users := make(map[string]*v2.Resource)
+	pToken := pagination.Token{}
+	var tokens []string
+	for calls := 0; ; calls++ {
+		resources, results, err := c.List(ctx, nil, resource.SyncOpAttrs{PageToken: pToken})
+		require.Nil(t, err)
+		if calls < 2 {
+			// The managed accounts are read a page per call, before any user.
+			require.Empty(t, resources)
+			require.Equal(t, calls+1, orgRequests())
+		}
+		for _, r := range resources {
+			users[r.Id.Resource] = r
+		}
+		if results.NextPageToken == "" {
+			break
+		}
+		pToken.Token = results.NextPageToken
+		tokens = append(tokens, pToken.Token)
+	}
+	require.Equal(t, 2, orgRequests())
+
+	userTrait := func(t *testing.T, accountId string) *v2.UserTrait {
+		require.Contains(t, users, accountId)
+		trait, err := resource.GetUserTrait(users[accountId])
+		require.Nil(t, err)
+		return trait
+	}
+
+	t.Run("should read the status and Confluence activity of managed accounts", func(t *testing.T) {
+		trait := userTrait(t, "alice")
+		require.Equal(t, v2.UserTrait_Status_STATUS_ENABLED, trait.GetStatus().GetStatus())
+		require.Equal(t, "alice@example.com", trait.GetLogin())
+		require.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), trait.GetLastLogin().AsTime())
+
+		trait = userTrait(t, "carol")
+		require.Equal(t, v2.UserTrait_Status_STATUS_DISABLED, trait.GetStatus().GetStatus())
+		require.Equal(t, client.OrgAccountStatusInactive, trait.GetStatus().GetDetails())
+		require.Nil(t, trait.GetLastLogin())
+		require.Equal(t, v2.Status_RESOURCE_STATUS_DISABLED, users["carol"].GetStatus().GetStatus())
+	})
+
+	t.Run("should use the verified email of managed accounts hidden by Confluence", func(t *testing.T) {
+		trait := userTrait(t, "dave")
+		require.Equal(t, "dave@example.com", trait.GetEmails()[0].GetAddress())
+		require.Equal(t, time.Date(2024, 6, 2, 10, 0, 0, 0, time.UTC), trait.GetLastLogin().AsTime())
+		require.Equal(t, v2.Status_RESOURCE_STATUS_ENABLED, users["dave"].GetStatus().GetStatus())
+	})
+
+	t.Run("should read the accounts when a sync resumes after them", func(t *testing.T) {
+		resumed := userBuilder(confluenceClient, orgAdmin, nil, 1)
+		resources, _, err := resumed.List(ctx, nil, resource.SyncOpAttrs{PageToken: pagination.Token{Token: tokens[1]}})
+		require.Nil(t, err)
+		require.Equal(t, 4, orgRequests())
+
+		for _, r := range resources {
+			if r.Id.Resource == "carol" {
+				trait, err := resource.GetUserTrait(r)
+				require.Nil(t, err)
+				require.Equal(t, v2.UserTrait_Status_STATUS_DISABLED, trait.GetStatus().GetStatus())
+			}
+		}
+	})
+
+	t.Run("should fall back to Confluence operations for other accounts", func(t *testing.T) {
+		trait := userTrait(t, "bob")
+		require.Empty(t, trait.GetLogin())
+		require.Nil(t, trait.GetLastLogin())
+		require.Equal(t, v2.Status_RESOURCE_STATUS_ENABLED, users["bob"].GetStatus().GetStatus())
+	})
+}
@@ -0,0 +1,113 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+)
+
+var (
+	cqlSpaceTitle   = regexp.MustCompile(`type\s*=\s*space\s+AND\s+title\s*=\s*"((?:[^"\\]|\\.)*)"`)
+	cqlUserFullName = regexp.MustCompile(`type\s*=\s*user\s+AND\s+user\.fullname\s*~\s*"((?:[^"\\]|\\.)*)"`)
+)
+
+// AddAuditRecord appends a record to the audit log.
+func (s *Server) AddAuditRecord(record client.AuditRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auditRecords = append(s.auditRecords, record)
+}
+
+// listAuditRecords serves the audit log newest first, filtered by the
+// optional startDate and endDate bounds, both inclusive. Only Confluence
+// admins can read it.
+func (s *Server) listAuditRecords(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findUser(s.currentUser)
+	if user == nil || !slices.Contains(user.Operations, client.ConfluenceOperation{Operation: "administer", TargetType: "application"}) {
+		writeError(writer, http.StatusForbidden, "Only Confluence admins can read the audit log")
+		return
+	}
+
+	query := request.URL.Query()
+	startDate, _ := strconv.ParseInt(query.Get("startDate"), 10, 64)
+	endDate, _ := strconv.ParseInt(query.Get("endDate"), 10, 64)
+
+	records := make([]client.AuditRecord, 0, len(s.auditRecords))
+	for _, record := range s.auditRecords {
+		if startDate > 0 && record.CreationDate < startDate {
+			continue
+		}
+		if endDate > 0 && record.CreationDate > endDate {
+			continue
+		}
+		records = append(records, record)
+	}
+	slices.SortStableFunc(records, func(a, b client.AuditRecord) int {
+		return int(b.CreationDate - a.CreationDate)
+	})
+
+	start, end, next := offsetPage(request, len(records))
+	writeJSON(writer, http.StatusOK, offsetList{
+		Start:   start,
+		Limit:   end - start,
+		Size:    end - start,
+		Links:   client.ConfluenceLink{Next: next},
+		Results: records[start:end],
+	})
+}
+
+func (s *Server) getGroupByName(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := request.URL.Query().Get("name")
+	for _, group := range s.groups {
+		if group.Name == name {
+			writeJSON(writer, http.StatusOK, groupResult{Type: "group", Name: group.Name, Id: group.Id})
+			return
+		}
+	}
+	writeError(writer, http.StatusNotFound, "No group found with the given name")
+}
+
+// searchSpaces answers CQL searches for a space by its title.
+func (s *Server) searchSpaces(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	match := cqlSpaceTitle.FindStringSubmatch(request.URL.Query().Get("cql"))
+	if match == nil {
+		writeError(writer, http.StatusBadRequest, "Unsupported CQL query")
+		return
+	}
+	title, _ := strconv.Unquote(`"` + match[1] + `"`)
+
+	results := make([]client.ConfluenceSearch, 0)
+	for _, space := range s.spaces {
+		if space.Name != title {
+			continue
+		}
+		results = append(results, client.ConfluenceSearch{
+			EntityType: "space",
+			Title:      space.Name,
+			Space: &client.ConfluenceSearchSpace{
+				Id:   json.Number(space.Id),
+				Key:  space.Key,
+				Name: space.Name,
+			},
+		})
+	}
+	writeJSON(writer, http.StatusOK, client.ConfluenceSearchList{
+		Limit:     len(results),
+		Size:      len(results),
+		TotalSize: len(results),
+		Results:   results,
+	})
+}
@@ -0,0 +1,282 @@
+package fake
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+)
+
+// Content is a page or a blog post. Pages can have a parent page.
+type Content struct {
+	Id       string
+	Type     string
+	Title    string
+	SpaceId  string
+	ParentId string
+	// Restrictions maps "read" and "update" to who they are limited to.
+	Restrictions map[string]*Restriction
+}
+
+// Restriction lists the account IDs and group IDs an operation on a piece of
+// content is limited to.
+type Restriction struct {
+	Users  []string
+	Groups []string
+}
+
+var (
+	cqlSpaceId = regexp.MustCompile(`space\.id\s*=\s*"?(\w+)"?`)
+	cqlType    = regexp.MustCompile(`type\s*=\s*"?(\w+)"?`)
+)
+
+// AddContent adds a page or blog post.
+func (s *Server) AddContent(content Content) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if content.Restrictions == nil {
+		content.Restrictions = make(map[string]*Restriction)
+	}
+	s.contents = append(s.contents, &content)
+}
+
+// Restrict limits an operation on a piece of content to a user ("user") or
+// a group ("group").
+func (s *Server) Restrict(contentId string, operation string, principalType string, principalId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content := s.findContent(contentId)
+	if content == nil {
+		return
+	}
+	restriction := content.restriction(operation)
+	switch principalType {
+	case "user":
+		restriction.Users = append(restriction.Users, principalId)
+	case "group":
+		restriction.Groups = append(restriction.Groups, principalId)
+	}
+}
+
+// ContentRestriction returns who an operation on a piece of content is
+// limited to.
+func (s *Server) ContentRestriction(contentId string, operation string) Restriction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content := s.findContent(contentId)
+	if content == nil || content.Restrictions[operation] == nil {
+		return Restriction{}
+	}
+	restriction := content.Restrictions[operation]
+	return Restriction{
+		Users:  slices.Clone(restriction.Users),
+		Groups: slices.Clone(restriction.Groups),
+	}
+}
+
+func (c *Content) restriction(operation string) *Restriction {
+	if c.Restrictions[operation] == nil {
+		c.Restrictions[operation] = &Restriction{}
+	}
+	return c.Restrictions[operation]
+}
+
+func (s *Server) findContent(contentId string) *Content {
+	for _, content := range s.contents {
+		if content.Id == contentId {
+			return content
+		}
+	}
+	return nil
+}
+
+// contentRestriction returns a page of the users and groups of a restriction.
+// Both are paged together, with the same start and limit.
+func (s *Server) contentRestriction(content *Content, operation string, start int, limit int) client.ConfluenceContentRestriction {
+	rv := client.ConfluenceContentRestriction{Operation: operation}
+	rv.Restrictions.User.Start, rv.Restrictions.User.Limit = start, limit
+	rv.Restrictions.Group.Start, rv.Restrictions.Group.Limit = start, limit
+	restriction := content.Restrictions[operation]
+	if restriction == nil {
+		return rv
+	}
+	for _, accountId := range restriction.Users[min(start, len(restriction.Users)):min(start+limit, len(restriction.Users))] {
+		if user := s.findUser(accountId); user != nil {
+			rv.Restrictions.User.Results = append(rv.Restrictions.User.Results, *user)
+		}
+	}
+	for _, groupId := range restriction.Groups[min(start, len(restriction.Groups)):min(start+limit, len(restriction.Groups))] {
+		if group := s.findGroup(groupId); group != nil {
+			rv.Restrictions.Group.Results = append(rv.Restrictions.Group.Results, client.ConfluenceGroup{
+				Type: "group",
+				Name: group.Name,
+				Id:   group.Id,
+			})
+		}
+	}
+	rv.Restrictions.User.Size = len(rv.Restrictions.User.Results)
+	rv.Restrictions.Group.Size = len(rv.Restrictions.Group.Results)
+	return rv
+}
+
+func (s *Server) contentRestrictions(content *Content) client.ConfluenceContentRestrictions {
+	return client.ConfluenceContentRestrictions{
+		Read:   s.contentRestriction(content, client.ContentOperationRead, 0, maxRestrictionLimit),
+		Update: s.contentRestriction(content, client.ContentOperationUpdate, 0, maxRestrictionLimit),
+	}
+}
+
+// searchContent only understands the `space.id = X AND type = Y` CQL queries
+// the connector sends. Content is always returned with its restrictions and
+// the read restrictions of its ancestors, from the root down.
+func (s *Server) searchContent(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cql := request.URL.Query().Get("cql")
+	spaceMatch := cqlSpaceId.FindStringSubmatch(cql)
+	typeMatch := cqlType.FindStringSubmatch(cql)
+	if spaceMatch == nil || typeMatch == nil {
+		writeError(writer, http.StatusBadRequest, "Unsupported CQL query")
+		return
+	}
+
+	results := make([]client.ConfluenceContent, 0)
+	for _, content := range s.contents {
+		if content.SpaceId != spaceMatch[1] || content.Type != typeMatch[1] {
+			continue
+		}
+		ancestors := make([]client.ConfluenceContent, 0)
+		for parent := s.findContent(content.ParentId); parent != nil; parent = s.findContent(parent.ParentId) {
+			ancestors = slices.Insert(ancestors, 0, client.ConfluenceContent{
+				Id:     parent.Id,
+				Type:   parent.Type,
+				Status: "current",
+				Title:  parent.Title,
+				Restrictions: client.ConfluenceContentRestrictions{
+					Read: s.contentRestriction(parent, client.ContentOperationRead, 0, maxRestrictionLimit),
+				},
+			})
+		}
+		results = append(results, client.ConfluenceContent{
+			Id:           content.Id,
+			Type:         content.Type,
+			Status:       "current",
+			Title:        content.Title,
+			Ancestors:    ancestors,
+			Restrictions: s.contentRestrictions(content),
+		})
+	}
+
+	start, end, next, err := cursorPage(request, len(results))
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(writer, http.StatusOK, cursorList{
+		Links:   client.ConfluenceLink{Next: next},
+		Results: results[start:end],
+	})
+}
+
+// getContentRestriction pages the users and groups of the restriction of an
+// operation with v1 `start` and `limit` parameters.
+func (s *Server) getContentRestriction(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content := s.findContent(request.PathValue("contentId"))
+	if content == nil {
+		writeError(writer, http.StatusNotFound, "No content found with the given ID")
+		return
+	}
+	operation := request.PathValue("operation")
+	if operation != client.ContentOperationRead && operation != client.ContentOperationUpdate {
+		writeError(writer, http.StatusBadRequest, "Invalid operation")
+		return
+	}
+	query := request.URL.Query()
+	start, _ := strconv.Atoi(query.Get("start"))
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultOffsetLimit
+	}
+	writeJSON(writer, http.StatusOK, s.contentRestriction(content, operation, max(start, 0), min(limit, maxRestrictionLimit)))
+}
+
+// restrictionSubject reads the user or group a restriction request is about:
+// users are passed by account ID in the query and groups by ID in the path.
+func (s *Server) restrictionSubject(request *http.Request) (string, string, bool) {
+	if groupId := request.PathValue("groupId"); groupId != "" {
+		return "group", groupId, s.findGroup(groupId) != nil
+	}
+	accountId := request.URL.Query().Get("accountId")
+	return "user", accountId, s.findUser(accountId) != nil
+}
+
+// addContentRestriction succeeds when the subject is already in the
+// restriction, like Confluence.
+func (s *Server) addContentRestriction(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content := s.findContent(request.PathValue("contentId"))
+	if content == nil {
+		writeError(writer, http.StatusNotFound, "No content found with the given ID")
+		return
+	}
+	operation := request.PathValue("operation")
+	if operation != client.ContentOperationRead && operation != client.ContentOperationUpdate {
+		writeError(writer, http.StatusBadRequest, "Invalid operation")
+		return
+	}
+	principalType, principalId, ok := s.restrictionSubject(request)
+	if !ok {
+		writeError(writer, http.StatusBadRequest, "No user or group found with the given ID")
+		return
+	}
+
+	restriction := content.restriction(operation)
+	switch principalType {
+	case "user":
+		if !slices.Contains(restriction.Users, principalId) {
+			restriction.Users = append(restriction.Users, principalId)
+		}
+	case "group":
+		if !slices.Contains(restriction.Groups, principalId) {
+			restriction.Groups = append(restriction.Groups, principalId)
+		}
+	}
+	writer.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) removeContentRestriction(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content := s.findContent(request.PathValue("contentId"))
+	if content == nil {
+		writeError(writer, http.StatusNotFound, "No content found with the given ID")
+		return
+	}
+	principalType, principalId, _ := s.restrictionSubject(request)
+	restriction := content.Restrictions[request.PathValue("operation")]
+	if restriction == nil {
+		writeError(writer, http.StatusNotFound, "The subject is not in the restriction")
+		return
+	}
+
+	subjects := &restriction.Users
+	if principalType == "group" {
+		subjects = &restriction.Groups
+	}
+	index := slices.Index(*subjects, principalId)
+	if index < 0 {
+		writeError(writer, http.StatusNotFound, "The subject is not in the restriction")
+		return
+	}
+	*subjects = slices.Delete(*subjects, index, index+1)
+	writer.WriteHeader(http.StatusNoContent)
+}
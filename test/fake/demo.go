@@ -0,0 +1,137 @@
+package fake
+
+import (
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+)
+
+// activeUserOperations are the operations Confluence reports for users that
+// are allowed to use the product.
+var activeUserOperations = []client.ConfluenceOperation{
+	{Operation: "use", TargetType: "application"},
+}
+
+// adminOperations are the operations Confluence reports for site admins.
+var adminOperations = []client.ConfluenceOperation{
+	{Operation: "use", TargetType: "application"},
+	{Operation: "create", TargetType: "space"},
+	{Operation: "administer", TargetType: "application"},
+}
+
+// NewDemo returns a server seeded with a small site: a handful of users and
+// groups, a global and a personal space with permissions, the default space
+// roles with a few assignments, and pages and a blog post, some of them
+// restricted.
+func NewDemo() *Server {
+	s := New()
+
+	s.AddUser(client.ConfluenceUser{
+		AccountId:   "admin",
+		DisplayName: "Site Admin",
+		Email:       "admin@example.com",
+		Operations:  adminOperations,
+	})
+	s.AddUser(client.ConfluenceUser{
+		AccountId:   "alice",
+		DisplayName: "Alice Doe",
+		Email:       "alice@example.com",
+		Operations:  activeUserOperations,
+	})
+	s.AddUser(client.ConfluenceUser{
+		AccountId:   "bob",
+		DisplayName: "Bob Doe",
+		Email:       "bob@example.com",
+		Operations:  activeUserOperations,
+	})
+	s.AddUser(client.ConfluenceUser{
+		AccountId:   "carol",
+		DisplayName: "Carol Doe (deactivated)",
+		Email:       "carol@example.com",
+	})
+	s.AddUser(client.ConfluenceUser{
+		AccountId:   "automation",
+		AccountType: "app",
+		DisplayName: "Automation for Confluence",
+		Operations:  activeUserOperations,
+	})
+
+	s.AddGroup("group-admins", "confluence-admins", "admin")
+	s.AddGroup("group-users", "confluence-users", "admin", "alice", "bob", "carol")
+	s.AddGroup("group-engineering", "engineering", "alice")
+
+	s.AddSpace(client.ConfluenceSpace{
+		Id:          "100",
+		Key:         "ENG",
+		Name:        "Engineering",
+		Description: client.ConfluenceSpaceDescription{Plain: client.ConfluenceSpaceDescriptionValue{Value: "Design docs and runbooks", Representation: "plain"}},
+		HomepageId:  "1001",
+		AuthorId:    "admin",
+		CreatedAt:   "2023-01-16T09:30:00.000Z",
+	})
+	s.AddSpace(client.ConfluenceSpace{
+		Id:         "200",
+		Key:        "~alice",
+		Name:       "Alice Doe",
+		Type:       "personal",
+		HomepageId: "2001",
+		AuthorId:   "alice",
+		CreatedAt:  "2023-03-02T14:00:00.000Z",
+	})
+
+	for _, operation := range [][2]string{
+		{"read", "space"},
+		{"create", "page"},
+		{"administer", "space"},
+	} {
+		s.AddSpacePermission("100", "group", "group-admins", operation[0], operation[1])
+	}
+	s.AddSpacePermission("100", "group", "group-engineering", "read", "space")
+	s.AddSpacePermission("100", "group", "group-engineering", "create", "page")
+	s.AddSpacePermission("100", "user", "bob", "read", "space")
+	s.AddSpacePermission("200", "user", "alice", "read", "space")
+	s.AddSpacePermission("200", "user", "alice", "administer", "space")
+
+	s.AddSpaceRole(client.SpaceRole{
+		Id:               "role-viewer",
+		Type:             "DEFAULT",
+		Name:             "Viewer",
+		Description:      "View content in spaces",
+		SpacePermissions: []string{"read-space"},
+	})
+	s.AddSpaceRole(client.SpaceRole{
+		Id:               "role-collaborator",
+		Type:             "DEFAULT",
+		Name:             "Collaborator",
+		Description:      "Create and edit content in spaces",
+		SpacePermissions: []string{"read-space", "create-page"},
+	})
+	s.AddSpaceRole(client.SpaceRole{
+		Id:               "role-admin",
+		Type:             "DEFAULT",
+		Name:             "Admin",
+		Description:      "Administer spaces",
+		SpacePermissions: []string{"read-space", "create-page", "administer-space"},
+	})
+	s.AddSpaceRole(client.SpaceRole{
+		Id:          "role-no-access",
+		Type:        "DEFAULT",
+		Name:        "No access",
+		Description: "No access to spaces",
+	})
+
+	s.AssignSpaceRole("100", "GROUP", "group-admins", "role-admin")
+	s.AssignSpaceRole("100", "GROUP", "group-engineering", "role-collaborator")
+	s.AssignSpaceRole("100", "USER", "bob", "role-viewer")
+	s.AssignSpaceRole("100", "ACCESS_CLASS", "all-licensed-users", "role-collaborator")
+	s.AssignSpaceRole("200", "USER", "alice", "role-admin")
+
+	s.AddContent(Content{Id: "500", Type: "page", Title: "Handbook", SpaceId: "100"})
+	s.AddContent(Content{Id: "501", Type: "page", Title: "HR", SpaceId: "100"})
+	s.AddContent(Content{Id: "502", Type: "page", Title: "Salaries", SpaceId: "100", ParentId: "501"})
+	s.AddContent(Content{Id: "503", Type: "page", Title: "Onboarding", SpaceId: "100", ParentId: "500"})
+	s.AddContent(Content{Id: "510", Type: "blogpost", Title: "Release notes", SpaceId: "100"})
+	s.Restrict("501", "read", "group", "group-admins")
+	s.Restrict("501", "update", "user", "alice")
+	s.Restrict("510", "update", "user", "bob")
+
+	return s
+}
@@ -0,0 +1,717 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+)
+
+type groupResult struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Id   string `json:"id"`
+}
+
+type offsetList struct {
+	Start   int                   `json:"start"`
+	Limit   int                   `json:"limit"`
+	Size    int                   `json:"size"`
+	Links   client.ConfluenceLink `json:"_links"`
+	Results interface{}           `json:"results"`
+}
+
+type cursorList struct {
+	Links   client.ConfluenceLink `json:"_links"`
+	Results interface{}           `json:"results"`
+}
+
+func (s *Server) getCurrentUser(writer http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findUser(s.currentUser)
+	if user == nil {
+		writeError(writer, http.StatusUnauthorized, "Current user not permitted to use Confluence")
+		return
+	}
+	writeJSON(writer, http.StatusOK, user)
+}
+
+func (s *Server) searchUsers(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := s.users
+	cql := request.URL.Query().Get("cql")
+	if match := cqlUserFullName.FindStringSubmatch(cql); match != nil {
+		fullName, _ := strconv.Unquote(`"` + match[1] + `"`)
+		users = slices.DeleteFunc(slices.Clone(s.users), func(user client.ConfluenceUser) bool {
+			return !strings.Contains(strings.ToLower(user.DisplayName), strings.ToLower(fullName))
+		})
+	} else if cql != "type=user" {
+		writeError(writer, http.StatusBadRequest, fmt.Sprintf("Unsupported CQL query: %q", cql))
+		return
+	}
+
+	// Search results have no "next" link, clients stop at the first short page.
+	start, end, _ := offsetPage(request, len(users))
+	results := make([]client.ConfluenceSearch, 0, end-start)
+	for _, user := range users[start:end] {
+		results = append(results, client.ConfluenceSearch{
+			EntityType: "user",
+			Title:      user.DisplayName,
+			User:       user,
+		})
+	}
+	writeJSON(writer, http.StatusOK, client.ConfluenceSearchList{
+		Start:     start,
+		Limit:     end - start,
+		Size:      len(results),
+		TotalSize: len(users),
+		Results:   results,
+	})
+}
+
+// getUser looks up a user by account ID.
+func (s *Server) getUser(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findUser(request.URL.Query().Get("accountId"))
+	if user == nil {
+		writeError(writer, http.StatusNotFound, "No user found with the given account ID")
+		return
+	}
+	writeJSON(writer, http.StatusOK, user)
+}
+
+func (s *Server) listGroups(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start, end, next := offsetPage(request, len(s.groups))
+	results := make([]groupResult, 0, end-start)
+	for _, group := range s.groups[start:end] {
+		results = append(results, groupResult{Type: "group", Name: group.Name, Id: group.Id})
+	}
+	writeJSON(writer, http.StatusOK, offsetList{
+		Start:   start,
+		Limit:   end - start,
+		Size:    len(results),
+		Links:   client.ConfluenceLink{Next: next},
+		Results: results,
+	})
+}
+
+func (s *Server) listGroupMembers(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group := s.findGroup(request.PathValue("groupId"))
+	if group == nil {
+		writeError(writer, http.StatusNotFound, "No group found with the given ID")
+		return
+	}
+
+	start, end, next := offsetPage(request, len(group.Members))
+	results := make([]client.ConfluenceUser, 0, end-start)
+	for _, accountId := range group.Members[start:end] {
+		if user := s.findUser(accountId); user != nil {
+			results = append(results, *user)
+		}
+	}
+	writeJSON(writer, http.StatusOK, offsetList{
+		Start:   start,
+		Limit:   end - start,
+		Size:    len(results),
+		Links:   client.ConfluenceLink{Next: next},
+		Results: results,
+	})
+}
+
+func (s *Server) createGroup(writer http.ResponseWriter, request *http.Request) {
+	var body client.CreateGroupRequestBody
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil || body.Name == "" {
+		writeError(writer, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, group := range s.groups {
+		if strings.EqualFold(group.Name, body.Name) {
+			writeError(writer, http.StatusBadRequest, "A group with this name already exists")
+			return
+		}
+	}
+	group := &Group{Id: s.newId(), Name: body.Name}
+	s.groups = append(s.groups, group)
+	writeJSON(writer, http.StatusCreated, groupResult{Type: "group", Name: group.Name, Id: group.Id})
+}
+
+// deleteGroup deletes a group along with the space permissions granted to
+// it, like Confluence does.
+func (s *Server) deleteGroup(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	groupId := request.URL.Query().Get("id")
+	index := slices.IndexFunc(s.groups, func(group *Group) bool {
+		return group.Id == groupId
+	})
+	if index < 0 {
+		writeError(writer, http.StatusNotFound, "No group found with the given ID")
+		return
+	}
+	s.groups = slices.Delete(s.groups, index, index+1)
+	for spaceId, permissions := range s.permissions {
+		s.permissions[spaceId] = slices.DeleteFunc(permissions, func(permission client.ConfluenceSpacePermission) bool {
+			return permission.Principal.Type == "group" && permission.Principal.Id == groupId
+		})
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) addGroupMember(writer http.ResponseWriter, request *http.Request) {
+	var body client.AddUserToGroupRequestBody
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writeError(writer, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group := s.findGroup(request.URL.Query().Get("groupId"))
+	if group == nil {
+		writeError(writer, http.StatusNotFound, "No group found with the given ID")
+		return
+	}
+	if s.findUser(body.AccountId) == nil {
+		writeError(writer, http.StatusBadRequest, "No user found with the given account ID")
+		return
+	}
+	if !slices.Contains(group.Members, body.AccountId) {
+		group.Members = append(group.Members, body.AccountId)
+	}
+	writer.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) removeGroupMember(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := request.URL.Query()
+	group := s.findGroup(query.Get("groupId"))
+	if group == nil {
+		writeError(writer, http.StatusNotFound, "No group found with the given ID")
+		return
+	}
+	group.Members = slices.DeleteFunc(group.Members, func(accountId string) bool {
+		return accountId == query.Get("accountId")
+	})
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// listSpaces supports the keys, type, status and labels filters. keys and
+// labels are comma separated lists of which a space must match one.
+func (s *Server) listSpaces(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := request.URL.Query()
+	spaces := make([]client.ConfluenceSpace, 0, len(s.spaces))
+	for _, space := range s.spaces {
+		if keys := query.Get("keys"); keys != "" && !slices.Contains(strings.Split(keys, ","), space.Key) {
+			continue
+		}
+		if spaceType := query.Get("type"); spaceType != "" && space.Type != spaceType {
+			continue
+		}
+		if status := query.Get("status"); status != "" && space.Status != status {
+			continue
+		}
+		if labels := query.Get("labels"); labels != "" && !slices.ContainsFunc(strings.Split(labels, ","), func(label string) bool {
+			return slices.Contains(s.spaceLabels[space.Id], label)
+		}) {
+			continue
+		}
+		spaces = append(spaces, space)
+	}
+
+	start, end, next, err := cursorPage(request, len(spaces))
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(writer, http.StatusOK, cursorList{
+		Links:   client.ConfluenceLink{Next: next},
+		Results: spaces[start:end],
+	})
+}
+
+func (s *Server) listSpaceLabels(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	space := s.findSpaceById(request.PathValue("spaceId"))
+	if space == nil {
+		writeError(writer, http.StatusNotFound, "Space not found")
+		return
+	}
+
+	names := s.spaceLabels[space.Id]
+	start, end, next, err := cursorPage(request, len(names))
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+	labels := make([]client.ConfluenceLabel, 0, end-start)
+	for _, name := range names[start:end] {
+		labels = append(labels, client.ConfluenceLabel{Id: space.Id + "-" + name, Name: name, Prefix: "global"})
+	}
+	writeJSON(writer, http.StatusOK, cursorList{
+		Links:   client.ConfluenceLink{Next: next},
+		Results: labels,
+	})
+}
+
+// getSpace includes, when asked to, the distinct operations that are granted
+// on the space.
+func (s *Server) getSpace(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	space := s.findSpaceById(request.PathValue("spaceId"))
+	if space == nil {
+		writeError(writer, http.StatusNotFound, "Space not found")
+		return
+	}
+
+	response := *space
+	if request.URL.Query().Get("include-operations") != "" {
+		operations := make([]client.ConfluenceSpaceOperation, 0)
+		for _, permission := range s.permissions[space.Id] {
+			operation := client.ConfluenceSpaceOperation{
+				Operation:  permission.Operation.Key,
+				TargetType: permission.Operation.TargetType,
+			}
+			if !slices.Contains(operations, operation) {
+				operations = append(operations, operation)
+			}
+		}
+		response.Operations = client.ConfluenceSpaceOperationsResponse{Results: operations}
+	}
+	writeJSON(writer, http.StatusOK, response)
+}
+
+// createSpace creates a space and, like Confluence, makes its creator an
+// admin: with the read and administer permissions, and with the default role
+// that administers spaces once roles are enabled.
+func (s *Server) createSpace(writer http.ResponseWriter, request *http.Request) {
+	var body struct {
+		Key         string `json:"key"`
+		Name        string `json:"name"`
+		Description *struct {
+			Value string `json:"value"`
+		} `json:"description"`
+	}
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil || body.Key == "" || body.Name == "" {
+		writeError(writer, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findSpaceByKey(body.Key) != nil {
+		writeError(writer, http.StatusBadRequest, "A space with this key already exists")
+		return
+	}
+	space := client.ConfluenceSpace{
+		Id:        s.newId(),
+		Key:       body.Key,
+		Name:      body.Name,
+		Status:    client.SpaceStatusCurrent,
+		Type:      "global",
+		AuthorId:  s.currentUser,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if body.Description != nil {
+		space.Description.Plain.Value = body.Description.Value
+		space.Description.Plain.Representation = "plain"
+	}
+	s.spaces = append(s.spaces, space)
+
+	s.addSpacePermission(space.Id, "user", s.currentUser, "read", "space")
+	s.addSpacePermission(space.Id, "user", s.currentUser, "administer", "space")
+	if s.roleMode != "PRE_ROLES" {
+		for _, role := range s.roles {
+			if role.Type != client.SpaceRoleTypeCustom && slices.Contains(role.SpacePermissions, "administer-space") {
+				s.assignSpaceRole(space.Id, "USER", s.currentUser, role.Id)
+				break
+			}
+		}
+	}
+	writeJSON(writer, http.StatusOK, space)
+}
+
+// deleteSpace deletes a space right away, where Confluence would start a
+// long-running task.
+func (s *Server) deleteSpace(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := slices.IndexFunc(s.spaces, func(space client.ConfluenceSpace) bool {
+		return space.Key == request.PathValue("spaceKey")
+	})
+	if index < 0 {
+		writeError(writer, http.StatusNotFound, "No space found with the given key")
+		return
+	}
+	spaceId := s.spaces[index].Id
+	s.spaces = slices.Delete(s.spaces, index, index+1)
+	delete(s.permissions, spaceId)
+	delete(s.roleAssignments, spaceId)
+	delete(s.spaceLabels, spaceId)
+	writeJSON(writer, http.StatusAccepted, map[string]interface{}{"id": s.newId()})
+}
+
+// updateSpace only supports changing the status of a space.
+func (s *Server) updateSpace(writer http.ResponseWriter, request *http.Request) {
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writeError(writer, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	space := s.findSpaceByKey(request.PathValue("spaceKey"))
+	if space == nil {
+		writeError(writer, http.StatusNotFound, "No space found with the given key")
+		return
+	}
+	switch body.Status {
+	case "":
+	case client.SpaceStatusCurrent, client.SpaceStatusArchived:
+		space.Status = body.Status
+	default:
+		writeError(writer, http.StatusBadRequest, "Invalid space status")
+		return
+	}
+	id, _ := strconv.Atoi(space.Id)
+	writeJSON(writer, http.StatusOK, map[string]interface{}{
+		"id":     id,
+		"key":    space.Key,
+		"name":   space.Name,
+		"status": space.Status,
+	})
+}
+
+func (s *Server) listSpacePermissions(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	space := s.findSpaceById(request.PathValue("spaceId"))
+	if space == nil {
+		writeError(writer, http.StatusNotFound, "Space not found")
+		return
+	}
+
+	permissions := s.permissions[space.Id]
+	start, end, next, err := cursorPage(request, len(permissions))
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(writer, http.StatusOK, cursorList{
+		Links:   client.ConfluenceLink{Next: next},
+		Results: permissions[start:end],
+	})
+}
+
+// createSpacePermission mirrors the v1 endpoint, which addresses spaces by
+// key and refuses to grant a permission twice.
+func (s *Server) createSpacePermission(writer http.ResponseWriter, request *http.Request) {
+	var body client.CreateSpacePermissionRequestBody
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writeError(writer, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	space := s.findSpaceByKey(request.PathValue("spaceKey"))
+	if space == nil {
+		writeError(writer, http.StatusNotFound, "No space found with the given key")
+		return
+	}
+	if !s.principalExists(body.Subject.Type, body.Subject.Identifier) {
+		writeError(writer, http.StatusBadRequest, "No subject found with the given identifier")
+		return
+	}
+	for _, permission := range s.permissions[space.Id] {
+		if permission.Principal.Type == body.Subject.Type &&
+			permission.Principal.Id == body.Subject.Identifier &&
+			permission.Operation.Key == body.Operation.Key &&
+			permission.Operation.TargetType == body.Operation.Target {
+			writeError(writer, http.StatusBadRequest, "Permission already exists")
+			return
+		}
+	}
+
+	id := s.addSpacePermission(space.Id, body.Subject.Type, body.Subject.Identifier, body.Operation.Key, body.Operation.Target)
+	writeJSON(writer, http.StatusOK, map[string]interface{}{
+		"id":        id,
+		"subject":   body.Subject,
+		"operation": body.Operation,
+	})
+}
+
+func (s *Server) deleteSpacePermission(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	space := s.findSpaceByKey(request.PathValue("spaceKey"))
+	if space == nil {
+		writeError(writer, http.StatusNotFound, "No space found with the given key")
+		return
+	}
+	permissionId := request.PathValue("permissionId")
+	permissions := s.permissions[space.Id]
+	index := slices.IndexFunc(permissions, func(permission client.ConfluenceSpacePermission) bool {
+		return permission.Id == permissionId
+	})
+	if index < 0 {
+		writeError(writer, http.StatusNotFound, "No permission found with the given ID")
+		return
+	}
+	s.permissions[space.Id] = slices.Delete(permissions, index, index+1)
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// listSpaceRoles serves the site-wide role catalog, which applies to every
+// space.
+func (s *Server) listSpaceRoles(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roleMode == "PRE_ROLES" {
+		writeError(writer, http.StatusNotFound, "Space roles are not enabled")
+		return
+	}
+	if spaceId := request.URL.Query().Get("space-id"); spaceId != "" && s.findSpaceById(spaceId) == nil {
+		writeError(writer, http.StatusNotFound, "Space not found")
+		return
+	}
+
+	start, end, next, err := cursorPage(request, len(s.roles))
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(writer, http.StatusOK, client.SpaceRolesResponse{
+		Links:   client.ConfluenceLink{Next: next},
+		Results: s.roles[start:end],
+	})
+}
+
+func (s *Server) getSpaceRole(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	role := s.findRole(request.PathValue("roleId"))
+	if role == nil {
+		writeError(writer, http.StatusNotFound, "Space role not found")
+		return
+	}
+	writeJSON(writer, http.StatusOK, role)
+}
+
+// createSpaceRole defines a custom role. Like Confluence, it allows at most
+// maxCustomRoles of them and refuses duplicate names.
+func (s *Server) createSpaceRole(writer http.ResponseWriter, request *http.Request) {
+	var body client.SpaceRoleRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil || body.Name == "" {
+		writeError(writer, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	customRoles := 0
+	for _, role := range s.roles {
+		if role.Name == body.Name {
+			writeError(writer, http.StatusConflict, "A space role with this name already exists")
+			return
+		}
+		if role.Type == client.SpaceRoleTypeCustom {
+			customRoles++
+		}
+	}
+	if customRoles >= maxCustomRoles {
+		writeError(writer, http.StatusBadRequest, "The maximum number of custom space roles has been reached")
+		return
+	}
+
+	role := client.SpaceRole{
+		Id:               s.newId(),
+		Type:             client.SpaceRoleTypeCustom,
+		Name:             body.Name,
+		Description:      body.Description,
+		SpacePermissions: body.SpacePermissions,
+	}
+	s.roles = append(s.roles, role)
+	writeJSON(writer, http.StatusCreated, role)
+}
+
+// updateSpaceRole replaces a custom role. Default roles cannot be changed.
+func (s *Server) updateSpaceRole(writer http.ResponseWriter, request *http.Request) {
+	var body client.SpaceRoleRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil || body.Name == "" {
+		writeError(writer, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	role := s.findRole(request.PathValue("roleId"))
+	if role == nil {
+		writeError(writer, http.StatusNotFound, "Space role not found")
+		return
+	}
+	if role.Type != client.SpaceRoleTypeCustom {
+		writeError(writer, http.StatusBadRequest, "Default space roles cannot be changed")
+		return
+	}
+	role.Name = body.Name
+	role.Description = body.Description
+	role.SpacePermissions = body.SpacePermissions
+	writeJSON(writer, http.StatusOK, role)
+}
+
+// deleteSpaceRole deletes a custom role and the assignments of it.
+func (s *Server) deleteSpaceRole(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	role := s.findRole(request.PathValue("roleId"))
+	if role == nil {
+		writeError(writer, http.StatusNotFound, "Space role not found")
+		return
+	}
+	if role.Type != client.SpaceRoleTypeCustom {
+		writeError(writer, http.StatusBadRequest, "Default space roles cannot be deleted")
+		return
+	}
+	roleId := role.Id
+	s.roles = slices.DeleteFunc(s.roles, func(role client.SpaceRole) bool { return role.Id == roleId })
+	for spaceId, assignments := range s.roleAssignments {
+		s.roleAssignments[spaceId] = slices.DeleteFunc(assignments, func(assignment client.SpaceRoleAssignment) bool {
+			return assignment.RoleId == roleId
+		})
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listRoleAssignments(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	space := s.findSpaceById(request.PathValue("spaceId"))
+	if space == nil {
+		writeError(writer, http.StatusNotFound, "Space not found")
+		return
+	}
+
+	query := request.URL.Query()
+	assignments := make([]client.SpaceRoleAssignment, 0)
+	for _, assignment := range s.roleAssignments[space.Id] {
+		if roleId := query.Get("role-id"); roleId != "" && assignment.RoleId != roleId {
+			continue
+		}
+		if principalId := query.Get("principal-id"); principalId != "" && assignment.Principal.PrincipalId != principalId {
+			continue
+		}
+		if principalType := query.Get("principal-type"); principalType != "" && assignment.Principal.PrincipalType != principalType {
+			continue
+		}
+		assignments = append(assignments, assignment)
+	}
+
+	start, end, next, err := cursorPage(request, len(assignments))
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(writer, http.StatusOK, client.SpaceRoleAssignmentsResponse{
+		Links:   client.ConfluenceLink{Next: next},
+		Results: assignments[start:end],
+	})
+}
+
+// setRoleAssignments gives each principal the requested role, replacing the
+// one it had, or removes its role when no role ID is given.
+func (s *Server) setRoleAssignments(writer http.ResponseWriter, request *http.Request) {
+	var body []client.SetSpaceRoleAssignmentRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writeError(writer, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	space := s.findSpaceById(request.PathValue("spaceId"))
+	if space == nil {
+		writeError(writer, http.StatusNotFound, "Space not found")
+		return
+	}
+	for _, assignment := range body {
+		if !s.principalExists(assignment.Principal.PrincipalType, assignment.Principal.PrincipalId) {
+			writeError(writer, http.StatusBadRequest, "No principal found with the given ID")
+			return
+		}
+		if assignment.RoleId != "" && s.findRole(assignment.RoleId) == nil {
+			writeError(writer, http.StatusBadRequest, "No role found with the given ID")
+			return
+		}
+	}
+
+	results := make([]client.SpaceRoleAssignment, 0, len(body))
+	for _, assignment := range body {
+		principal := assignment.Principal
+		if assignment.RoleId == "" {
+			s.unassignSpaceRole(space.Id, principal.PrincipalType, principal.PrincipalId)
+			continue
+		}
+		s.assignSpaceRole(space.Id, principal.PrincipalType, principal.PrincipalId, assignment.RoleId)
+		results = append(results, client.SpaceRoleAssignment{Principal: principal, RoleId: assignment.RoleId})
+	}
+	writeJSON(writer, http.StatusOK, client.SpaceRoleAssignmentsResponse{Results: results})
+}
+
+func (s *Server) getSpaceRoleMode(writer http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.roleMode == "" {
+		writeError(writer, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(writer, http.StatusOK, client.SpaceRoleModeResponse{Mode: s.roleMode})
+}
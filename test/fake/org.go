@@ -0,0 +1,69 @@
+package fake
+
+import (
+	"net/http"
+
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+)
+
+// orgUserList is the envelope of the organization admin API user list.
+type orgUserList struct {
+	Data  []client.OrgUser `json:"data"`
+	Links struct {
+		Next string `json:"next,omitempty"`
+	} `json:"links"`
+}
+
+// SetOrg sets the ID of the Atlassian organization that manages the site's
+// accounts. Requests for other organizations answer 404.
+func (s *Server) SetOrg(orgId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orgId = orgId
+}
+
+// AddOrgUser adds an account managed by the organization. Status defaults to
+// "active".
+func (s *Server) AddOrgUser(user client.OrgUser) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user.AccountStatus == "" {
+		user.AccountStatus = client.OrgAccountStatusActive
+	}
+	s.orgUsers = append(s.orgUsers, user)
+}
+
+func (s *Server) getOrg(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orgId := request.PathValue("orgId")
+	if s.orgId == "" || orgId != s.orgId {
+		writeError(writer, http.StatusNotFound, "Organization not found")
+		return
+	}
+	writeJSON(writer, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"id": orgId, "type": "orgs"},
+	})
+}
+
+// listOrgUsers pages the managed accounts with the cursors of the v2 API; the
+// next link carries the cursor like the real API does.
+func (s *Server) listOrgUsers(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.orgId == "" || request.PathValue("orgId") != s.orgId {
+		writeError(writer, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	start, end, next, err := cursorPage(request, len(s.orgUsers))
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+	response := orgUserList{Data: s.orgUsers[start:end]}
+	response.Links.Next = next
+	writeJSON(writer, http.StatusOK, response)
+}
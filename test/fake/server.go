@@ -0,0 +1,475 @@
+// Package fake is a stateful, in-memory stand-in for Confluence Cloud. It
+// serves the v1 and v2 REST endpoints used by the connector's client and the
+// user endpoints of the Atlassian organization admin API, keeps track of
+// every mutation and can be told to rate limit requests, so that provisioning
+// can be tested end to end without a real site.
+package fake
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-confluence/pkg/connector/client"
+)
+
+const (
+	// Page sizes used when the request does not set a limit, matching the
+	// Confluence Cloud defaults of the v1 and v2 APIs respectively.
+	defaultOffsetLimit = 25
+	defaultCursorLimit = 25
+	maxCursorLimit     = 250
+	// maxRestrictionLimit caps the users and groups returned per page of a
+	// content restriction. Confluence caps them too; the fake caps them low so
+	// that tests page through them.
+	maxRestrictionLimit = 2
+
+	// maxCustomRoles is how many custom space roles a site can define.
+	maxCustomRoles = 10
+)
+
+// accessClasses are the principal IDs of ACCESS_CLASS role assignments.
+var accessClasses = []string{
+	"anonymous-users",
+	"authenticated-users",
+	"all-licensed-users",
+	"all-product-admins",
+	"jsm-project-admins",
+}
+
+// Group is a Confluence group. Members are referenced by account ID.
+type Group struct {
+	Id      string
+	Name    string
+	Members []string
+}
+
+// Server holds the in-memory state and implements http.Handler. The zero value
+// is not usable, use New instead.
+type Server struct {
+	mu sync.Mutex
+
+	currentUser     string
+	users           []client.ConfluenceUser
+	groups          []*Group
+	spaces          []client.ConfluenceSpace
+	spaceLabels     map[string][]string
+	permissions     map[string][]client.ConfluenceSpacePermission
+	roles           []client.SpaceRole
+	roleAssignments map[string][]client.SpaceRoleAssignment
+	roleMode        string
+	contents        []*Content
+	auditRecords    []client.AuditRecord
+	orgId           string
+	orgUsers        []client.OrgUser
+	nextId          int
+
+	rateLimitedRequests int
+	retryAfter          time.Duration
+	requests            []string
+
+	mux *http.ServeMux
+}
+
+// New returns an empty server in the ROLES space role mode.
+func New() *Server {
+	s := &Server{
+		spaceLabels:     make(map[string][]string),
+		permissions:     make(map[string][]client.ConfluenceSpacePermission),
+		roleAssignments: make(map[string][]client.SpaceRoleAssignment),
+		roleMode:        "ROLES",
+		nextId:          1000,
+		mux:             http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET "+client.CurrentUserUrlPath, s.getCurrentUser)
+	s.mux.HandleFunc("GET "+client.SearchUrlPath, s.searchUsers)
+	s.mux.HandleFunc("GET /wiki/rest/api/user", s.getUser)
+	s.mux.HandleFunc("GET "+client.GroupsListUrlPath, s.listGroups)
+	s.mux.HandleFunc("POST "+client.GroupsListUrlPath, s.createGroup)
+	s.mux.HandleFunc("DELETE /wiki/rest/api/group/by-id", s.deleteGroup)
+	s.mux.HandleFunc("GET /wiki/rest/api/group/{groupId}/membersByGroupId", s.listGroupMembers)
+	s.mux.HandleFunc("POST /wiki/rest/api/group/userByGroupId", s.addGroupMember)
+	s.mux.HandleFunc("DELETE /wiki/rest/api/group/userByGroupId", s.removeGroupMember)
+	s.mux.HandleFunc("POST /wiki/rest/api/space/{spaceKey}/permissions", s.createSpacePermission)
+	s.mux.HandleFunc("DELETE /wiki/rest/api/space/{spaceKey}/permissions/{permissionId}", s.deleteSpacePermission)
+	s.mux.HandleFunc("GET "+client.SpacesListUrlPath, s.listSpaces)
+	s.mux.HandleFunc("POST "+client.SpacesListUrlPath, s.createSpace)
+	s.mux.HandleFunc("PUT /wiki/rest/api/space/{spaceKey}", s.updateSpace)
+	s.mux.HandleFunc("DELETE /wiki/rest/api/space/{spaceKey}", s.deleteSpace)
+	s.mux.HandleFunc("GET /wiki/api/v2/spaces/{spaceId}", s.getSpace)
+	s.mux.HandleFunc("GET /wiki/api/v2/spaces/{spaceId}/permissions", s.listSpacePermissions)
+	s.mux.HandleFunc("GET /wiki/api/v2/spaces/{spaceId}/labels", s.listSpaceLabels)
+	s.mux.HandleFunc("GET /wiki/api/v2/spaces/{spaceId}/role-assignments", s.listRoleAssignments)
+	s.mux.HandleFunc("POST /wiki/api/v2/spaces/{spaceId}/role-assignments", s.setRoleAssignments)
+	s.mux.HandleFunc("GET "+client.SpaceRolesUrlPath, s.listSpaceRoles)
+	s.mux.HandleFunc("POST "+client.SpaceRolesUrlPath, s.createSpaceRole)
+	s.mux.HandleFunc("GET /wiki/api/v2/space-roles/{roleId}", s.getSpaceRole)
+	s.mux.HandleFunc("PUT /wiki/api/v2/space-roles/{roleId}", s.updateSpaceRole)
+	s.mux.HandleFunc("DELETE /wiki/api/v2/space-roles/{roleId}", s.deleteSpaceRole)
+	s.mux.HandleFunc("GET "+client.SpaceRoleModeUrlPath, s.getSpaceRoleMode)
+	s.mux.HandleFunc("GET "+client.ContentSearchUrlPath, s.searchContent)
+	s.mux.HandleFunc("GET "+client.ContentSearchCqlUrlPath, s.searchSpaces)
+	s.mux.HandleFunc("GET "+client.AuditUrlPath, s.listAuditRecords)
+	s.mux.HandleFunc("GET /wiki/rest/api/group/by-name", s.getGroupByName)
+	s.mux.HandleFunc("GET /wiki/rest/api/content/{contentId}/restriction/byOperation/{operation}", s.getContentRestriction)
+	s.mux.HandleFunc("PUT /wiki/rest/api/content/{contentId}/restriction/byOperation/{operation}/user", s.addContentRestriction)
+	s.mux.HandleFunc("DELETE /wiki/rest/api/content/{contentId}/restriction/byOperation/{operation}/user", s.removeContentRestriction)
+	s.mux.HandleFunc("PUT /wiki/rest/api/content/{contentId}/restriction/byOperation/{operation}/byGroupId/{groupId}", s.addContentRestriction)
+	s.mux.HandleFunc("DELETE /wiki/rest/api/content/{contentId}/restriction/byOperation/{operation}/byGroupId/{groupId}", s.removeContentRestriction)
+	s.mux.HandleFunc("GET /admin/v1/orgs/{orgId}", s.getOrg)
+	s.mux.HandleFunc("GET /admin/v1/orgs/{orgId}/users", s.listOrgUsers)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, request.Method+" "+request.URL.Path)
+	rateLimited := s.rateLimitedRequests > 0
+	if rateLimited {
+		s.rateLimitedRequests--
+	}
+	retryAfter := s.retryAfter
+	s.mu.Unlock()
+
+	if rateLimited {
+		writer.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		writeError(writer, http.StatusTooManyRequests, "Rate limit exceeded")
+		return
+	}
+
+	s.mux.ServeHTTP(writer, request)
+}
+
+// RateLimitNext makes the next count requests fail with 429 Too Many Requests
+// and the given Retry-After.
+func (s *Server) RateLimitNext(count int, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimitedRequests = count
+	s.retryAfter = retryAfter
+}
+
+// Requests returns the method and path of every request received so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// AddUser adds a user. The first user added is the current user unless
+// SetCurrentUser says otherwise.
+func (s *Server) AddUser(user client.ConfluenceUser) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user.AccountType == "" {
+		user.AccountType = "atlassian"
+	}
+	s.users = append(s.users, user)
+	if s.currentUser == "" {
+		s.currentUser = user.AccountId
+	}
+}
+
+// SetCurrentUser sets the account the credentials belong to.
+func (s *Server) SetCurrentUser(accountId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.currentUser = accountId
+}
+
+// Groups returns the names of every group.
+func (s *Server) Groups() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.groups))
+	for _, group := range s.groups {
+		names = append(names, group.Name)
+	}
+	return names
+}
+
+// Space returns the space with the given key, if any.
+func (s *Server) Space(spaceKey string) *client.ConfluenceSpace {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	space := s.findSpaceByKey(spaceKey)
+	if space == nil {
+		return nil
+	}
+	spaceCopy := *space
+	return &spaceCopy
+}
+
+// AddGroup adds a group with the given members.
+func (s *Server) AddGroup(id string, name string, members ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups = append(s.groups, &Group{Id: id, Name: name, Members: members})
+}
+
+// AddSpace adds a space. Status defaults to "current" and type to "global".
+func (s *Server) AddSpace(space client.ConfluenceSpace) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if space.Status == "" {
+		space.Status = "current"
+	}
+	if space.Type == "" {
+		space.Type = "global"
+	}
+	s.spaces = append(s.spaces, space)
+}
+
+// SetSpaceLabels replaces the labels of a space.
+func (s *Server) SetSpaceLabels(spaceId string, labels ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spaceLabels[spaceId] = labels
+}
+
+// AddSpacePermission grants an operation on a space and returns the ID of the
+// new permission.
+func (s *Server) AddSpacePermission(
+	spaceId string,
+	principalType string,
+	principalId string,
+	key string,
+	target string,
+) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addSpacePermission(spaceId, principalType, principalId, key, target)
+}
+
+// AddSpaceRole adds a role to the site-wide role catalog.
+func (s *Server) AddSpaceRole(role client.SpaceRole) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roles = append(s.roles, role)
+}
+
+// AssignSpaceRole gives a principal ("USER", "GROUP" or "ACCESS_CLASS") a role in a space,
+// replacing the role it had there before.
+func (s *Server) AssignSpaceRole(spaceId string, principalType string, principalId string, roleId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assignSpaceRole(spaceId, principalType, principalId, roleId)
+}
+
+// SetSpaceRoleMode sets what the space-role-mode endpoint reports, e.g.
+// "PRE_ROLES", "ROLES_TRANSITION" or "ROLES". With "", the endpoint answers
+// 404 like on sites without space roles.
+func (s *Server) SetSpaceRoleMode(mode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roleMode = mode
+}
+
+// GroupMembers returns the account IDs of the members of a group.
+func (s *Server) GroupMembers(groupId string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group := s.findGroup(groupId)
+	if group == nil {
+		return nil
+	}
+	return slices.Clone(group.Members)
+}
+
+// SpacePermissions returns the permissions granted on a space.
+func (s *Server) SpacePermissions(spaceId string) []client.ConfluenceSpacePermission {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.permissions[spaceId])
+}
+
+// SpaceRoles returns the site-wide role catalog.
+func (s *Server) SpaceRoles() []client.SpaceRole {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.roles)
+}
+
+// SpaceRoleAssignments returns the role assignments of a space.
+func (s *Server) SpaceRoleAssignments(spaceId string) []client.SpaceRoleAssignment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.roleAssignments[spaceId])
+}
+
+func (s *Server) newId() string {
+	s.nextId++
+	return strconv.Itoa(s.nextId)
+}
+
+func (s *Server) findUser(accountId string) *client.ConfluenceUser {
+	for i := range s.users {
+		if s.users[i].AccountId == accountId {
+			return &s.users[i]
+		}
+	}
+	return nil
+}
+
+func (s *Server) findGroup(groupId string) *Group {
+	for _, group := range s.groups {
+		if group.Id == groupId {
+			return group
+		}
+	}
+	return nil
+}
+
+func (s *Server) findSpace(match func(client.ConfluenceSpace) bool) *client.ConfluenceSpace {
+	for i := range s.spaces {
+		if match(s.spaces[i]) {
+			return &s.spaces[i]
+		}
+	}
+	return nil
+}
+
+func (s *Server) findSpaceById(spaceId string) *client.ConfluenceSpace {
+	return s.findSpace(func(space client.ConfluenceSpace) bool { return space.Id == spaceId })
+}
+
+func (s *Server) findSpaceByKey(spaceKey string) *client.ConfluenceSpace {
+	return s.findSpace(func(space client.ConfluenceSpace) bool { return space.Key == spaceKey })
+}
+
+func (s *Server) findRole(roleId string) *client.SpaceRole {
+	for i := range s.roles {
+		if s.roles[i].Id == roleId {
+			return &s.roles[i]
+		}
+	}
+	return nil
+}
+
+func (s *Server) principalExists(principalType string, principalId string) bool {
+	switch principalType {
+	case "user", "USER":
+		return s.findUser(principalId) != nil
+	case "group", "GROUP":
+		return s.findGroup(principalId) != nil
+	case "ACCESS_CLASS":
+		return slices.Contains(accessClasses, principalId)
+	}
+	return false
+}
+
+func (s *Server) addSpacePermission(
+	spaceId string,
+	principalType string,
+	principalId string,
+	key string,
+	target string,
+) string {
+	permission := client.ConfluenceSpacePermission{
+		Id: s.newId(),
+		Principal: client.ConfluenceSpacePermissionPrincipal{
+			Id:   principalId,
+			Type: principalType,
+		},
+		Operation: client.ConfluenceSpacePermissionOperation{
+			Key:        key,
+			TargetType: target,
+		},
+	}
+	s.permissions[spaceId] = append(s.permissions[spaceId], permission)
+	return permission.Id
+}
+
+func (s *Server) assignSpaceRole(spaceId string, principalType string, principalId string, roleId string) {
+	s.unassignSpaceRole(spaceId, principalType, principalId)
+	s.roleAssignments[spaceId] = append(s.roleAssignments[spaceId], client.SpaceRoleAssignment{
+		Principal: client.SpaceRoleAssignmentPrincipal{
+			PrincipalType: principalType,
+			PrincipalId:   principalId,
+		},
+		RoleId: roleId,
+	})
+}
+
+func (s *Server) unassignSpaceRole(spaceId string, principalType string, principalId string) {
+	s.roleAssignments[spaceId] = slices.DeleteFunc(
+		s.roleAssignments[spaceId],
+		func(assignment client.SpaceRoleAssignment) bool {
+			return assignment.Principal.PrincipalType == principalType &&
+				assignment.Principal.PrincipalId == principalId
+		},
+	)
+}
+
+func writeJSON(writer http.ResponseWriter, statusCode int, body interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	_ = json.NewEncoder(writer).Encode(body)
+}
+
+func writeError(writer http.ResponseWriter, statusCode int, message string) {
+	writeJSON(writer, statusCode, map[string]interface{}{
+		"statusCode": statusCode,
+		"message":    message,
+	})
+}
+
+// offsetPage applies v1 `start` and `limit` pagination to a list of n items.
+// It returns the bounds of the page and the "next" link, if there is one.
+func offsetPage(request *http.Request, n int) (int, int, string) {
+	query := request.URL.Query()
+	start, _ := strconv.Atoi(query.Get("start"))
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultOffsetLimit
+	}
+	start = min(max(start, 0), n)
+	end := min(start+limit, n)
+
+	if end >= n {
+		return start, end, ""
+	}
+	query.Set("start", strconv.Itoa(end))
+	query.Set("limit", strconv.Itoa(limit))
+	return start, end, (&url.URL{Path: request.URL.Path, RawQuery: query.Encode()}).String()
+}
+
+// cursorPage applies v2 cursor pagination to a list of n items. Cursors are
+// opaque to clients, here they wrap the offset of the next item.
+func cursorPage(request *http.Request, n int) (int, int, string, error) {
+	query := request.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultCursorLimit
+	}
+	limit = min(limit, maxCursorLimit)
+
+	start := 0
+	if cursor := query.Get("cursor"); cursor != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return 0, 0, "", fmt.Errorf("invalid cursor")
+		}
+		start, err = strconv.Atoi(string(decoded))
+		if err != nil {
+			return 0, 0, "", fmt.Errorf("invalid cursor")
+		}
+	}
+	start = min(max(start, 0), n)
+	end := min(start+limit, n)
+
+	if end >= n {
+		return start, end, "", nil
+	}
+	query.Set("cursor", base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(end))))
+	query.Set("limit", strconv.Itoa(limit))
+	return start, end, (&url.URL{Path: request.URL.Path, RawQuery: query.Encode()}).String(), nil
+}
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/conductorone/baton-confluence/pkg/connector/client"
+	"github.com/conductorone/baton-confluence/test/fake"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
@@ -92,3 +93,13 @@ func FixturesServer() *httptest.Server {
 		),
 	)
 }
+
+// FakeServer starts a stateful fake Confluence seeded with the demo site. The
+// HTTP response cache is disabled so that reads observe the test's mutations.
+func FakeServer(t *testing.T) (*fake.Server, *httptest.Server) {
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "true")
+	site := fake.NewDemo()
+	server := httptest.NewServer(site)
+	t.Cleanup(server.Close)
+	return site, server
+}